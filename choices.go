@@ -0,0 +1,476 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SetFlatChoices makes the generator emit the alternatives of xs:choice model
+// groups as sibling struct fields instead of sealed interfaces. Nothing then
+// prevents setting several alternatives of a choice.
+func (g *GoWSDL) SetFlatChoices(flat bool) {
+	g.flatChoices = flat
+}
+
+// structContent is the element content of a generated struct, along with
+// what its UnmarshalXML method needs to decode the choices generated as
+// sealed interfaces.
+type structContent struct {
+	TypeName string
+	Fields   []*contentField
+	// Choices are the choices declared by the type itself.
+	Choices []*choiceGroup
+	// Decoded are the choices decoded by the UnmarshalXML method of the
+	// type, including the ones of its base types.
+	Decoded []*choiceTarget
+	// Wildcards are the xs:any wildcards which are not alternatives of a
+	// choice.
+	Wildcards []*XSDAny
+	// Enumeration are the constants of the values allowed by the facets of a
+	// restriction of simple content.
+	Enumeration *enumeration
+	// Items is the field of the xs:any wildcards, if any.
+	Items *fieldTarget
+	// Chooses is set when some of the decoded targets are choices, and
+	// Repeats when some of these choices are repeated.
+	Chooses bool
+	Repeats bool
+	// Polymorphic is set when some of the decoded targets are elements of
+	// abstract or extended types, and Substitutes when some are heads of
+	// substitution groups.
+	Polymorphic bool
+	Substitutes bool
+	// Skips is set when the UnmarshalXML method must skip the elements which
+	// are not alternatives of its repeated choices.
+	Skips bool
+	// HidesBase is set when the type embeds a base type with its own
+	// UnmarshalXML method, which must not be promoted to the decoded struct.
+	HidesBase bool
+	// QNames is set when the type has QName attributes, resolved by its
+	// UnmarshalXML method.
+	QNames bool
+}
+
+// contentField is a field of a generated struct: an element, an element of
+// an abstract or extended type or a choice.
+type contentField struct {
+	Element     *XSDElement
+	Polymorphic *polymorphicField
+	Choice      *choiceGroup
+}
+
+// choiceGroup is an xs:choice generated as a sealed interface, implemented by
+// one wrapper type per alternative. A repeated choice is generated as a slice
+// of alternatives, in document order.
+type choiceGroup struct {
+	Field        string
+	Interface    string
+	Repeated     bool
+	Alternatives []*choiceAlternative
+
+	occurs occurs
+}
+
+// choiceAlternative is an element or a wildcard of a choice.
+type choiceAlternative struct {
+	Name    string
+	Wrapper string
+	GoType  string
+	Space   string
+	Local   string
+	Doc     string
+	Any     bool
+	// Polymorphic is set when the element is of an abstract or extended
+	// type, generated as the interface Interface. Its values are decoded
+	// into the type named by their xsi:type attribute, or into Default
+	// without it, and marshalled with their xsi:type.
+	Polymorphic bool
+	Interface   string
+	Default     string
+	Slice       bool
+	// Checks are the checks of the Validate method of the wrapper, if any.
+	Checks []*validationCheck
+}
+
+// fieldTarget is a field of a struct, or of one of its embedded base types
+// which must be allocated before setting it.
+type fieldTarget struct {
+	Path  string
+	Inits []*embeddedBase
+}
+
+// embeddedBase is a base type embedded by pointer.
+type embeddedBase struct {
+	Path string
+	Type string
+}
+
+// choiceTarget is a choice, or an element of an abstract or extended type,
+// decoded by an UnmarshalXML method.
+type choiceTarget struct {
+	fieldTarget
+	Var         string
+	Interface   string
+	Names       string
+	Repeated    bool
+	Polymorphic bool
+	// Default is the type of the polymorphic elements without xsi:type, and
+	// Decode the function decoding them.
+	Default  string
+	Decode   string
+	Captures []*choiceCapture
+}
+
+// choiceCapture is a field of the struct decoding an alternative of a choice.
+// The alternatives of repeated choices are decoded by functions appending
+// them to the choice, to keep them in document order.
+type choiceCapture struct {
+	Field   string
+	Type    string
+	Tag     string
+	Present string
+	Value   string
+	Wrapper string
+	Any     bool
+	// Polymorphic is set when the wrapper decodes the alternative.
+	Polymorphic bool
+}
+
+// structContent returns the element content of the struct named typeName
+// generated for the complex type.
+func (g *GoWSDL) structContent(typeName string, ct *XSDComplexType) *structContent {
+	content := g.describeStruct(typeName, ct, "", "t", nil, make(map[*XSDComplexType]bool))
+	content.Enumeration = g.simpleContentEnumeration(typeName, ct)
+	content.QNames = g.qnameAttributes(ct)
+	skipped := content.Items == nil
+	vars := make(map[string]bool)
+	for _, target := range content.Decoded {
+		// base types may declare elements of the same name
+		name := target.Var
+		for i := 2; vars[target.Var]; i++ {
+			target.Var = name + strconv.Itoa(i)
+		}
+		vars[target.Var] = true
+
+		if target.Polymorphic {
+			for _, capture := range target.Captures {
+				capture.Field = makePublic(target.Var) + capture.Field
+				capture.Value = "v." + capture.Field
+			}
+			if target.Decode == "soap.DecodeSubstitution" {
+				content.Substitutes = true
+			} else {
+				content.Polymorphic = true
+			}
+			continue
+		}
+		content.Chooses = true
+		if !target.Repeated {
+			continue
+		}
+		content.Repeats = true
+		for _, capture := range target.Captures {
+			if capture.Any {
+				skipped = false
+			}
+		}
+	}
+	content.Skips = content.Repeats && skipped
+	return content
+}
+
+// describeStruct returns the content of the struct of the complex type. path
+// is the expression of the struct in its UnmarshalXML method and qualifier the
+// qualifier of the identifiers of its package, for base types.
+func (g *GoWSDL) describeStruct(typeName string, ct *XSDComplexType, qualifier, path string, inits []*embeddedBase, visiting map[*XSDComplexType]bool) *structContent {
+	content := &structContent{TypeName: typeName}
+	if ct == nil || visiting[ct] {
+		return content
+	}
+	visiting[ct] = true
+	defer delete(visiting, ct)
+
+	taken := map[string]bool{"XMLName": true, "Items": true}
+	model := ct.ModelGroup()
+	attributes := ct.Attributes
+	if base := ct.ComplexContent.Extension.Base; base != "" {
+		model = ct.ComplexContent.Extension.ModelGroup()
+		attributes = ct.ComplexContent.Extension.Attributes
+
+		inherited := g.baseContent(base, path, inits, visiting)
+		for _, target := range inherited.Decoded {
+			taken[target.Path[strings.LastIndex(target.Path, ".")+1:]] = true
+		}
+		content.Decoded = inherited.Decoded
+		content.Items = inherited.Items
+		content.HidesBase = len(inherited.Decoded) > 0 || g.qnameAttributesOf(base)
+	} else if ct.ComplexContent.Restriction.Base != "" {
+		model = ct.ComplexContent.Restriction.ModelGroup()
+		attributes = g.contentAttributes(ct)
+	}
+	for _, attr := range attributes {
+		taken[makePublic(normalize(attr.Name))] = true
+	}
+
+	content.Fields, content.Choices, content.Wildcards = g.contentFields(typeName, model, taken)
+	if ct.ComplexContent.Extension.Base == "" && ct.SimpleContent.Extension.Base == "" && len(content.Wildcards) > 0 {
+		content.Items = &fieldTarget{Path: path + ".Items", Inits: inits}
+	}
+	for _, choice := range content.Choices {
+		target := &choiceTarget{
+			fieldTarget: fieldTarget{Path: path + "." + choice.Field, Inits: inits},
+			Var:         makePrivate(choice.Field),
+			Interface:   qualifier + choice.Interface,
+			Repeated:    choice.Repeated,
+		}
+		var names []string
+		for _, alt := range choice.Alternatives {
+			names = append(names, alt.Local)
+			capture := &choiceCapture{
+				Field:       choice.Field + alt.Name,
+				Type:        alt.GoType,
+				Tag:         strings.TrimSpace(alt.Space + " " + alt.Local),
+				Wrapper:     qualifier + alt.Wrapper,
+				Any:         alt.Any,
+				Polymorphic: alt.Polymorphic,
+			}
+			capture.Value = "v." + capture.Field
+			if choice.Repeated {
+				capture.Type = "soap.UnmarshalFunc"
+				if alt.Any {
+					capture.Tag = ",any"
+				}
+			} else if alt.Polymorphic {
+				// decoded by the wrapper, which appends the occurrences of
+				// repeated elements
+				capture.Type = "*" + capture.Wrapper
+				capture.Present = "v." + capture.Field + " != nil"
+				capture.Value = "v." + capture.Field + ".Value"
+			} else if strings.HasPrefix(alt.GoType, "[]") {
+				capture.Present = "len(v." + capture.Field + ") > 0"
+			} else {
+				if !strings.HasPrefix(alt.GoType, "*") {
+					capture.Type = "*" + alt.GoType
+					capture.Value = "*v." + capture.Field
+				}
+				capture.Present = "v." + capture.Field + " != nil"
+			}
+			target.Captures = append(target.Captures, capture)
+		}
+		target.Names = strings.Join(names, ", ")
+		content.Decoded = append(content.Decoded, target)
+	}
+	for _, field := range content.Fields {
+		if field.Polymorphic == nil {
+			continue
+		}
+		target := &choiceTarget{
+			fieldTarget: fieldTarget{Path: path + "." + field.Polymorphic.Name, Inits: inits},
+			Var:         "decoded" + field.Polymorphic.Name,
+			Interface:   field.Polymorphic.Interface,
+			Repeated:    field.Polymorphic.Slice,
+			Polymorphic: true,
+			Default:     field.Polymorphic.Default,
+			Decode:      "soap.DecodeXSIType",
+		}
+		if field.Polymorphic.Members == nil {
+			target.Captures = []*choiceCapture{{Type: "soap.UnmarshalFunc", Tag: field.Polymorphic.Local}}
+		} else {
+			target.Decode = "soap.DecodeSubstitution"
+		}
+		for _, member := range field.Polymorphic.Members {
+			target.Captures = append(target.Captures, &choiceCapture{
+				Field: member.Name,
+				Type:  "soap.UnmarshalFunc",
+				Tag:   member.Tag,
+			})
+		}
+		content.Decoded = append(content.Decoded, target)
+	}
+
+	return content
+}
+
+// baseContent returns the content of the base type of an extension, embedded
+// in the struct at path.
+func (g *GoWSDL) baseContent(base, path string, inits []*embeddedBase, visiting map[*XSDComplexType]bool) *structContent {
+	s := g.symbols.lookupType(resolveQName(base, g.currentSchema.Xmlns))
+	if s == nil {
+		return &structContent{}
+	}
+	ct, ok := s.decl.(*XSDComplexType)
+	if !ok {
+		return &structContent{}
+	}
+
+	name := replaceReservedWords(makePublic(s.goName))
+	qualifier := g.qualifier(s.name.Space, g.currentPackage, g.typeImports)
+	path += "." + name
+	inits = append(inits[:len(inits):len(inits)], &embeddedBase{Path: path, Type: qualifier + name})
+
+	schema := g.currentSchema
+	g.currentSchema = s.schema
+	defer func() { g.currentSchema = schema }()
+	return g.describeStruct(name, ct, qualifier, path, inits, visiting)
+}
+
+// decodesChoices reports whether the struct generated for the given type has
+// an UnmarshalXML method decoding choices.
+func (g *GoWSDL) decodesChoices(xsdType string) bool {
+	s := g.symbols.lookupType(resolveQName(xsdType, g.currentSchema.Xmlns))
+	if s == nil {
+		return false
+	}
+	ct, ok := s.decl.(*XSDComplexType)
+	if !ok {
+		return false
+	}
+
+	// the types of the alternatives are not used by the generated code
+	schema, imports := g.currentSchema, g.typeImports
+	g.currentSchema, g.typeImports = s.schema, make(importSet)
+	defer func() { g.currentSchema, g.typeImports = schema, imports }()
+	return len(g.structContent(s.goName, ct).Decoded) > 0
+}
+
+// contentFields returns the fields of the model group of the struct named
+// typeName, the choices among them and the wildcards which are not
+// alternatives of a choice. Elements of abstract or extended types are
+// generated as interfaces. Choices are only generated as sealed interfaces
+// when their alternatives are elements of named or simple types, or a
+// wildcard for repeated choices, otherwise their elements are flattened.
+func (g *GoWSDL) contentFields(typeName string, model *XSDModelGroup, taken map[string]bool) ([]*contentField, []*choiceGroup, []*XSDAny) {
+	var fields []*contentField
+	var choices []*choiceGroup
+	var wildcards []*XSDAny
+	enter := func(m *XSDModelGroup, o occurs) bool {
+		if g.flatChoices || typeName == "" {
+			return true
+		}
+		choice := g.choiceGroup(m, o)
+		if choice == nil {
+			return true
+		}
+		fields = append(fields, &contentField{Choice: choice})
+		choices = append(choices, choice)
+		return false
+	}
+	model.walk(occurs{1, 1}, enter, func(p *XSDParticle, o occurs) {
+		if p.Any != nil {
+			wildcards = append(wildcards, p.Any)
+		}
+		if p.Element == nil {
+			return
+		}
+		el := p.Element.withOccurs(o)
+		taken[g.elementFieldName(el)] = true
+		field := &contentField{Element: el}
+		if typeName != "" && el.Ref != "" {
+			field.Polymorphic = g.substitutionField(el)
+		} else if typeName != "" {
+			field.Polymorphic = g.polymorphicField(el)
+		}
+		fields = append(fields, field)
+	})
+
+	for _, choice := range choices {
+		choice.Field = "Choice"
+		for i := 2; taken[choice.Field]; i++ {
+			choice.Field = "Choice" + strconv.Itoa(i)
+		}
+		taken[choice.Field] = true
+		choice.Interface = typeName + choice.Field
+		for _, alt := range choice.Alternatives {
+			alt.Wrapper = choice.Interface + alt.Name
+		}
+	}
+
+	return fields, choices, wildcards
+}
+
+// choiceGroup returns the choice generated for the model group, or nil if it
+// is not a choice or cannot be generated as a sealed interface.
+func (g *GoWSDL) choiceGroup(m *XSDModelGroup, o occurs) *choiceGroup {
+	if m.XMLName.Local != "choice" || len(m.Particles) < 2 || o.max == 0 {
+		return nil
+	}
+
+	choice := &choiceGroup{Repeated: o.max != 1, occurs: o}
+	names := make(map[string]bool)
+	for _, p := range m.Particles {
+		if p.Any != nil && choice.Repeated && !names["Any"] {
+			names["Any"] = true
+			choice.Alternatives = append(choice.Alternatives, &choiceAlternative{
+				Name:   "Any",
+				GoType: "AnyType",
+				Local:  "any element",
+				Doc:    p.Any.Doc,
+				Any:    true,
+			})
+			continue
+		}
+
+		el := p.Element
+		if el == nil || el.Ref == "" && el.Type == "" && el.SimpleType == nil {
+			return nil
+		}
+
+		alt := &choiceAlternative{
+			Name:  makePublic(g.elementFieldName(el)),
+			Local: el.Name,
+			Doc:   el.Doc,
+		}
+		if names[alt.Name] {
+			return nil
+		}
+		names[alt.Name] = true
+
+		// each occurrence of an element of a repeated choice is an alternative
+		var slice string
+		if el.Repeated() && !choice.Repeated {
+			slice = "[]"
+		}
+		switch {
+		case el.Ref != "":
+			xmlName := g.elementXMLName(el.Ref)
+			if i := strings.LastIndex(xmlName, " "); i >= 0 {
+				alt.Space, alt.Local = xmlName[:i], xmlName[i+1:]
+			} else {
+				alt.Local = xmlName
+			}
+			alt.GoType = slice + g.elementGoType(el.Ref)
+		case el.Type != "":
+			alt.GoType = slice + g.toGoType(el.Type, el.Nillable)
+			if field := g.polymorphicField(el); field != nil {
+				alt.Polymorphic, alt.Interface, alt.Default = true, field.Interface, field.Default
+				alt.Slice = slice != ""
+				alt.GoType = slice + field.Interface
+			}
+		case el.SimpleType.List.ItemType != "":
+			alt.GoType = "[]" + g.toGoType(el.SimpleType.List.ItemType, false)
+		default:
+			alt.GoType = g.toGoType(el.SimpleType.Restriction.Base, false)
+		}
+		alt.Checks = g.alternativeChecks(el)
+		choice.Alternatives = append(choice.Alternatives, alt)
+	}
+	return choice
+}
+
+// elementFieldName returns the name of the struct field of an element, as
+// generated by the Elements template.
+func (g *GoWSDL) elementFieldName(el *XSDElement) string {
+	switch {
+	case el.Ref != "":
+		return g.makePublicFn(replaceReservedWords(removeNS(el.Ref)))
+	case el.Type != "":
+		return makePublic(replaceAttrReservedWords(el.Name))
+	case el.SimpleType != nil:
+		return makePublic(normalize(el.Name))
+	}
+	return g.makePublicFn(replaceReservedWords(el.Name))
+}
@@ -7,23 +7,43 @@ Gowsdl generates Go code from a WSDL file.
 
 This project is originally intended to generate Go clients for WS-* services.
 
-Usage: gowsdl [options] myservice.wsdl
+Usage: gowsdl [options] myservice.wsdl [otherservice.wsdl ...]
   -o string
         File where the generated code will be saved (default "myservice.go")
   -p string
         Package under which code will be generated (default "myservice")
   -v    Shows gowsdl version
+  -ns-packages
+        Generate the types of each XML namespace in their own package
+  -import-path string
+        Import path of the generated package, required by -ns-packages
+  -ns-map value
+        Import path of the package of a namespace, as namespace=importpath (repeatable)
+  -flat-choices
+        Generate the alternatives of choices as struct fields instead of sealed interfaces
+  -strict-enums
+        Reject the values which are not enumerated by the schema when marshalling and unmarshalling enumerations
+  -zero-value-optionals
+        Generate the optional elements of built-in types as values instead of pointers, absent elements decoding to the zero value
+  -type-map string
+        JSON file mapping XML Schema types, keyed by {namespace}name, to Go types
 
 Features
 
-Supports only Document/Literal wrapped services, which are WS-I (http://ws-i.org/) compliant.
+Supports Document/Literal wrapped services, which are WS-I (http://ws-i.org/) compliant, as well as RPC/Literal and RPC/Encoded services.
 
 Attempts to generate idiomatic Go code as much as possible.
 
-Supports WSDL 1.1, XML Schema 1.0, SOAP 1.1.
+Supports WSDL 1.1 and WSDL 2.0, XML Schema 1.0, SOAP 1.1 and SOAP 1.2.
+
+Generates choices as sealed interfaces implemented by one type per alternative.
 
 Resolves external XML Schemas
 
+Generates one Go package per XML namespace, in subdirectories of the main package.
+
+Generates several services at once, each in a subdirectory of the main package named after its WSDL file, sharing the packages of the schemas they have in common.
+
 Supports providing WSDL HTTP URL as well as a local WSDL file.
 
 Not supported
@@ -38,8 +58,6 @@ If WSDL file is local, resolve external XML schemas locally too instead of faili
 
 Resolve XSD element references.
 
-Support for generating namespaces.
-
 Make code generation agnostic so generating code to other programming languages is feasible through plugins.
 
 */
@@ -48,12 +66,15 @@ package main
 
 import (
 	"bytes"
+	"encoding/xml"
 	"flag"
 	"fmt"
 	"go/format"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	gen "github.com/ilmich/gowsdl"
 )
@@ -70,6 +91,39 @@ var outFile = flag.String("o", "myservice.go", "File where the generated code wi
 var dir = flag.String("d", "./", "Directory under which package directory will be created")
 var insecure = flag.Bool("i", false, "Skips TLS Verification")
 var makePublic = flag.Bool("make-public", true, "Make the generated types public/exported")
+var nsPackages = flag.Bool("ns-packages", false, "Generate the types of each XML namespace in their own package")
+var importPath = flag.String("import-path", "", "Import path of the generated package, required by -ns-packages")
+var flatChoices = flag.Bool("flat-choices", false, "Generate the alternatives of choices as struct fields instead of sealed interfaces")
+var strictEnums = flag.Bool("strict-enums", false, "Reject the values which are not enumerated by the schema when marshalling and unmarshalling enumerations")
+var typeMapFile = flag.String("type-map", "", "JSON file mapping XML Schema types, keyed by {namespace}name, to Go types")
+var zeroValueOptionals = flag.Bool("zero-value-optionals", false, "Generate the optional elements of built-in types as values instead of pointers, absent elements decoding to the zero value")
+var nsMap = make(namespaceMap)
+
+func init() {
+	flag.Var(nsMap, "ns-map", "Import path of the package of a namespace, as namespace=importpath (repeatable)")
+}
+
+// namespaceMap maps XML namespaces to Go import paths.
+type namespaceMap map[string]string
+
+func (m namespaceMap) String() string {
+	var pairs []string
+	for ns, importPath := range m {
+		pairs = append(pairs, ns+"="+importPath)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m namespaceMap) Set(value string) error {
+	// namespaces usually contain colons and slashes, import paths can't
+	// contain an equal sign
+	i := strings.LastIndex(value, "=")
+	if i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("expected namespace=importpath, got %q", value)
+	}
+	m[value[:i]] = value[i+1:]
+	return nil
+}
 
 func init() {
 	log.SetFlags(0)
@@ -79,7 +133,7 @@ func init() {
 
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] myservice.wsdl\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] myservice.wsdl [otherservice.wsdl ...]\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 
@@ -96,6 +150,12 @@ func main() {
 		os.Exit(0)
 	}
 
+	if flag.NArg() > 1 {
+		generateAll(flag.Args())
+		log.Println("Done 👍")
+		return
+	}
+
 	wsdlPath := os.Args[len(os.Args)-1]
 
 	if *outFile == wsdlPath {
@@ -108,6 +168,20 @@ func main() {
 		log.Fatalln(err)
 	}
 
+	gowsdl.SetFlatChoices(*flatChoices)
+	gowsdl.SetStrictEnums(*strictEnums)
+	gowsdl.SetZeroValueOptionals(*zeroValueOptionals)
+	if typeMapping := readTypeMapping(); typeMapping != nil {
+		gowsdl.SetTypeMapping(typeMapping)
+	}
+
+	if *nsPackages {
+		if *importPath == "" {
+			log.Fatalln("-ns-packages requires the -import-path of the generated package")
+		}
+		gowsdl.SetPackagePerNamespace(*importPath, nsMap)
+	}
+
 	// generate code
 	gocode, err := gowsdl.Start()
 	if err != nil {
@@ -115,9 +189,90 @@ func main() {
 	}
 
 	pkg := filepath.Join(*dir, *pkg)
-	err = os.Mkdir(pkg, 0744)
+	writeService(pkg, *outFile, gocode, pkg)
+
+	log.Println("Done 👍")
+}
+
+// generateAll generates several services in subpackages of the package,
+// sharing the packages of their common schemas.
+func generateAll(wsdlPaths []string) {
+	if *importPath == "" {
+		log.Fatalln("generating several services requires the -import-path of the generated package")
+	}
 
-	file, err := os.Create(filepath.Join(pkg, *outFile))
+	typeMapping := readTypeMapping()
+	var services []*gen.GoWSDL
+	var names []string
+	for _, wsdlPath := range wsdlPaths {
+		name := servicePackage(wsdlPath)
+		gowsdl, err := gen.NewGoWSDL(wsdlPath, name, *insecure, *makePublic)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		gowsdl.SetPackagePerNamespace(*importPath+"/"+name, nsMap)
+		gowsdl.SetFlatChoices(*flatChoices)
+		gowsdl.SetStrictEnums(*strictEnums)
+		gowsdl.SetZeroValueOptionals(*zeroValueOptionals)
+		if typeMapping != nil {
+			gowsdl.SetTypeMapping(typeMapping)
+		}
+
+		services = append(services, gowsdl)
+		names = append(names, name)
+	}
+
+	gocodes, err := gen.StartAll(*importPath, services...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	root := filepath.Join(*dir, *pkg)
+	for i, gocode := range gocodes {
+		writeService(filepath.Join(root, names[i]), names[i]+".go", gocode, root)
+	}
+}
+
+// readTypeMapping reads the -type-map file, if any.
+func readTypeMapping() map[xml.Name]gen.TypeMapping {
+	if *typeMapFile == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(*typeMapFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	mapping, err := gen.ParseTypeMapping(data)
+	if err != nil {
+		log.Fatalln(*typeMapFile + ": " + err.Error())
+	}
+	return mapping
+}
+
+// servicePackage returns the package name of the service described by a
+// WSDL file, after the name of the file.
+func servicePackage(wsdlPath string) string {
+	name := strings.TrimSuffix(filepath.Base(wsdlPath), filepath.Ext(wsdlPath))
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r - 'A' + 'a'
+		}
+		return -1
+	}, name)
+}
+
+// writeService writes the code of a service in pkg, and the code of the
+// namespace packages in subdirectories of packagesDir.
+func writeService(pkg, outFile string, gocode map[string][]byte, packagesDir string) {
+	err := os.MkdirAll(pkg, 0744)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	file, err := os.Create(filepath.Join(pkg, outFile))
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -139,7 +294,7 @@ func main() {
 	file.Write(source)
 
 	// server
-	serverFile, err := os.Create(pkg + "/" + "server" + *outFile)
+	serverFile, err := os.Create(pkg + "/" + "server" + outFile)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -157,5 +312,27 @@ func main() {
 	}
 	serverFile.Write(serverSource)
 
-	log.Println("Done 👍")
+	// namespace packages
+	for key, code := range gocode {
+		if !strings.HasPrefix(key, gen.PackagePrefix) {
+			continue
+		}
+		nsDir := filepath.Join(packagesDir, filepath.FromSlash(strings.TrimPrefix(key, gen.PackagePrefix)))
+		if err := os.MkdirAll(nsDir, 0744); err != nil {
+			log.Fatalln(err)
+		}
+
+		nsFile, err := os.Create(filepath.Join(nsDir, filepath.Base(nsDir)+".go"))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer nsFile.Close()
+
+		nsSource, err := format.Source(code)
+		if err != nil {
+			nsFile.Write(code)
+			log.Fatalln(err)
+		}
+		nsFile.Write(nsSource)
+	}
 }
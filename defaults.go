@@ -0,0 +1,232 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import "strings"
+
+// constructor is the New function of a generated struct, setting the default
+// and fixed values declared by its schema.
+type constructor struct {
+	TypeName string
+	// Base is the field of the embedded base type, set by the constructor
+	// BaseNew when the base type has one.
+	Base    string
+	BaseNew string
+	Values  []*defaultValue
+}
+
+// defaultValue is the default or fixed value of an element or attribute.
+type defaultValue struct {
+	// Ref is the address of the field.
+	Ref   string
+	Value string
+}
+
+// fixedAttribute is the type of the attributes of the same name whose value
+// is fixed to Value, which always marshals Value.
+type fixedAttribute struct {
+	TypeName string
+	Name     string
+	Value    string
+}
+
+// fixedAttributeType returns the Go type of the field of an attribute with a
+// fixed value, declared after the types of the current package.
+func (g *GoWSDL) fixedAttributeType(attr *XSDAttribute) string {
+	key := [2]string{attr.Name, attr.Fixed}
+	if f := g.fixedAttributeTypes[key]; f != nil {
+		return f.TypeName
+	}
+	f := &fixedAttribute{
+		TypeName: g.uniqueIdentifier("Fixed" + makePublic(normalize(attr.Name))),
+		Name:     attr.Name,
+		Value:    attr.Fixed,
+	}
+	g.fixedAttributeTypes[key] = f
+	g.fixedAttributes = append(g.fixedAttributes, f)
+	return f.TypeName
+}
+
+// structConstructor returns the New function of the struct generated for the
+// complex type, whose element content is content, or nil if it has no
+// default or fixed values.
+func (g *GoWSDL) structConstructor(content *structContent, ct *XSDComplexType) *constructor {
+	if !g.hasDefaults(ct, make(map[*XSDComplexType]bool)) {
+		return nil
+	}
+	c := &constructor{TypeName: content.TypeName}
+	switch {
+	case ct.ComplexContent.Extension.Base != "":
+		c.Base, c.BaseNew = g.baseConstructor(ct.ComplexContent.Extension.Base)
+		c.Values = append(c.Values, g.fieldDefaults("t.", content.Fields)...)
+		c.Values = append(c.Values, attributeDefaults("t.", ct.ComplexContent.Extension.Attributes)...)
+	case ct.SimpleContent.Extension.Base != "":
+		c.Values = attributeDefaults("t.", ct.SimpleContent.Extension.Attributes)
+	case ct.SimpleContent.Restriction.Base != "":
+		c.Values = attributeDefaults("t.", g.contentAttributes(ct))
+	default:
+		c.Values = append(g.fieldDefaults("t.", content.Fields), attributeDefaults("t.", g.contentAttributes(ct))...)
+	}
+	return c
+}
+
+// baseConstructor returns the field of the base type embedded by an
+// extension and its constructor, if it has one.
+func (g *GoWSDL) baseConstructor(base string) (string, string) {
+	if !g.constructs(base) {
+		return "", ""
+	}
+	goType := removePointerFromType(g.toGoType(base, false))
+	i := strings.LastIndex(goType, ".") + 1
+	return goType[i:], goType[:i] + "New" + goType[i:]
+}
+
+// constructorOf returns the New function of the Go type of a type reference
+// of the current schema, or an empty string if it has none.
+func (g *GoWSDL) constructorOf(xsdType string) string {
+	_, newFunc := g.baseConstructor(xsdType)
+	return newFunc
+}
+
+// constructs reports whether the Go type of a type reference of the current
+// schema has a New function.
+func (g *GoWSDL) constructs(xsdType string) bool {
+	name, resolved := resolveQName(xsdType, g.currentSchema.Xmlns)
+	if !resolved || builtinNamespaces[name.Space] {
+		return false
+	}
+	s := g.symbols.lookupType(name, resolved)
+	if s == nil {
+		return false
+	}
+	ct, ok := s.decl.(*XSDComplexType)
+	if !ok {
+		return false
+	}
+
+	schema := g.currentSchema
+	g.currentSchema = s.schema
+	defer func() { g.currentSchema = schema }()
+	return g.hasDefaults(ct, make(map[*XSDComplexType]bool))
+}
+
+// hasDefaults reports whether the struct generated for the complex type has
+// elements or attributes with default or fixed values, directly or through
+// its base types and anonymous structs. Fixed attributes have their own type,
+// which needs no constructor.
+func (g *GoWSDL) hasDefaults(ct *XSDComplexType, visiting map[*XSDComplexType]bool) bool {
+	if ct == nil || visiting[ct] {
+		return false
+	}
+	visiting[ct] = true
+	defer delete(visiting, ct)
+
+	switch {
+	case ct.ComplexContent.Extension.Base != "":
+		ext := ct.ComplexContent.Extension
+		return g.constructs(ext.Base) || g.elementsHaveDefaults(ext.Elements(), visiting) ||
+			attributesHaveDefaults(ext.Attributes)
+	case ct.SimpleContent.Extension.Base != "":
+		return attributesHaveDefaults(ct.SimpleContent.Extension.Attributes)
+	case ct.SimpleContent.Restriction.Base != "":
+		return attributesHaveDefaults(g.contentAttributes(ct))
+	case ct.ComplexContent.Restriction.Base != "":
+		return g.elementsHaveDefaults(ct.ComplexContent.Restriction.Elements(), visiting) ||
+			attributesHaveDefaults(g.contentAttributes(ct))
+	}
+	return g.elementsHaveDefaults(ct.Elements(), visiting) || attributesHaveDefaults(ct.Attributes)
+}
+
+func (g *GoWSDL) elementsHaveDefaults(elements []*XSDElement, visiting map[*XSDComplexType]bool) bool {
+	for _, el := range elements {
+		if g.elementDefault(el) != "" {
+			return true
+		}
+		if el.Type == "" && el.Ref == "" && !el.Repeated() && g.hasDefaults(el.ComplexType, visiting) {
+			return true
+		}
+	}
+	return false
+}
+
+func attributesHaveDefaults(attributes []*XSDAttribute) bool {
+	return len(attributeDefaults("", attributes)) > 0
+}
+
+// elementDefault returns the default or fixed value of an element, or of the
+// global element it references.
+func (g *GoWSDL) elementDefault(el *XSDElement) string {
+	if el.Ref != "" {
+		if s := g.symbols.lookupElement(resolveQName(el.Ref, g.currentSchema.Xmlns)); s != nil {
+			el = s.decl.(*XSDElement)
+		}
+	}
+	if el.Fixed != "" {
+		return el.Fixed
+	}
+	return el.Default
+}
+
+// fieldDefaults returns the default and fixed values of the fields of a
+// struct, prefix being the expression of the struct. Repeated elements,
+// choices and polymorphic elements have no default value.
+func (g *GoWSDL) fieldDefaults(prefix string, fields []*contentField) []*defaultValue {
+	var elements []*XSDElement
+	for _, field := range fields {
+		if field.Choice == nil && field.Polymorphic == nil {
+			elements = append(elements, field.Element)
+		}
+	}
+	return g.elementDefaults(prefix, elements)
+}
+
+// elementDefaults returns the default and fixed values of the fields of the
+// elements, as generated by the Element template.
+func (g *GoWSDL) elementDefaults(prefix string, elements []*XSDElement) []*defaultValue {
+	var values []*defaultValue
+	for _, el := range elements {
+		if el.Repeated() {
+			continue
+		}
+		if value := g.elementDefault(el); value != "" {
+			values = append(values, &defaultValue{Ref: "&" + prefix + g.elementFieldName(el), Value: value})
+			continue
+		}
+		if el.Type == "" && el.Ref == "" && el.ComplexType != nil {
+			values = append(values, g.inlineDefaults(prefix+g.elementFieldName(el)+".", el.ComplexType)...)
+		}
+	}
+	return values
+}
+
+// inlineDefaults returns the default and fixed values of the fields of the
+// anonymous struct generated for a local complex type, whose embedded base
+// type, if any, is left unset.
+func (g *GoWSDL) inlineDefaults(prefix string, ct *XSDComplexType) []*defaultValue {
+	switch {
+	case ct.ComplexContent.Extension.Base != "":
+		ext := ct.ComplexContent.Extension
+		return append(g.elementDefaults(prefix, ext.Elements()), attributeDefaults(prefix, ext.Attributes)...)
+	case ct.SimpleContent.Extension.Base != "":
+		return attributeDefaults(prefix, ct.SimpleContent.Extension.Attributes)
+	case ct.SimpleContent.Restriction.Base != "":
+		return attributeDefaults(prefix, g.contentAttributes(ct))
+	case ct.ComplexContent.Restriction.Base != "":
+		return append(g.elementDefaults(prefix, ct.ComplexContent.Restriction.Elements()), attributeDefaults(prefix, g.contentAttributes(ct))...)
+	}
+	return append(g.elementDefaults(prefix, ct.Elements()), attributeDefaults(prefix, ct.Attributes)...)
+}
+
+// attributeDefaults returns the default values of the fields of the
+// attributes, as generated by the Attributes template.
+func attributeDefaults(prefix string, attributes []*XSDAttribute) []*defaultValue {
+	var values []*defaultValue
+	for _, attr := range attributes {
+		if attr.Default != "" && attr.Fixed == "" {
+			values = append(values, &defaultValue{Ref: "&" + prefix + makePublic(normalize(attr.Name)), Value: attr.Default})
+		}
+	}
+	return values
+}
@@ -0,0 +1,248 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SetStrictEnums makes the MarshalText and UnmarshalText methods of the
+// generated enumerations reject the values which are not enumerated by the
+// schema with a *soap.EnumerationError. By default they are accepted, as
+// services often return values added after their schemas were published.
+func (g *GoWSDL) SetStrictEnums(strict bool) {
+	g.strictEnums = strict
+}
+
+// enumeration are the constants generated for the values enumerated by a
+// restriction.
+type enumeration struct {
+	TypeName string
+	// Kind is the kind of the Go type of the values: string, int, uint,
+	// float, decimal or integer.
+	Kind string
+	// GoType is the Go type of the values, and Parse the function of the
+	// soap package parsing them when they are of the decimal or integer kind.
+	// Their values cannot be constants, and are variables built with it.
+	GoType string
+	Parse  string
+	// Typed is set when the constants have the type TypeName, and Methods
+	// when the type has the methods of enumerations, which requires a
+	// constant for each value.
+	Typed   bool
+	Methods bool
+	Strict  bool
+	Values  []*enumerationValue
+	// Distinct are the names of the constants of distinct values.
+	Distinct []string
+}
+
+type enumerationValue struct {
+	Name    string
+	Literal string
+	Doc     string
+}
+
+// goKinds are the kinds of the basic Go types of simple types whose values
+// can be constants, or variables for the exact numbers of the soap package.
+var goKinds = map[string]string{
+	"string":  "string",
+	"int":     "int",
+	"int8":    "int",
+	"int16":   "int",
+	"int32":   "int",
+	"int64":   "int",
+	"byte":    "uint",
+	"uint":    "uint",
+	"uint8":   "uint",
+	"uint16":  "uint",
+	"uint32":  "uint",
+	"uint64":  "uint",
+	"float32": "float",
+	"float64": "float",
+	// exact types of the soap package
+	"soap.XSDDecimal": "decimal",
+	"soap.XSDInteger": "integer",
+	// string types declared by the header
+	"AnyURI": "string",
+	"NCName": "string",
+}
+
+// simpleTypeEnumeration returns the enumeration of the type named typeName
+// generated for the simple type, or nil if it enumerates no values.
+func (g *GoWSDL) simpleTypeEnumeration(typeName string, st *XSDSimpleType) *enumeration {
+	if len(st.Restriction.Enumeration) == 0 || st.List.ItemType != "" ||
+		st.Union.MemberTypes != "" || st.Union.SimpleType != nil || st.Restriction.Base == "" {
+		return nil
+	}
+	goType := g.basicGoType(st.Restriction.Base, make(map[*symbol]bool))
+	e := g.enumeration(typeName, goType, st.Restriction.Enumeration)
+	if e == nil {
+		log.Printf("[WARN] enumeration of %s is not generated: %s values cannot be constants", typeName, st.Restriction.Base)
+		return nil
+	}
+	e.Typed = true
+	e.Methods = e.Methods && len(e.Values) > 0
+	e.Strict = g.strictEnums
+	return e
+}
+
+// simpleContentEnumeration returns the untyped constants of the values
+// enumerated by the restriction of the simple content of the struct named
+// typeName.
+func (g *GoWSDL) simpleContentEnumeration(typeName string, ct *XSDComplexType) *enumeration {
+	restriction := ct.SimpleContent.Restriction
+	if len(restriction.Enumeration) == 0 {
+		return nil
+	}
+	base := restriction.Base
+	if st := restriction.SimpleType; st != nil && st.Restriction.Base != "" {
+		base = st.Restriction.Base
+	}
+	e := g.enumeration(typeName, g.basicGoType(base, make(map[*symbol]bool)), restriction.Enumeration)
+	if e == nil {
+		log.Printf("[WARN] enumeration of %s is not generated: %s values cannot be constants", typeName, base)
+		return nil
+	}
+	e.Methods = false
+	return e
+}
+
+// enumeration returns the constants of the values, of the basic Go type
+// goType, or nil if they cannot be constants.
+func (g *GoWSDL) enumeration(typeName, goType string, values []XSDRestrictionValue) *enumeration {
+	kind := goKinds[goType]
+	if kind == "" {
+		return nil
+	}
+
+	e := &enumeration{TypeName: typeName, Kind: kind, GoType: goType, Methods: true}
+	switch kind {
+	case "decimal":
+		e.Parse = "ParseXsdDecimal"
+	case "integer":
+		e.Parse = "ParseXsdInteger"
+	}
+	distinct := make(map[string]bool)
+	for _, value := range values {
+		literal, err := goLiteral(kind, goType, value.Value)
+		if err != nil {
+			log.Printf("[WARN] value %q of %s is not generated: %v", value.Value, typeName, err)
+			e.Methods = false
+			continue
+		}
+		name := g.uniqueIdentifier(typeName + g.makePublicFn(replaceReservedWords(value.Value)))
+		e.Values = append(e.Values, &enumerationValue{Name: name, Literal: literal, Doc: value.Doc})
+		if !distinct[literal] {
+			distinct[literal] = true
+			e.Distinct = append(e.Distinct, name)
+		}
+	}
+	return e
+}
+
+// decimalPattern is the lexical space of xsd:decimal.
+var decimalPattern = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+
+// goLiteral returns the Go literal of an enumerated value of the basic Go
+// type goType, of the given kind. Decimals and integers are quoted lexical
+// representations, the canonical one for integers.
+func goLiteral(kind, goType, value string) (string, error) {
+	bits := 64
+	if n := strings.TrimLeft(goType, "abcdefghijklmnopqrstuvwxyz"); n != "" {
+		bits, _ = strconv.Atoi(n)
+	} else if goType == "byte" {
+		bits = 8
+	}
+
+	switch kind {
+	case "int":
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, bits)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(n, 10), nil
+	case "uint":
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, bits)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(n, 10), nil
+	case "float":
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), bits)
+		if err != nil {
+			return "", err
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", strconv.ErrRange
+		}
+		return strconv.FormatFloat(f, 'g', -1, bits), nil
+	case "decimal":
+		s := strings.TrimSpace(value)
+		if !decimalPattern.MatchString(s) {
+			return "", fmt.Errorf("invalid xsd:decimal %q", s)
+		}
+		return strconv.Quote(s), nil
+	case "integer":
+		n, ok := new(big.Int).SetString(strings.TrimSpace(value), 10)
+		if !ok {
+			return "", fmt.Errorf("invalid xsd:integer %q", strings.TrimSpace(value))
+		}
+		return strconv.Quote(n.String()), nil
+	}
+	return strconv.Quote(value), nil
+}
+
+// basicGoType returns the basic Go type of the values of the simple type or
+// of the complex type with simple content named xsdType, or an empty string
+// if they are not of a basic type.
+func (g *GoWSDL) basicGoType(xsdType string, visiting map[*symbol]bool) string {
+	if g.isMappedType(xsdType) {
+		return ""
+	}
+	name, resolved := resolveQName(xsdType, g.currentSchema.Xmlns)
+	if !resolved || builtinNamespaces[name.Space] {
+		return removePointerFromType(toGoType(xsdType, false))
+	}
+	s := g.symbols.lookupType(name, resolved)
+	if s == nil || visiting[s] {
+		return ""
+	}
+	visiting[s] = true
+	defer delete(visiting, s)
+
+	schema := g.currentSchema
+	g.currentSchema = s.schema
+	defer func() { g.currentSchema = schema }()
+
+	switch decl := s.decl.(type) {
+	case *XSDSimpleType:
+		switch {
+		case decl.List.ItemType != "":
+			return ""
+		case decl.Union.MemberTypes != "" || decl.Union.SimpleType != nil:
+			return "string"
+		case decl.Restriction.Base != "":
+			return g.basicGoType(decl.Restriction.Base, visiting)
+		}
+	case *XSDComplexType:
+		content := decl.SimpleContent
+		switch {
+		case content.Extension.Base != "":
+			return g.basicGoType(content.Extension.Base, visiting)
+		case content.Restriction.SimpleType != nil && content.Restriction.SimpleType.Restriction.Base != "":
+			return g.basicGoType(content.Restriction.SimpleType.Restriction.Base, visiting)
+		case content.Restriction.Base != "":
+			return g.basicGoType(content.Restriction.Base, visiting)
+		}
+	}
+	return ""
+}
@@ -25,7 +25,8 @@ import (
 )
 
 const maxRecursion uint8 = 120
-// GoWSDL defines the struct for WSDL generator. 
+
+// GoWSDL defines the struct for WSDL generator.
 type GoWSDL struct {
 	loc                   *Location
 	rawWSDL               []byte
@@ -34,20 +35,47 @@ type GoWSDL struct {
 	makePublicFn          func(string) string
 	wsdl                  *WSDL
 	resolvedXSDExternals  map[string]bool
+	resolvedWSDLImports   map[string]bool
 	currentRecursionLevel uint8
-	currentNamespace      string
-	resolveCollisions     map[string]string
+	currentSchema         *XSDSchema
+	symbols               *symbolTable
+	rpcWrappers           map[rpcWrapperKey]*XSDElement
+	packagePerNamespace   bool
+	importPath            string
+	namespaceImports      map[string]string
+	packages              map[string]*nsPackage
+	packageRoot           string
+	currentPackage        *nsPackage
+	typeImports           importSet
+	operationsImports     importSet
+	serverImports         importSet
+	flatChoices           bool
+	strictEnums           bool
+	typeMapping           map[xml.Name]TypeMapping
+	mappedPackages        map[string]*nsPackage
+	zeroValueOptionals    bool
+	identifiers           map[string]bool
+	fixedAttributes       []*fixedAttribute
+	fixedAttributeTypes   map[[2]string]*fixedAttribute
+}
+
+// fileHeader is the data of the header templates.
+type fileHeader struct {
+	Pkg     string
+	Imports []string
 }
 
-// Method setNS sets (and returns) the currently active XML namespace.
-func (g *GoWSDL) setNS(ns string) string {
-	g.currentNamespace = ns
-	return ns
+// Method setSchema sets the schema whose types are being generated, and
+// returns its target namespace. Type references are resolved using the
+// namespace prefixes declared by the current schema.
+func (g *GoWSDL) setSchema(schema *XSDSchema) string {
+	g.currentSchema = schema
+	return schema.TargetNamespace
 }
 
-// Method setNS returns the currently active XML namespace.
+// Method getNS returns the currently active XML namespace.
 func (g *GoWSDL) getNS() string {
-	return g.currentNamespace
+	return g.currentSchema.TargetNamespace
 }
 
 var cacheDir = filepath.Join(os.TempDir(), "gowsdl-cache")
@@ -125,70 +153,53 @@ func NewGoWSDL(file, pkg string, ignoreTLS bool, exportAllTypes bool) (*GoWSDL,
 // Start initiaties the code generation process by starting two goroutines: one
 // to generate types and another one to generate operations.
 func (g *GoWSDL) Start() (map[string][]byte, error) {
-	gocode := make(map[string][]byte)
-	
-	g.resolveCollisions = make(map[string]string)
-
 	err := g.unmarshal()
 	if err != nil {
 		return nil, err
 	}
 
-	// resolve complex type name collision
-	{
-		seen := map[string]int{}
-		for _, schema := range g.wsdl.Types.Schemas {
-			for _, complexType := range schema.ComplexTypes {
-				seen[complexType.Name] += 1
-			}
-			for _, simpleType := range schema.SimpleType {
-				seen[simpleType.Name] += 1
-			}
-		}
-		for k, v := range seen {
-			if v < 2 {
-				delete(seen, k)
-			}
-		}
-		for i := range g.wsdl.Types.Schemas {
-			schema := g.wsdl.Types.Schemas[i]
-			for j := range schema.ComplexTypes {
-				complexType := schema.ComplexTypes[j]
-				if num := seen[complexType.Name]; num > 0 {
-					org := complexType.Name
-					update := fmt.Sprintf("%s%d", org, num)
-					g.resolveCollisions[fmt.Sprintf("%s/%s", schema.TargetNamespace, org)] = update
-					complexType.Name = update
-					seen[org] -= 1
-				}
-			}
-			
-			for j := range schema.SimpleType {
-				simpleType := schema.SimpleType[j]
-				if num := seen[simpleType.Name]; num > 0 {
-					org := simpleType.Name
-					update := fmt.Sprintf("%s%d", org, num)
-					g.resolveCollisions[fmt.Sprintf("%s/%s", schema.TargetNamespace, org)] = update
-					simpleType.Name = update
-					seen[org] -= 1
-				}
-			}
-		}
-	}
+	g.genRPCWrappers()
+
+	return g.generate()
+}
+
+// generate generates the code of the unmarshalled WSDL.
+func (g *GoWSDL) generate() (map[string][]byte, error) {
+	gocode := make(map[string][]byte)
+	var err error
+
+	g.symbols = newSymbolTable(g.wsdl.Types.Schemas)
+	g.symbols.removeMappedTypes(g.typeMapping)
 
 	// Process WSDL nodes
 	for _, schema := range g.wsdl.Types.Schemas {
-		newTraverser(schema, g.wsdl.Types.Schemas, g.resolveCollisions).traverse()
+		newTraverser(schema, g.wsdl.Types.Schemas, g.symbols).traverse()
+	}
+	g.symbols.indexDerivedTypes()
+	g.symbols.indexSubstitutionGroups()
+
+	if g.packagePerNamespace {
+		g.genPackages()
+		g.symbols.assignGoNames(func(ns string) string {
+			return g.packageOf(ns).importPath
+		})
 	}
 
 	var wg sync.WaitGroup
+	var types, operations, server []byte
+	var packages map[string][]byte
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		var err error
 
-		gocode["types"], err = g.genTypes()
+		if g.packagePerNamespace {
+			packages, err = g.genPackageTypes()
+		} else {
+			g.typeImports = make(importSet)
+			types, err = g.genTypes(g.wsdl.Types)
+		}
 		if err != nil {
 			log.Println("genTypes", "error", err)
 		}
@@ -199,7 +210,7 @@ func (g *GoWSDL) Start() (map[string][]byte, error) {
 		defer wg.Done()
 		var err error
 
-		gocode["operations"], err = g.genOperations()
+		operations, err = g.genOperations()
 		if err != nil {
 			log.Println(err)
 		}
@@ -210,7 +221,7 @@ func (g *GoWSDL) Start() (map[string][]byte, error) {
 		defer wg.Done()
 		var err error
 
-		gocode["server"], err = g.genServer()
+		server, err = g.genServer()
 		if err != nil {
 			log.Println(err)
 		}
@@ -218,6 +229,13 @@ func (g *GoWSDL) Start() (map[string][]byte, error) {
 
 	wg.Wait()
 
+	gocode["types"] = types
+	gocode["operations"] = operations
+	gocode["server"] = server
+	for dir, code := range packages {
+		gocode[PackagePrefix+dir] = code
+	}
+
 	gocode["header"], err = g.genHeader()
 	if err != nil {
 		log.Println(err)
@@ -250,8 +268,7 @@ func (g *GoWSDL) unmarshal() error {
 		return err
 	}
 
-	g.wsdl = new(WSDL)
-	err = xml.Unmarshal(data, g.wsdl)
+	g.wsdl, err = parseWSDL(data)
 	if err != nil {
 		return err
 	}
@@ -264,9 +281,114 @@ func (g *GoWSDL) unmarshal() error {
 		}
 	}
 
+	g.resolvedWSDLImports = make(map[string]bool)
+	return g.resolveWSDLImports(g.wsdl, g.loc, map[string]bool{g.loc.String(): true})
+}
+
+// resolveWSDLImports fetches the documents imported by w, relative to its
+// location, and merges their definitions into the generated WSDL.
+//
+// visiting holds the locations of the documents being imported, used to
+// detect import cycles.
+func (g *GoWSDL) resolveWSDLImports(w *WSDL, loc *Location, visiting map[string]bool) error {
+	for _, impt := range w.Imports {
+		if impt.Location == "" {
+			log.Printf("[WARN] Don't know where to find WSDL for %s", impt.Namespace)
+			continue
+		}
+
+		location, err := loc.Parse(impt.Location)
+		if err != nil {
+			return err
+		}
+		key := location.String()
+		if visiting[key] {
+			log.Printf("[WARN] WSDL import cycle detected, %s imports %s again", loc, key)
+			continue
+		}
+		if g.resolvedWSDLImports[key] {
+			continue
+		}
+		g.resolvedWSDLImports[key] = true
+
+		data, err := g.fetchFile(location)
+		if err != nil {
+			return err
+		}
+
+		// wsdl:import is sometimes used to import plain schemas
+		if root := documentRoot(data); root.Space == xmlschema11 && root.Local == "schema" {
+			schema := new(XSDSchema)
+			if err := xml.Unmarshal(data, schema); err != nil {
+				return err
+			}
+			if err := g.resolveXSDExternals(schema, location); err != nil {
+				return err
+			}
+			g.wsdl.Types.Schemas = append(g.wsdl.Types.Schemas, schema)
+			continue
+		}
+
+		imported, err := parseWSDL(data)
+		if err != nil {
+			return err
+		}
+
+		visiting[key] = true
+		err = g.resolveWSDLImports(imported, location, visiting)
+		delete(visiting, key)
+		if err != nil {
+			return err
+		}
+
+		for _, schema := range imported.Types.Schemas {
+			if err := g.resolveXSDExternals(schema, location); err != nil {
+				return err
+			}
+		}
+
+		g.wsdl.Types.Schemas = append(g.wsdl.Types.Schemas, imported.Types.Schemas...)
+		g.wsdl.Messages = append(g.wsdl.Messages, imported.Messages...)
+		g.wsdl.PortTypes = append(g.wsdl.PortTypes, imported.PortTypes...)
+		g.wsdl.Binding = append(g.wsdl.Binding, imported.Binding...)
+		g.wsdl.Service = append(g.wsdl.Service, imported.Service...)
+	}
+
 	return nil
 }
 
+// parseWSDL decodes a WSDL 1.1 or WSDL 2.0 document. WSDL 2.0 descriptions
+// are converted to the WSDL 1.1 model used by the templates.
+func parseWSDL(data []byte) (*WSDL, error) {
+	if documentRoot(data).Space == wsdl20Namespace {
+		w := new(WSDL2)
+		if err := xml.Unmarshal(data, w); err != nil {
+			return nil, err
+		}
+		return w.toWSDL(), nil
+	}
+
+	w := new(WSDL)
+	if err := xml.Unmarshal(data, w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// documentRoot returns the name of the root element of an XML document.
+func documentRoot(data []byte) xml.Name {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return xml.Name{}
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name
+		}
+	}
+}
+
 func (g *GoWSDL) resolveXSDExternals(schema *XSDSchema, loc *Location) error {
 	download := func(base *Location, ref string) error {
 		location, err := base.Parse(ref)
@@ -330,9 +452,16 @@ func (g *GoWSDL) resolveXSDExternals(schema *XSDSchema, loc *Location) error {
 	return nil
 }
 
-func (g *GoWSDL) genTypes() ([]byte, error) {
+func (g *GoWSDL) genTypes(types WSDLType) ([]byte, error) {
+	g.identifiers = g.packageIdentifiers()
+	g.fixedAttributes = nil
+	g.fixedAttributeTypes = make(map[[2]string]*fixedAttribute)
 	funcMap := template.FuncMap{
-		"toGoType":                 toGoType,
+		"toGoType":                 g.toGoType,
+		"goTypeName":               g.symbols.goName,
+		"elementFieldType":         g.elementFieldType,
+		"elementXMLName":           g.elementXMLName,
+		"attributeXMLName":         attributeXMLName,
 		"stripns":                  stripns,
 		"replaceReservedWords":     replaceReservedWords,
 		"replaceAttrReservedWords": replaceAttrReservedWords,
@@ -344,13 +473,42 @@ func (g *GoWSDL) genTypes() ([]byte, error) {
 		"goString":                 goString,
 		"findNameByType":           g.findNameByType,
 		"removePointerFromType":    removePointerFromType,
-		"setNS":                    g.setNS,
+		"setSchema":                g.setSchema,
 		"getNS":                    g.getNS,
+		"soapArrayItemType":        g.soapArrayItemType,
+		"usesSOAPEncoding":         g.usesSOAPEncoding,
+		"structContent":            g.structContent,
+		"decodesChoices":           g.decodesChoices,
+		"contentAttributes":        g.contentAttributes,
+		"simpleContentType":        g.simpleContentType,
+		"typeHierarchy":            g.typeHierarchy,
+		"marshalsXSIType":          g.marshalsXSIType,
+		"qnameMethods":             g.qnameMethods,
+		"marshalsQNames":           g.marshalsQNames,
+		"qnameAttributes":          g.qnameAttributesOf,
+		"substitution":             g.substitution,
+		"rpcWrapper":               g.rpcWrapper,
+		"simpleTypeValidation":     g.simpleTypeValidation,
+		"structValidation":         g.structValidation,
+		"validatesType":            g.validatesType,
+		"simpleTypeEnumeration":    g.simpleTypeEnumeration,
+		"fixedAttributeType":       g.fixedAttributeType,
+		"structConstructor":        g.structConstructor,
+		"constructorOf":            g.constructorOf,
+		"mapsType":                 g.mapsType,
+		"isMappedType":             g.isMappedType,
+		"restrictsMappedType":      g.restrictsMappedType,
+		"delegatesMarshalling":     delegatesMarshalling,
+		"delegation":               newDelegation,
 	}
 
 	data := new(bytes.Buffer)
 	tmpl := template.Must(template.New("types").Funcs(funcMap).Parse(typesTmpl))
-	err := tmpl.Execute(data, g.wsdl.Types)
+	err := tmpl.Execute(data, types)
+	if err != nil {
+		return nil, err
+	}
+	err = tmpl.ExecuteTemplate(data, "FixedAttributes", g.fixedAttributes)
 	if err != nil {
 		return nil, err
 	}
@@ -358,7 +516,39 @@ func (g *GoWSDL) genTypes() ([]byte, error) {
 	return data.Bytes(), nil
 }
 
+// genPackageTypes generates the source of each namespace package, keyed by
+// package directory.
+func (g *GoWSDL) genPackageTypes() (map[string][]byte, error) {
+	packages := make(map[string][]byte)
+	for _, schema := range g.wsdl.Types.Schemas {
+		p := g.packageOf(schema.TargetNamespace)
+		if _, ok := packages[p.dir]; ok || p.dir == "" {
+			continue
+		}
+
+		g.currentPackage = p
+		g.typeImports = make(importSet)
+		types, err := g.genTypes(WSDLType{Schemas: p.schemas})
+		if err != nil {
+			return nil, err
+		}
+
+		header := new(bytes.Buffer)
+		tmpl := template.Must(template.New("types_header").Parse(typesHeaderTmpl))
+		err = tmpl.Execute(header, fileHeader{Pkg: p.name, Imports: g.typeImports.specs()})
+		if err != nil {
+			return nil, err
+		}
+
+		packages[p.dir] = append(header.Bytes(), types...)
+	}
+	g.currentPackage = nil
+
+	return packages, nil
+}
+
 func (g *GoWSDL) genOperations() ([]byte, error) {
+	g.operationsImports = make(importSet)
 	funcMap := template.FuncMap{
 		"toGoType":             toGoType,
 		"stripns":              stripns,
@@ -367,8 +557,13 @@ func (g *GoWSDL) genOperations() ([]byte, error) {
 		"makePublic":           g.makePublicFn,
 		"makePrivate":          makePrivate,
 		"findType":             g.findType,
-		"findSOAPAction":       g.findSOAPAction,
-		"findServiceAddress":   g.findServiceAddress,
+		"findGoType": func(portType string, op *WSDLOperation, direction string) string {
+			return g.findGoType(portType, op, direction, g.operationsImports)
+		},
+		"findSOAPAction":     g.findSOAPAction,
+		"findSOAPVersion":    g.findSOAPVersion,
+		"isSOAPEncoded":      g.isSOAPEncoded,
+		"findServiceAddress": g.findServiceAddress,
 	}
 
 	data := new(bytes.Buffer)
@@ -382,14 +577,18 @@ func (g *GoWSDL) genOperations() ([]byte, error) {
 }
 
 func (g *GoWSDL) genServer() ([]byte, error) {
+	g.serverImports = make(importSet)
 	funcMap := template.FuncMap{
 		"toGoType":             toGoType,
 		"stripns":              stripns,
 		"replaceReservedWords": replaceReservedWords,
 		"makePublic":           g.makePublicFn,
 		"findType":             g.findType,
-		"findSOAPAction":       g.findSOAPAction,
-		"findServiceAddress":   g.findServiceAddress,
+		"findGoType": func(portType string, op *WSDLOperation, direction string) string {
+			return g.findGoType(portType, op, direction, g.serverImports)
+		},
+		"findSOAPAction":     g.findSOAPAction,
+		"findServiceAddress": g.findServiceAddress,
 	}
 
 	data := new(bytes.Buffer)
@@ -413,9 +612,15 @@ func (g *GoWSDL) genHeader() ([]byte, error) {
 		"comment":              comment,
 	}
 
+	imports := g.operationsImports.specs()
+	if !g.packagePerNamespace {
+		// the types are generated in the same file
+		imports = append(imports, g.typeImports.specs()...)
+	}
+
 	data := new(bytes.Buffer)
 	tmpl := template.Must(template.New("header").Funcs(funcMap).Parse(headerTmpl))
-	err := tmpl.Execute(data, g.pkg)
+	err := tmpl.Execute(data, fileHeader{Pkg: g.pkg, Imports: imports})
 	if err != nil {
 		return nil, err
 	}
@@ -435,7 +640,7 @@ func (g *GoWSDL) genServerHeader() ([]byte, error) {
 
 	data := new(bytes.Buffer)
 	tmpl := template.Must(template.New("server_header").Funcs(funcMap).Parse(serverHeaderTmpl))
-	err := tmpl.Execute(data, g.pkg)
+	err := tmpl.Execute(data, fileHeader{Pkg: g.pkg, Imports: g.serverImports.specs()})
 	if err != nil {
 		return nil, err
 	}
@@ -546,31 +751,94 @@ func goString(s string) string {
 	return strings.ReplaceAll(s, "\"", "\\\"")
 }
 
+// xsd2GoTypes maps the built-in types of XML Schema, by their lower case
+// name, to Go types.
 var xsd2GoTypes = map[string]string{
-	"string":             "string",
-	"token":              "string",
+	"anytype":       "AnyType",
+	"anysimpletype": "string",
+	"anyatomictype": "string",
+
+	"string":           "string",
+	"normalizedstring": "string",
+	"token":            "string",
+	"language":         "string",
+	"name":             "string",
+	"ncname":           "NCName",
+	"id":               "string",
+	"idref":            "string",
+	"idrefs":           "soap.XSDList",
+	"entity":           "string",
+	"entities":         "soap.XSDList",
+	"nmtoken":          "string",
+	"nmtokens":         "soap.XSDList",
+	"anyuri":           "AnyURI",
+	"qname":            "soap.QName",
+	"notation":         "soap.QName",
+
+	"boolean":            "bool",
 	"float":              "float32",
 	"double":             "float64",
-	"decimal":            "float64",
-	"integer":            "int32",
+	"decimal":            "soap.XSDDecimal",
+	"integer":            "soap.XSDInteger",
+	"nonpositiveinteger": "soap.XSDInteger",
+	"negativeinteger":    "soap.XSDInteger",
+	"nonnegativeinteger": "soap.XSDInteger",
+	"positiveinteger":    "soap.XSDInteger",
+	"long":               "int64",
 	"int":                "int32",
 	"short":              "int16",
 	"byte":               "int8",
-	"long":               "int64",
-	"boolean":            "bool",
-	"datetime":           "soap.XSDDateTime",
-	"date":               "soap.XSDDate",
-	"time":               "soap.XSDTime",
-	"base64binary":       "[]byte",
-	"hexbinary":          "[]byte",
+	"unsignedlong":       "uint64",
 	"unsignedint":        "uint32",
-	"nonnegativeinteger": "uint32",
 	"unsignedshort":      "uint16",
 	"unsignedbyte":       "byte",
-	"unsignedlong":       "uint64",
-	"anytype":            "AnyType",
-	"ncname":             "NCName",
-	"anyuri":             "AnyURI",
+
+	"datetime":          "soap.XSDDateTime",
+	"datetimestamp":     "soap.XSDDateTime",
+	"date":              "soap.XSDDate",
+	"time":              "soap.XSDTime",
+	"duration":          "soap.XSDDuration",
+	"daytimeduration":   "soap.XSDDuration",
+	"yearmonthduration": "soap.XSDDuration",
+	"gyear":             "soap.XSDGYear",
+	"gyearmonth":        "soap.XSDGYearMonth",
+	"gmonth":            "soap.XSDGMonth",
+	"gmonthday":         "soap.XSDGMonthDay",
+	"gday":              "soap.XSDGDay",
+
+	"base64binary": "[]byte",
+	"hexbinary":    "[]byte",
+}
+
+// delegatingTypes are the runtime types of the soap package whose methods are
+// not promoted to the types declared with them as underlying type, which
+// delegate their marshalling to them.
+var delegatingTypes = map[string]bool{
+	"soap.XSDDateTime": true,
+	"soap.XSDDate":     true,
+	"soap.XSDTime":     true,
+	"soap.QName":       true,
+	"soap.XSDList":     true,
+}
+
+func delegatesMarshalling(goType string) bool {
+	return delegatingTypes[goType]
+}
+
+// delegation are the marshalling methods of a simple type delegating to the
+// runtime type it is declared with.
+type delegation struct {
+	TypeName string
+	Type     string
+}
+
+// newDelegation returns the delegation of the simple type typeName declared
+// with goType, or nil if it needs none.
+func newDelegation(typeName, goType string) *delegation {
+	if !delegatesMarshalling(goType) {
+		return nil
+	}
+	return &delegation{TypeName: typeName, Type: goType}
 }
 
 func removeNS(xsdType string) string {
@@ -606,80 +874,275 @@ func toGoType(xsdType string, nillable bool) string {
 	return "*" + replaceReservedWords(makePublic(t))
 }
 
+// toGoType returns the Go type of a type reference of the current schema.
+// Types of the XML Schema namespace map to Go built-in types, while the other
+// ones map to the Go name of their declaration.
+func (g *GoWSDL) toGoType(xsdType string, nillable bool) string {
+	if m, ok := g.mappedType(xsdType); ok {
+		goType := g.mappedGoType(m)
+		if nillable && !strings.HasPrefix(goType, "*") && !strings.HasPrefix(goType, "[]") {
+			goType = "*" + goType
+		}
+		return goType
+	}
+
+	name, resolved := resolveQName(xsdType, g.currentSchema.Xmlns)
+	if resolved && schemaNamespaces[name.Space] && xsd2GoTypes[strings.ToLower(name.Local)] == "" {
+		// types of other versions of XML Schema, generated as their lexical
+		// representation rather than as undefined types
+		return toGoType("string", nillable)
+	}
+	if !resolved || builtinNamespaces[name.Space] {
+		return toGoType(xsdType, nillable)
+	}
+
+	if s := g.symbols.lookupType(name, resolved); s != nil {
+		return "*" + g.qualifier(s.name.Space, g.currentPackage, g.typeImports) + replaceReservedWords(makePublic(s.goName))
+	}
+	return "*" + replaceReservedWords(makePublic(name.Local))
+}
+
+// elementGoType returns the Go type of an element reference of the current
+// schema.
+func (g *GoWSDL) elementGoType(ref string) string {
+	name, resolved := resolveQName(ref, g.currentSchema.Xmlns)
+	if s := g.symbols.lookupElement(name, resolved); s != nil {
+		return "*" + g.qualifier(s.name.Space, g.currentPackage, g.typeImports) + replaceReservedWords(makePublic(s.goName))
+	}
+	return toGoType(ref, false)
+}
+
+// elementXMLName returns the name used in the XML tags of an element
+// reference of the current schema. Global elements are always qualified by
+// the target namespace of their schema.
+func (g *GoWSDL) elementXMLName(ref string) string {
+	name, resolved := resolveQName(ref, g.currentSchema.Xmlns)
+	if s := g.symbols.lookupElement(name, resolved); s != nil {
+		name = s.name
+	}
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + " " + name.Local
+}
+
+// attributeXMLName returns the name used in the XML tag of an attribute,
+// qualified when it references a global attribute.
+func attributeXMLName(attr *XSDAttribute) string {
+	if attr.namespace == "" {
+		return attr.Name
+	}
+	return attr.namespace + " " + attr.Name
+}
+
 func removePointerFromType(goType string) string {
 	return regexp.MustCompile("^\\s*\\*").ReplaceAllLiteralString(goType, "")
 }
 
-// Given a message, finds its type.
+// findType returns the name of the type of the input or output of an
+// operation of a port type.
+func (g *GoWSDL) findType(portType string, op *WSDLOperation, direction string) string {
+	name, _ := g.messageType(portType, op, direction)
+	return name
+}
+
+// findGoType returns the Go type of the input or output of an operation of a
+// port type, qualified by its package when generating one package per
+// namespace.
+func (g *GoWSDL) findGoType(portType string, op *WSDLOperation, direction string, imports importSet) string {
+	name, s := g.messageType(portType, op, direction)
+	goType := g.makePublicFn(replaceReservedWords(name))
+	if s != nil {
+		goType = g.qualifier(s.name.Space, nil, imports) + goType
+	}
+	return goType
+}
+
+// messageType returns the name of the type of the input or output message of
+// an operation of a port type, and its declaration if it is known.
 //
-// I'm not very proud of this function but
-// it works for now and performance doesn't
-// seem critical at this point
-func (g *GoWSDL) findType(message string) string {
-	message = stripns(message)
-
-	for _, msg := range g.wsdl.Messages {
-		if msg.Name != message {
-			continue
+// Assumes document/literal wrapped WS-I: the type is the one of the element
+// referenced by the first part.
+func (g *GoWSDL) messageType(portType string, op *WSDLOperation, direction string) (string, *symbol) {
+	// RPC style messages are wrapped in an element named after the operation
+	if wrapper, ok := g.rpcWrappers[rpcWrapperKey{portType: portType, operation: op.Name, direction: direction}]; ok {
+		s := g.symbols.decls[wrapper]
+		return s.goName, s
+	}
+
+	message := op.Input.Message
+	if direction == outputDirection {
+		message = op.Output.Message
+	}
+	msg := g.findMessage(message)
+	if msg == nil {
+		return "", nil
+	}
+
+	if len(msg.Parts) == 0 {
+		// Message does not have parts. This could be a Port
+		// with HTTP binding, which is not currently supported.
+		log.Printf("[WARN] %s message doesn't have any parts, ignoring message...", msg.Name)
+		return "", nil
+	}
+
+	part := msg.Parts[0]
+	if part.Type != "" {
+		name, resolved := resolveQName(part.Type, msg.xmlns)
+		if s := g.symbols.lookupType(name, resolved); s != nil {
+			return s.goName, s
 		}
+		return name.Local, nil
+	}
 
-		// Assumes document/literal wrapped WS-I
-		if len(msg.Parts) == 0 {
-			// Message does not have parts. This could be a Port
-			// with HTTP binding or SOAP 1.2 binding, which are not currently
-			// supported.
-			log.Printf("[WARN] %s message doesn't have any parts, ignoring message...", msg.Name)
+	name, resolved := resolveQName(part.Element, msg.xmlns)
+	el := g.symbols.lookupElement(name, resolved)
+	if el == nil {
+		return "", nil
+	}
+	if elType := el.decl.(*XSDElement).Type; elType != "" {
+		name, resolved := resolveQName(elType, el.schema.Xmlns)
+		// elements of built-in and mapped types have their own named type
+		if _, mapped := g.typeMapping[name]; mapped || resolved && builtinNamespaces[name.Space] {
+			return el.goName, el
+		}
+		if s := g.symbols.lookupType(name, resolved); s != nil {
+			return s.goName, s
+		}
+		return name.Local, nil
+	}
+	return el.goName, el
+}
+
+// Given a complex type, check if there's an Element with that type, and return its name.
+func (g *GoWSDL) findNameByType(ct *XSDComplexType) string {
+	return newTraverser(nil, g.wsdl.Types.Schemas, g.symbols).findNameByType(ct)
+}
+
+// Given a port type, finds the binding used by the generated client.
+//
+// SOAP 1.1 bindings are preferred over SOAP 1.2 ones, and non SOAP bindings
+// (HTTP GET/POST) are only returned when nothing else is available.
+func (g *GoWSDL) findBinding(portType string) *WSDLBinding {
+	var found *WSDLBinding
+	for _, binding := range g.wsdl.Binding {
+		if strings.ToUpper(stripns(binding.Type)) != strings.ToUpper(portType) {
 			continue
 		}
 
-		part := msg.Parts[0]
-		if part.Type != "" {
-			return stripns(part.Type)
+		switch binding.soapVersion() {
+		case "1.1":
+			return binding
+		case "1.2":
+			if found == nil || found.soapVersion() == "" {
+				found = binding
+			}
+		default:
+			if found == nil {
+				found = binding
+			}
 		}
+	}
+	return found
+}
 
-		elRef := stripns(part.Element)
+// TODO(c4milo): Add support for namespaces instead of striping them out
+// TODO(c4milo): improve runtime complexity if performance turns out to be an issue.
+func (g *GoWSDL) findSOAPAction(operation, portType string) string {
+	binding := g.findBinding(portType)
+	if binding == nil {
+		return ""
+	}
 
-		for _, schema := range g.wsdl.Types.Schemas {
-			for _, el := range schema.Elements {
-				if strings.EqualFold(elRef, el.Name) {
-					if el.Type != "" {
-						return stripns(el.Type)
-					}
-					return el.Name
-				}
-			}
+	for _, soapOp := range binding.Operations {
+		if soapOp.Name == operation {
+			return binding.soapOperation(soapOp).SOAPAction
 		}
 	}
 	return ""
 }
 
-// Given a type, check if there's an Element with that type, and return its name.
-func (g *GoWSDL) findNameByType(name string) string {	
-	return newTraverser(nil, g.wsdl.Types.Schemas, g.resolveCollisions).findNameByType(name)
+// Given a port type, returns the SOAP version of the binding used by the
+// generated client.
+func (g *GoWSDL) findSOAPVersion(portType string) string {
+	if binding := g.findBinding(portType); binding != nil {
+		return binding.soapVersion()
+	}
+	return ""
 }
 
-// TODO(c4milo): Add support for namespaces instead of striping them out
-// TODO(c4milo): improve runtime complexity if performance turns out to be an issue.
-func (g *GoWSDL) findSOAPAction(operation, portType string) string {
+// Given a port type, reports whether the binding used by the generated client
+// serializes messages with the SOAP encoding (use="encoded").
+func (g *GoWSDL) isSOAPEncoded(portType string) bool {
+	binding := g.findBinding(portType)
+	return binding != nil && binding.isEncoded()
+}
+
+// usesSOAPEncoding reports whether any binding of the WSDL uses the SOAP
+// encoding, in which case generated types carry their schema type name.
+func (g *GoWSDL) usesSOAPEncoding() bool {
 	for _, binding := range g.wsdl.Binding {
-		if strings.ToUpper(stripns(binding.Type)) != strings.ToUpper(portType) {
-			continue
+		if binding.isEncoded() {
+			return true
 		}
+	}
+	return false
+}
+
+// soapArrayItemType returns the Go type of the items of a SOAP encoded array,
+// declared as a restriction of soapenc:Array, or an empty string if the complex
+// type is not such an array.
+func (g *GoWSDL) soapArrayItemType(ct *XSDComplexType) string {
+	restriction := ct.ComplexContent.Restriction
+	if stripns(restriction.Base) != "Array" {
+		return ""
+	}
 
-		for _, soapOp := range binding.Operations {
-			if soapOp.Name == operation {
-				return soapOp.SOAPOperation.SOAPAction
+	for _, attr := range restriction.Attributes {
+		if attr.ArrayType != "" {
+			// e.g. xsd:string[] or tns:Foo[5]
+			itemType, rank := attr.ArrayType, ""
+			if i := strings.Index(itemType, "["); i >= 0 {
+				itemType, rank = itemType[:i], itemType[i:]
+			}
+			if !oneDimensional(rank) {
+				log.Printf("[WARN] items of %s are generated as AnyType: arrays of rank %s are not supported", ct.Name, rank)
+				return "AnyType"
 			}
+			return g.toGoType(itemType, false)
 		}
 	}
-	return ""
+	for _, el := range restriction.Elements() {
+		if el.Type != "" {
+			return g.toGoType(el.Type, false)
+		}
+	}
+	return "AnyType"
+}
+
+// oneDimensional reports whether the rank suffix of a soapenc:arrayType, e.g.
+// [] or [5], is the one of a one-dimensional array, rather than the one of an
+// array of arrays, e.g. [][2], or of a multi-dimensional array, e.g. [,].
+func oneDimensional(rank string) bool {
+	if len(rank) < 2 || rank[0] != '[' || rank[len(rank)-1] != ']' {
+		return false
+	}
+	for _, c := range rank[1 : len(rank)-1] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 func (g *GoWSDL) findServiceAddress(name string) string {
 	for _, service := range g.wsdl.Service {
 		for _, port := range service.Ports {
 			if port.Name == name {
-				return port.SOAPAddress.Location
+				if port.SOAPAddress.Location != "" {
+					return port.SOAPAddress.Location
+				}
+				return port.SOAP12Address.Location
 			}
 		}
 	}
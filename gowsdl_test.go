@@ -16,6 +16,8 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -84,7 +86,7 @@ func TestAttributeRef(t *testing.T) {
 	Status	[]struct {
 		Value	string  ` + "`" + `xml:",chardata" json:"-,"` + "`" + `
 
-		Code	string	` + "`" + `xml:"http://www.mnb.hu/webservices/ code,attr,omitempty" json:"code,omitempty"` + "`" + `
+		Code	string	` + "`" + `xml:"code,attr,omitempty" json:"code,omitempty"` + "`" + `
 	}	` + "`" + `xml:"status,omitempty" json:"status,omitempty"` + "`" + `
 
 	ResponseCode	string	` + "`" + `xml:"http://www.mnb.hu/webservices/ responseCode,attr,omitempty" json:"responseCode,omitempty"` + "`" + `
@@ -202,6 +204,1642 @@ func TestDateTimeType(t *testing.T) {
 	}
 }
 
+func TestSOAP12Binding(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/soap12.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := g.findSOAPVersion("stockQuotePortType"); v != "1.2" {
+		t.Errorf("got SOAP version %q want %q", v, "1.2")
+	}
+	if action := g.findSOAPAction("GetLastTradePrice", "stockQuotePortType"); action != "http://example.com/GetLastTradePrice" {
+		t.Errorf("got SOAP action %q", action)
+	}
+	if addr := g.findServiceAddress("StockQuotePort"); addr != "http://example.com/stockquote12" {
+		t.Errorf("got service address %q", addr)
+	}
+	if !strings.Contains(string(resp["operations"]), "soap.WithSOAPVersion(soap.SOAP12)") {
+		t.Error("SOAP 1.2 client should select the SOAP 1.2 protocol")
+		t.Error(string(resp["operations"]))
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestSOAP11BindingPreferred(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/mnb-exchange.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := g.findSOAPVersion("mNBArfolyamServiceSoap"); v != "1.1" {
+		t.Errorf("got SOAP version %q want %q", v, "1.1")
+	}
+	if strings.Contains(string(resp["operations"]), "soap.SOAP12") {
+		t.Error("SOAP 1.1 binding should be preferred when both are available")
+	}
+}
+
+func TestRPCLiteralWrappers(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/rpc-literal.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "GetQuote")
+	if err != nil {
+		fmt.Println(string(resp["types"]))
+		t.Fatal(err)
+	}
+
+	expected := `type GetQuote struct {
+	XMLName	xml.Name	` + "`" + `xml:"urn:quotes:rpc getQuote"` + "`" + `
+
+	Symbol	string	` + "`" + `xml:"symbol" json:"symbol,omitempty"` + "`" + `
+
+	Currency	string	` + "`" + `xml:"currency" json:"currency,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getTypeDeclaration(resp, "Quote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(actual, "XMLName") {
+		t.Error("RPC part accessors should not name the part types")
+		t.Error(actual)
+	}
+
+	if _, err := getTypeDeclaration(resp, "Ping"); err != nil {
+		t.Error("operations without parts should have an empty wrapper: ", err)
+	}
+
+	ops := string(resp["operations"])
+	for _, sig := range []string{
+		"GetQuoteContext (ctx context.Context, request *GetQuote) (*GetQuoteResponse, error)",
+		"PingContext (ctx context.Context, request *Ping) (*PingResponse, error)",
+		// operations sharing a message have their own wrapper
+		"StatusContext (ctx context.Context, request *Status) (*StatusResponse, error)",
+	} {
+		if !strings.Contains(ops, sig) {
+			t.Errorf("operations are missing %s", sig)
+		}
+	}
+
+	// the part accessors are unqualified (WS-I R2735)
+	testGenerated(t, resp, `package myservice
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestWrappers(t *testing.T) {
+	out, err := xml.Marshal(&GetQuote{Symbol: "ACME"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<rpc:getQuote xmlns:rpc=\"urn:quotes:rpc\"><symbol>ACME</symbol><currency></currency></rpc:getQuote>"
+	if string(out) != want {
+		t.Errorf("got %s want %s", out, want)
+	}
+
+	var response GetQuoteResponse
+	data := "<rpc:getQuoteResponse xmlns:rpc=\"urn:quotes:rpc\"><quote><symbol>ACME</symbol><price>1.5</price></quote></rpc:getQuoteResponse>"
+	if err := xml.Unmarshal([]byte(data), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Quote == nil || response.Quote.Symbol != "ACME" || response.Quote.Price != 1.5 {
+		t.Errorf("got %+v", response.Quote)
+	}
+	out, err = xml.Marshal(&response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != data {
+		t.Errorf("got %s want %s", out, data)
+	}
+}
+`)
+}
+
+func TestRPCEncodedArrays(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/rpc-encoded.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "ArrayOfItem")
+	if err != nil {
+		fmt.Println(string(resp["types"]))
+		t.Fatal(err)
+	}
+
+	expected := `type ArrayOfItem struct {
+	Items []*Item ` + "`" + `xml:",any" json:"items,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	// arrays of arrays and multi-dimensional arrays are not one-dimensional
+	for _, name := range []string{"ArrayOfArrayOf_xsd_int", "Matrix"} {
+		actual, err = getTypeDeclaration(resp, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(actual, "Items []AnyType") {
+			t.Errorf("items of %s should be generated as AnyType:\n%s", name, actual)
+		}
+	}
+
+	actual, err = getFuncDeclaration(resp, "XSIType", "Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `func (t *Item) XSIType() xml.Name {
+	return xml.Name{Space: "urn:inventory", Local: "Item"}
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	if !strings.Contains(string(resp["operations"]), "soap.WithSOAPEncoding()") {
+		t.Error("RPC/encoded client should enable the SOAP encoding")
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestWSDLImports(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/import/service.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// types.xsd is imported relative to abstract/stock.wsdl
+	if _, err := getTypeDeclaration(resp, "TradePriceRequest"); err != nil {
+		fmt.Println(string(resp["types"]))
+		t.Fatal(err)
+	}
+
+	// the service only binds the port type declared in the imported document
+	expected := "GetLastTradePriceContext (ctx context.Context, request *TradePriceRequest) (*TradePrice, error)"
+	if !strings.Contains(string(resp["operations"]), expected) {
+		t.Error("operations of imported port types are not generated")
+		t.Error(string(resp["operations"]))
+	}
+	if !strings.Contains(string(resp["operations"]), `"http://example.com/GetLastTradePrice"`) {
+		t.Error("SOAP action of the importing binding is not used")
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestWSDL20(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/wsdl20.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := getTypeDeclaration(resp, "CheckAvailability"); err != nil {
+		fmt.Println(string(resp["types"]))
+		t.Fatal(err)
+	}
+
+	ops := string(resp["operations"])
+	for _, expected := range []string{
+		"OpCheckAvailabilityContext (ctx context.Context, request *CheckAvailability) (*CheckAvailabilityResponse, error)",
+		`"http://example.com/reservation/opCheckAvailability"`,
+		// operations inherited from the extended interface
+		"func (service *reservationInterface) PingContext (ctx context.Context, request *Ping) (*Pong, error)",
+		// WSDL 2.0 SOAP bindings default to SOAP 1.2
+		"soap.WithSOAPVersion(soap.SOAP12)",
+		"invalidDataFault",
+	} {
+		if !strings.Contains(ops, expected) {
+			t.Errorf("operations don't contain %s", expected)
+		}
+	}
+	if addr := g.findServiceAddress("reservationEndpoint"); addr != "http://example.com/reservation" {
+		t.Errorf("got endpoint address %q", addr)
+	}
+	if addr := g.findServiceAddress("reservationHTTPEndpoint"); addr != "" {
+		t.Errorf("HTTP endpoints should be ignored, got address %q", addr)
+	}
+	// interfaces which are only extended are not generated, and the operation
+	// inherited from both is generated once
+	if strings.Contains(ops, "type BaseInterface interface") || strings.Contains(ops, "type MonitorInterface interface") {
+		t.Error("unbound interfaces should not be generated")
+	}
+	if n := strings.Count(ops, "PingContext (ctx"); n != 2 {
+		t.Errorf("got %d declarations of PingContext, want the method and its implementation", n)
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestNamespaceCollisions(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/namespaces.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// same-named types are suffixed after their namespace
+	for _, name := range []string{"AddressBilling", "AddressOrders", "Date"} {
+		if _, err := getTypeDeclaration(resp, name); err != nil {
+			fmt.Println(string(resp["types"]))
+			t.Fatal(err)
+		}
+	}
+
+	actual, err := getTypeDeclaration(resp, "PlaceOrder")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `type PlaceOrder struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/orders/v2 placeOrder"` + "`" + `
+
+	ShipTo	*AddressOrders	` + "`" + `xml:"shipTo,omitempty" json:"shipTo,omitempty"` + "`" + `
+
+	BillTo	*AddressBilling	` + "`" + `xml:"billTo,omitempty" json:"billTo,omitempty"` + "`" + `
+
+	Day	*Date	` + "`" + `xml:"day,omitempty" json:"day,omitempty"` + "`" + `
+
+	Created	soap.XSDDate	` + "`" + `xml:"created,omitempty" json:"created,omitempty"` + "`" + `
+
+	Invoice	*Invoice	` + "`" + `xml:"http://example.com/billing invoice,omitempty" json:"invoice,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestPackagePerNamespace(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/namespaces.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetPackagePerNamespace("example.com/myservice", map[string]string{
+		"http://example.com/billing": "example.com/shared/billing",
+	})
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// namespaces mapped outside of the main package are not generated
+	if _, ok := resp[PackagePrefix+"billing"]; ok {
+		t.Error("billing package should not be generated")
+	}
+
+	orders := map[string][]byte{"types": resp[PackagePrefix+"orders"]}
+	actual, err := getTypeDeclaration(orders, "PlaceOrder")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `type PlaceOrder struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/orders/v2 placeOrder"` + "`" + `
+
+	ShipTo	*Address	` + "`" + `xml:"shipTo,omitempty" json:"shipTo,omitempty"` + "`" + `
+
+	BillTo	*billing.Address	` + "`" + `xml:"billTo,omitempty" json:"billTo,omitempty"` + "`" + `
+
+	Day	*Date	` + "`" + `xml:"day,omitempty" json:"day,omitempty"` + "`" + `
+
+	Created	soap.XSDDate	` + "`" + `xml:"created,omitempty" json:"created,omitempty"` + "`" + `
+
+	Invoice	*billing.Invoice	` + "`" + `xml:"http://example.com/billing invoice,omitempty" json:"invoice,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	if !bytes.Contains(resp[PackagePrefix+"orders"], []byte(`"example.com/shared/billing"`)) {
+		t.Error("orders package should import the billing package")
+	}
+	if !bytes.Contains(resp["header"], []byte(`"example.com/myservice/orders"`)) {
+		t.Error("operations should import the orders package")
+	}
+	if !bytes.Contains(resp["operations"], []byte("request *orders.PlaceOrder")) {
+		t.Error("operations should use the types of the orders package")
+	}
+}
+
+func TestStartAllSharesCommonSchemas(t *testing.T) {
+	var services []*GoWSDL
+	for _, file := range []string{"fixtures/shared/billing.wsdl", "fixtures/shared/shipping.wsdl"} {
+		g, err := NewGoWSDL(file, strings.TrimSuffix(filepath.Base(file), ".wsdl"), false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		services = append(services, g)
+	}
+
+	gocodes, err := StartAll("example.com/services", services...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gocodes) != 2 {
+		t.Fatalf("expected the code of 2 services, got %d", len(gocodes))
+	}
+
+	// the common schema is generated once, with the first service
+	if _, ok := gocodes[0][PackagePrefix+"common"]; !ok {
+		t.Error("common package should be generated with the billing service")
+	}
+	if _, ok := gocodes[1][PackagePrefix+"common"]; ok {
+		t.Error("common package should not be generated twice")
+	}
+
+	for i, dir := range []string{"billing/billing", "shipping/shipping"} {
+		code, ok := gocodes[i][PackagePrefix+dir]
+		if !ok {
+			t.Fatalf("%s package is missing", dir)
+		}
+		if !bytes.Contains(code, []byte(`"example.com/services/common"`)) {
+			t.Errorf("%s package should import the common package", dir)
+		}
+		if !bytes.Contains(code, []byte("*common.Address")) {
+			t.Errorf("%s package should use the common Address type", dir)
+		}
+	}
+
+	buildGeneratedModule(t, "example.com/services", map[string]map[string][]byte{
+		"billing":  gocodes[0],
+		"shipping": gocodes[1],
+	})
+}
+
+func TestGroups(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/groups.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "Customer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `type Customer struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/customers customer"` + "`" + `
+
+	Id	string	` + "`" + `xml:"id,omitempty" json:"id,omitempty"` + "`" + `
+
+	Name	string	` + "`" + `xml:"name,omitempty" json:"name,omitempty"` + "`" + `
+
+	Street	string	` + "`" + `xml:"street,omitempty" json:"street,omitempty"` + "`" + `
+
+	City	string	` + "`" + `xml:"city,omitempty" json:"city,omitempty"` + "`" + `
+
+	Country	*Country	` + "`" + `xml:"country,omitempty" json:"country,omitempty"` + "`" + `
+
+	Version	int32	` + "`" + `xml:"version,attr,omitempty" json:"version,omitempty"` + "`" + `
+
+	CreatedBy	string	` + "`" + `xml:"createdBy,attr,omitempty" json:"createdBy,omitempty"` + "`" + `
+
+	CreatedAt	soap.XSDDateTime	` + "`" + `xml:"createdAt,attr,omitempty" json:"createdAt,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getTypeDeclaration(resp, "ContactInfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `type ContactInfo struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/customers contact"` + "`" + `
+
+	Choice	ContactInfoChoice	` + "`" + `xml:",any" json:"-"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestNestedModelGroups(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/groups.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "Shipment")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `type Shipment struct {
+	Id	string	` + "`" + `xml:"id,omitempty" json:"id,omitempty"` + "`" + `
+
+	Carrier	*string	` + "`" + `xml:"carrier,omitempty" json:"carrier,omitempty"` + "`" + `
+
+	Street	*string	` + "`" + `xml:"street,omitempty" json:"street,omitempty"` + "`" + `
+
+	City	*string	` + "`" + `xml:"city,omitempty" json:"city,omitempty"` + "`" + `
+
+	PoBox	*string	` + "`" + `xml:"poBox,omitempty" json:"poBox,omitempty"` + "`" + `
+
+	Choice	[]ShipmentChoice	` + "`" + `xml:",any" json:"-"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+}
+
+func TestChoices(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/choices.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "Payment")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `type Payment struct {
+	Amount	soap.XSDDecimal	` + "`" + `xml:"amount,omitempty" json:"amount,omitempty"` + "`" + `
+
+	Choice	PaymentChoice	` + "`" + `xml:",any" json:"-"` + "`" + `
+
+	Choice2	PaymentChoice2	` + "`" + `xml:",any" json:"-"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getTypeDeclaration(resp, "PaymentChoiceVoucher")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `type PaymentChoiceVoucher struct {
+	Value *Voucher
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getFuncDeclaration(resp, "MarshalXML", "PaymentChoiceVoucher")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `func (c PaymentChoiceVoucher) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(c.Value, xml.StartElement{Name: xml.Name{Space: "http://example.com/payments", Local: "voucher"}})
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	// choices of base types are decoded by the derived types
+	actual, err = getFuncDeclaration(resp, "UnmarshalXML", "Payer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual = strings.Join(strings.Fields(actual), " ")
+	for _, want := range []string{
+		"ChoicePerson *string `xml:\"person\"`",
+		"Choice2Email *string `xml:\"email\"`",
+		"UnmarshalXML struct{} `xml:\"-\"`",
+		"t.Party.Choice = choice[0]",
+		"t.Choice2 = choice2[0]",
+	} {
+		if !strings.Contains(actual, want) {
+			t.Errorf("UnmarshalXML of Payer does not contain %q:\n%s", want, actual)
+		}
+	}
+
+	actual, err = getFuncDeclaration(resp, "UnmarshalXML", "PayResponse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `func (t *PayResponse) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return (*Payment)(t).UnmarshalXML(d, start)
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestRepeatedChoices(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/choices.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "Ledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `type Ledger struct {
+	Choice []LedgerChoice ` + "`" + `xml:",any" json:"-"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getTypeDeclaration(resp, "LedgerChoiceAny")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `type LedgerChoiceAny struct {
+	XMLName	xml.Name
+	Value	AnyType
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	// the alternatives are appended in document order
+	actual, err = getFuncDeclaration(resp, "UnmarshalXML", "Ledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual = strings.Join(strings.Fields(actual), " ")
+	for _, want := range []string{
+		"ChoicePayment soap.UnmarshalFunc `xml:\"payment\"`",
+		"ChoiceAny soap.UnmarshalFunc `xml:\",any\"`",
+		"var c LedgerChoiceRefund",
+		"c := LedgerChoiceAny{XMLName: start.Name}",
+		"choice = append(choice, c)",
+		"t.Choice = choice",
+	} {
+		if !strings.Contains(actual, want) {
+			t.Errorf("UnmarshalXML of Ledger does not contain %q:\n%s", want, actual)
+		}
+	}
+}
+
+func TestFlatChoices(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/choices.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetFlatChoices(true)
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "Payer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `type Payer struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/payments payer"` + "`" + `
+
+	*Party
+
+	Email	*string	` + "`" + `xml:"email,omitempty" json:"email,omitempty"` + "`" + `
+
+	Phone	*string	` + "`" + `xml:"phone,omitempty" json:"phone,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	if _, err := getTypeDeclaration(resp, "PayerChoice"); err == nil {
+		t.Error("PayerChoice is generated with flat choices")
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestRestrictions(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/restrictions.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "DomesticAddress")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `type DomesticAddress struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/shipping to"` + "`" + `
+
+	Street	string	` + "`" + `xml:"street,omitempty" json:"street,omitempty"` + "`" + `
+
+	City	string	` + "`" + `xml:"city,omitempty" json:"city,omitempty"` + "`" + `
+
+	Verified	bool	` + "`" + `xml:"verified,attr,omitempty" json:"verified,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getTypeDeclaration(resp, "BoxSize")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `type BoxSize struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/shipping size"` + "`" + `
+
+	Value	string	` + "`" + `xml:",chardata" json:"-,"` + "`" + `
+
+	System	string	` + "`" + `xml:"system,attr,omitempty" json:"system,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	if !strings.Contains(string(resp["types"]), `BoxSizeSmall = "small"`) {
+		t.Error("the enumeration of BoxSize is not generated")
+	}
+
+	// the base type is declared in another schema
+	actual, err = getTypeDeclaration(resp, "Weight")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `type Weight struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/shipping weight"` + "`" + `
+
+	Value	soap.XSDDecimal	` + "`" + `xml:",chardata" json:"-,"` + "`" + `
+
+	Unit	*Unit	` + "`" + `xml:"unit,attr,omitempty" json:"unit,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestPolymorphism(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/polymorphism.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "Feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `type Feed struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/zoo feed"` + "`" + `
+
+	Animal	[]AnyAnimal	` + "`" + `xml:"animal,omitempty" json:"animal,omitempty"` + "`" + `
+
+	Keeper	AnyKeeper	` + "`" + `xml:"keeper,omitempty" json:"keeper,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getTypeDeclaration(resp, "AnyAnimal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `type AnyAnimal interface {
+	GetAnimal() *Animal
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	// the abstract type has no default, the elements must name their type
+	actual, err = getFuncDeclaration(resp, "UnmarshalXML", "Feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual = strings.Join(strings.Fields(actual), " ")
+	for _, want := range []string{
+		"DecodedAnimal soap.UnmarshalFunc `xml:\"animal\"`",
+		"var c AnyAnimal if err := soap.DecodeXSIType(d, start, &c)",
+		"var c AnyKeeper = new(Keeper)",
+		"t.Animal = decodedAnimal",
+		"t.Keeper = decodedKeeper[len(decodedKeeper)-1]",
+	} {
+		if !strings.Contains(actual, want) {
+			t.Errorf("UnmarshalXML of Feed does not contain %q:\n%s", want, actual)
+		}
+	}
+
+	// the MarshalXML method of Dog is not promoted to Puppy
+	actual, err = getFuncDeclaration(resp, "MarshalXML", "Puppy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual = strings.Join(strings.Fields(actual), " ")
+	for _, want := range []string{
+		"soap.EncodeXSIType(e, start, t.XSIType(),",
+		"MarshalXML struct{} `xml:\"-\"`",
+	} {
+		if !strings.Contains(actual, want) {
+			t.Errorf("MarshalXML of Puppy does not contain %q:\n%s", want, actual)
+		}
+	}
+
+	// nor is the one of Puppy to the element extending it, without adding a
+	// field to its struct
+	actual, err = getTypeDeclaration(resp, "Rescue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(actual, "MarshalXML") {
+		t.Errorf("Rescue declares a MarshalXML field:\n%s", actual)
+	}
+	actual, err = getFuncDeclaration(resp, "MarshalXML", "Rescue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = strings.Join(strings.Fields(actual), " ")
+	if want := `start.Name = xml.Name{Space: "http://example.com/zoo", Local: "rescue"}`; !strings.Contains(actual, want) {
+		t.Errorf("MarshalXML of Rescue does not contain %q:\n%s", want, actual)
+	}
+
+	types := string(resp["types"])
+	if !strings.Contains(types, `Local: "Dog"}, func() interface{} {`) {
+		t.Error("Dog is not registered")
+	}
+	if strings.Contains(types, `Local: "Animal"}, func() interface{} {`) {
+		t.Error("the abstract Animal type is registered")
+	}
+
+	// Dog is the type of the global adopted element too
+	testGenerated(t, resp, `package myservice
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestXSITypes(t *testing.T) {
+	feed := Feed{
+		Animal: []AnyAnimal{
+			&Dog{Animal: &Animal{Name: "Rex"}, Breed: "Collie"},
+			&Cat{Animal: &Animal{Name: "Tom"}, Indoor: true},
+		},
+		Keeper: &Vet{Keeper: &Keeper{Name: "Ann"}, Licence: "L1"},
+	}
+	out, err := xml.Marshal(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Feed
+	if err := xml.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if dog, ok := decoded.Animal[0].(*Dog); !ok || dog.Name != "Rex" || dog.Breed != "Collie" {
+		t.Errorf("got %#v", decoded.Animal[0])
+	}
+	if cat, ok := decoded.Animal[1].(*Cat); !ok || cat.Name != "Tom" || !cat.Indoor {
+		t.Errorf("got %#v", decoded.Animal[1])
+	}
+	if vet, ok := decoded.Keeper.(*Vet); !ok || vet.Name != "Ann" || vet.Licence != "L1" {
+		t.Errorf("got %#v", decoded.Keeper)
+	}
+
+	again, err := xml.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(out) {
+		t.Errorf("got %s want %s", again, out)
+	}
+}
+
+func TestChoices(t *testing.T) {
+	for _, v := range []interface{}{
+		&Visit{Choice: VisitChoiceAnimal{Value: &Puppy{Dog: &Dog{Animal: &Animal{Name: "Rex"}, Breed: "Collie"}, Weeks: 8}}},
+		&Visit{Choice: VisitChoiceKeeper{Value: []AnyKeeper{&Vet{Keeper: &Keeper{Name: "Ann"}, Licence: "L1"}, &Keeper{Name: "Bob"}}}},
+		&Rounds{Choice: []RoundsChoice{
+			RoundsChoiceKeeper{Value: &Vet{Keeper: &Keeper{Name: "Ann"}, Licence: "L1"}},
+			RoundsChoiceAnimal{Value: &Cat{Animal: &Animal{Name: "Tom"}, Indoor: true}},
+			RoundsChoiceKeeper{Value: &Keeper{Name: "Bob"}},
+		}},
+	} {
+		out, err := xml.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+		if err := xml.Unmarshal(out, decoded); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		again, err := xml.Marshal(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(out) {
+			t.Errorf("got %s want %s", again, out)
+		}
+	}
+
+	var visit Visit
+	data := "<Visit xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\" xmlns:z=\"http://example.com/zoo\"><animal xsi:type=\"z:Puppy\"><name>Rex</name><weeks>8</weeks></animal></Visit>"
+	if err := xml.Unmarshal([]byte(data), &visit); err != nil {
+		t.Fatal(err)
+	}
+	if puppy, ok := visit.Choice.(VisitChoiceAnimal).Value.(*Puppy); !ok || puppy.Name != "Rex" || puppy.Weeks != 8 {
+		t.Errorf("got %#v", visit.Choice)
+	}
+}
+`)
+}
+
+func TestSubstitutionGroups(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/substitution.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "Draw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `type Draw struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/drawing draw"` + "`" + `
+
+	Shape	[]ShapeElement	` + "`" + `xml:"http://example.com/drawing shape,omitempty" json:"shape,omitempty"` + "`" + `
+
+	Note	NoteElement	` + "`" + `xml:"http://example.com/drawing note,omitempty" json:"note,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getTypeDeclaration(resp, "ShapeElement")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `type ShapeElement interface {
+	SubstitutesShape()
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	// members of the group declared by imported schemas are decoded as well
+	actual, err = getFuncDeclaration(resp, "UnmarshalXML", "Draw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual = strings.Join(strings.Fields(actual), " ")
+	for _, want := range []string{
+		"DecodedShapeCircle soap.UnmarshalFunc `xml:\"http://example.com/drawing circle\"`",
+		"DecodedShapeTriangle soap.UnmarshalFunc `xml:\"http://example.com/extra triangle\"`",
+		"DecodedNoteWarning soap.UnmarshalFunc `xml:\"http://example.com/drawing warning\"`",
+		"if err := soap.DecodeSubstitution(d, start, &c)",
+	} {
+		if !strings.Contains(actual, want) {
+			t.Errorf("UnmarshalXML of Draw does not contain %q:\n%s", want, actual)
+		}
+	}
+
+	// the simple type alias must name the element it is marshalled as
+	actual, err = getFuncDeclaration(resp, "MarshalXML", "Warning")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual = strings.Join(strings.Fields(actual), " ")
+	if !strings.Contains(actual, `Local: "warning"`) {
+		t.Errorf("MarshalXML of Warning does not set the name of the element:\n%s", actual)
+	}
+
+	types := string(resp["types"])
+	if !strings.Contains(types, "func (Circle) SubstitutesShape() {}") {
+		t.Error("Circle does not implement ShapeElement")
+	}
+	if strings.Contains(types, `Local: "shape"}, func() interface{} {`) {
+		t.Error("the abstract shape element is registered")
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestEnumerations(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/enumerations.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetStrictEnums(true)
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := format.Source([]byte(string(resp["header"]) + string(resp["types"])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		// values normalizing to the same identifier, or to the one of a type
+		`CarrierFed_ex Carrier = "fed-ex"`,
+		`CarrierFed_ex_2 Carrier = "fed.ex"`,
+		`CarrierUps_2 Carrier = "ups"`,
+		`CarrierSayhio Carrier = "say \"hi\" \\o/"`,
+		// numbers are not quoted
+		`Priority03 Priority = 3`,
+		`Express1 Express = 1`,
+		// exact numbers are variables
+		`Rate1_50 = func() Rate { v, _ := soap.ParseXsdDecimal("1.50"); return Rate(v) }()`,
+		`LotPlus12 = func() Lot { v, _ := soap.ParseXsdInteger("12"); return Lot(v) }()`,
+		`Parcel10 = 10`,
+	} {
+		if !bytes.Contains(source, []byte(want)) {
+			t.Errorf("types do not contain %q", want)
+		}
+	}
+
+	actual, err := getFuncDeclaration(resp, "Values", "Priority")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `func (Priority) Values() []Priority {
+	return []Priority{Priority1, Priority2, Priority03}
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getFuncDeclaration(resp, "UnmarshalText", "Priority")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `func (t *Priority) UnmarshalText(text []byte) error {
+	var v Priority
+	if err := soap.ParseNumber(text, &v); err != nil {
+		return err
+	}
+	if !v.IsValid() {
+		return &soap.EnumerationError{Type: "Priority", Value: string(text)}
+	}
+	*t = v
+	return nil
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	// booleans cannot be constants
+	if _, err := getFuncDeclaration(resp, "Values", "Flag"); err == nil {
+		t.Error("Flag has a Values method")
+	}
+
+	testGenerated(t, resp, `package myservice
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/ilmich/gowsdl/soap"
+)
+
+func TestExactEnumerations(t *testing.T) {
+	if len(Rate(Rate0_5).Values()) != 2 || !Rate1_50.IsValid() || !Lot1.IsValid() {
+		t.Error("enumerated values are not valid")
+	}
+
+	var ship Ship
+	data := "<ship xmlns=\"http://example.com/shipping\"><rate>1.50</rate><lot>+12</lot></ship>"
+	if err := xml.Unmarshal([]byte(data), &ship); err != nil {
+		t.Fatal(err)
+	}
+	if ship.Rate.String() != "1.50" || ship.Lot.String() != LotPlus12.String() {
+		t.Errorf("got %v %v", ship.Rate, ship.Lot)
+	}
+	out, err := xml.Marshal(Ship{Rate: &Rate0_5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<ship xmlns=\"http://example.com/shipping\"><rate>0.5</rate></ship>"; string(out) != want {
+		t.Errorf("got %s want %s", out, want)
+	}
+
+	// lexical values are compared
+	for _, data := range []string{"<rate>1.5</rate>", "<lot>2</lot>"} {
+		err := xml.Unmarshal([]byte("<ship xmlns=\"http://example.com/shipping\">"+data+"</ship>"), &ship)
+		var enumErr *soap.EnumerationError
+		if !errors.As(err, &enumErr) {
+			t.Errorf("%s: got %v", data, err)
+		}
+	}
+	rate, _ := soap.ParseXsdDecimal("2")
+	if _, err := xml.Marshal(Ship{Rate: (*Rate)(&rate)}); err == nil {
+		t.Error("a value which is not enumerated is marshalled")
+	}
+}
+`)
+}
+
+func TestValidation(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/validation.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getFuncDeclaration(resp, "Validate", "Line")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `func (t *Line) Validate() error {
+	v := new(soap.Validation)
+	v.Required("sku", t.Sku)
+	v.Validate("sku", &t.Sku)
+	v.Required("quantity", t.Quantity)
+	v.Validate("quantity", &t.Quantity)
+	v.Validate("size", &t.Size)
+	v.Facets("note", t.Note, soap.Facets{MaxLength: "10"})
+	v.Required("@id", t.Id)
+	return v.Err()
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	// the fields of local complex types are checked in place
+	actual, err = getFuncDeclaration(resp, "Validate", "Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual = strings.Join(strings.Fields(actual), " ")
+	for _, want := range []string{
+		`v.Occurs("line", len(t.Line), 1, -1)`,
+		`t, v := &t.Customer, v.At("customer")`,
+		`v.Facets("country", t.Country, soap.Facets{Length: "2"})`,
+	} {
+		if !strings.Contains(actual, want) {
+			t.Errorf("Validate of Order does not contain %q:\n%s", want, actual)
+		}
+	}
+
+	// XML Schema patterns are translated to the syntax of package regexp,
+	// and values match any of the patterns of a restriction
+	actual, err = getFuncDeclaration(resp, "Validate", "Sku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(actual, `soap.Facets{Pattern: "(?:[A-Z]{3}-\\p{Nd}+)|(?:SKU\\p{Nd}{6})"}`) {
+		t.Errorf("Validate of Sku does not check the pattern:\n%s", actual)
+	}
+
+	// false is a value of a required boolean, not a missing one
+	actual, err = getFuncDeclaration(resp, "Validate", "OrderResponse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(actual, "Required") {
+		t.Errorf("Validate of OrderResponse checks the presence of a boolean:\n%s", actual)
+	}
+
+	// the validating endpoint rejects invalid requests with a client fault
+	server, err := format.Source([]byte(string(resp["server_header"]) + string(resp["server"])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func ValidatingEndpoint(w http.ResponseWriter, r *http.Request)",
+		"soap.ValidateContent(field.Interface())",
+		`resp.setFault(true, "invalid request: "+err.Error(), err)`,
+	} {
+		if !bytes.Contains(server, []byte(want)) {
+			t.Errorf("server does not contain %q", want)
+		}
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestDefaults(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/defaults.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := format.Source([]byte(string(resp["header"]) + string(resp["types"])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`func NewHeader() *Header {
+	t := new(Header)
+	soap.SetDefault(&t.Retries, "3")
+	soap.SetDefault(&t.Mode, "fast")
+	soap.SetDefault(&t.Options.Verbose, "true")
+	soap.SetDefault(&t.Lang, "en")
+	return t
+}`,
+		// extensions set the defaults of their base type
+		`func NewExtendedHeader() *ExtendedHeader {
+	t := new(ExtendedHeader)
+	t.Header = NewHeader()
+	soap.SetDefault(&t.Channel, "web")
+	return t
+}`,
+		`func NewTrackResponse() *TrackResponse {
+	return (*TrackResponse)(NewExtendedHeader())
+}`,
+		// fixed attributes are never omitted
+		"Version FixedVersion `xml:\"version,attr\" json:\"version,omitempty\"`",
+		"SchemaVersion FixedSchemaVersion `xml:\"http://example.com/tracking schemaVersion,attr\" json:\"schemaVersion,omitempty\"`",
+	} {
+		if !bytes.Contains(source, []byte(want)) {
+			t.Errorf("types do not contain %q", want)
+		}
+	}
+
+	actual, err := getFuncDeclaration(resp, "MarshalXMLAttr", "FixedVersion_2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `func (FixedVersion_2) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: "1.0"}, nil
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestOccurrences(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/occurrences.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `type Order struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/orders order"` + "`" + `
+
+	Line	[]string	` + "`" + `xml:"line,omitempty" json:"line,omitempty"` + "`" + `
+
+	Quantity	*int32	` + "`" + `xml:"quantity,omitempty" json:"quantity,omitempty"` + "`" + `
+
+	Gift	*bool	` + "`" + `xml:"gift,omitempty" json:"gift,omitempty"` + "`" + `
+
+	Due	*soap.XSDDate	` + "`" + `xml:"due,omitempty" json:"due,omitempty"` + "`" + `
+
+	Code	[]string	` + "`" + `xml:"code,omitempty" json:"code,omitempty"` + "`" + `
+
+	Discount	*int32	` + "`" + `xml:"discount,omitempty" json:"discount,omitempty"` + "`" + `
+
+	Package_	[]struct {
+		Weight float64 ` + "`" + `xml:"weight,omitempty" json:"weight,omitempty"` + "`" + `
+	}	` + "`" + `xml:"package,omitempty" json:"package,omitempty"` + "`" + `
+
+	Tracking	[]string	` + "`" + `xml:"tracking,omitempty" json:"tracking,omitempty"` + "`" + `
+
+	Comment	[]*Comment	` + "`" + `xml:"http://example.com/orders comment,omitempty" json:"comment,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getFuncDeclaration(resp, "Validate", "Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `func (t *Order) Validate() error {
+	v := new(soap.Validation)
+	v.Occurs("line", len(t.Line), 1, 5)
+	v.Occurs("code", len(t.Code), 0, 3)
+	for i := range t.Code {
+		v.Facets(soap.Index("code", i), t.Code[i], soap.Facets{MaxLength: "3"})
+	}
+	v.Facets("discount", t.Discount, soap.Facets{MinInclusive: "1"})
+	v.Occurs("package", len(t.Package_), 1, 2)
+	v.Occurs("tracking", len(t.Tracking), 1, 2)
+	v.Occurs("comment", len(t.Comment), 0, 3)
+	return v.Err()
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	g, err = NewGoWSDL("fixtures/occurrences.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetZeroValueOptionals(true)
+
+	resp, err = g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := format.Source([]byte(string(resp["header"]) + string(resp["types"])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Quantity int32 `xml:\"quantity,omitempty\" json:\"quantity,omitempty\"`",
+		"Gift bool `xml:\"gift,omitempty\" json:\"gift,omitempty\"`",
+		// nillable elements are still pointers
+		"Due *soap.XSDDate `xml:\"due,omitempty\" json:\"due,omitempty\"`",
+		"Line []string `xml:\"line,omitempty\" json:\"line,omitempty\"`",
+	} {
+		if !bytes.Contains(source, []byte(want)) {
+			t.Errorf("types do not contain %q", want)
+		}
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestCalendarTypes(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/calendar.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := format.Source([]byte(string(resp["header"]) + string(resp["types"])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type Reminder soap.XSDDuration",
+		"type EventResponse soap.XSDDuration",
+		"Length soap.XSDDuration `xml:\"length,omitempty\" json:\"length,omitempty\"`",
+		"Season *soap.XSDGYearMonth `xml:\"season,omitempty\" json:\"season,omitempty\"`",
+		"Month *soap.XSDGMonth `xml:\"month,omitempty\" json:\"month,omitempty\"`",
+		"Anniversary []soap.XSDGMonthDay `xml:\"anniversary,omitempty\" json:\"anniversary,omitempty\"`",
+		"Payday *soap.XSDGDay `xml:\"payday,omitempty\" json:\"payday,omitempty\"`",
+		"Year soap.XSDGYear `xml:\"year,attr,omitempty\" json:\"year,omitempty\"`",
+	} {
+		if !bytes.Contains(source, []byte(want)) {
+			t.Errorf("types do not contain %q", want)
+		}
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestBuiltinTypes(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/builtins.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := format.Source([]byte(string(resp["header"]) + string(resp["types"])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"NormalizedStringValue *string `",
+		"LanguageValue *string `",
+		"IDREFValue *string `",
+		"NMTOKENSValue *soap.XSDList `",
+		"QNameValue *soap.QName `",
+		"NOTATIONValue *soap.QName `",
+		"PositiveIntegerValue *soap.XSDInteger `",
+		"DateTimeStampValue *soap.XSDDateTime `",
+		"DayTimeDurationValue *soap.XSDDuration `",
+		"AnyAtomicTypeValue *string `",
+		// types of other versions of XML Schema
+		"FutureValue *string `",
+		"Tokens soap.XSDList `xml:\"tokens,attr,omitempty\" json:\"tokens,omitempty\"`",
+		"Kind soap.QName `xml:\"kind,attr,omitempty\" json:\"kind,omitempty\"`",
+		"type ValuesResponse soap.QName",
+	} {
+		if !bytes.Contains(source, []byte(want)) {
+			t.Errorf("types do not contain %q", want)
+		}
+	}
+
+	actual, err := getFuncDeclaration(resp, "UnmarshalXMLAttr", "Codes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `func (t *Codes) UnmarshalXMLAttr(attr xml.Attr) error {
+	return (*soap.XSDList)(t).UnmarshalXMLAttr(attr)
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getFuncDeclaration(resp, "MarshalXML", "ValuesResponse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `func (t ValuesResponse) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.QName(t).MarshalXML(e, start)
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestQNameAttributes(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/qnames.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the element of a QName attribute declares its namespace
+	actual, err := getFuncDeclaration(resp, "MarshalXML", "Link")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `func (t Link) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type plain Link
+	start.Name = xml.Name{Space: "http://example.com/links", Local: "link"}
+	soap.DeclareQNameAttrs(&start, t)
+	return e.EncodeElement(plain(t), start)
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getFuncDeclaration(resp, "UnmarshalXML", "Link")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `func (t *Link) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain Link
+	return soap.DecodeQNameAttrs(d, (*plain)(t), &start)
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	// derived types resolve the attributes of their base type too, and
+	// declare them along with their xsi:type
+	for method, wants := range map[string][]string{
+		"UnmarshalXML": {
+			"return soap.DecodeQNameAttrs(d, &struct {",
+			"UnmarshalXML struct{} `xml:\"-\"` *plain",
+		},
+		"MarshalXML": {
+			"soap.DeclareQNameAttrs(&start, t) return soap.EncodeXSIType(",
+		},
+	} {
+		actual, err = getFuncDeclaration(resp, method, "TypedLink")
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual = strings.Join(strings.Fields(actual), " ")
+		for _, want := range wants {
+			if !strings.Contains(actual, want) {
+				t.Errorf("%s of TypedLink does not contain %q:\n%s", method, want, actual)
+			}
+		}
+	}
+
+	// the UnmarshalXML method decoding choices resolves the attributes
+	actual, err = getFuncDeclaration(resp, "UnmarshalXML", "Alternative")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(actual, "soap.DecodeQNameAttrs(d, &v, &start)") {
+		t.Errorf("UnmarshalXML of Alternative does not resolve the QName attributes:\n%s", actual)
+	}
+
+	// requests are decoded with the namespaces in scope
+	if !bytes.Contains(resp["server"], []byte("soap.NewDecoder(r.Body).Decode(service)")) {
+		t.Error("server does not decode requests with soap.NewDecoder")
+	}
+
+	buildGenerated(t, resp)
+}
+
+func TestTypeMapping(t *testing.T) {
+	mapping, err := ParseTypeMapping([]byte(`{
+		"decimal": {"type": "*decimal.Decimal", "import": "github.com/shopspring/decimal"},
+		"{http://www.w3.org/2001/XMLSchema}base64Binary": {"type": "*soap.Binary", "import": "github.com/ilmich/gowsdl/soap"},
+		"{http://example.com/invoices}Money": {"type": "money.Amount", "import": "example.com/money", "adapter": "XMLAmount"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := NewGoWSDL("fixtures/typemapping.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetTypeMapping(mapping)
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "Invoice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `type Invoice struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://example.com/invoices invoice"` + "`" + `
+
+	Price	*decimal.Decimal	` + "`" + `xml:"price,omitempty" json:"price,omitempty"` + "`" + `
+
+	Discount	*decimal.Decimal	` + "`" + `xml:"discount,omitempty" json:"discount,omitempty"` + "`" + `
+
+	Amount	[]money.XMLAmount	` + "`" + `xml:"amount,omitempty" json:"amount,omitempty"` + "`" + `
+
+	Rate	*Rate	` + "`" + `xml:"rate,omitempty" json:"rate,omitempty"` + "`" + `
+
+	Scan	*soap.Binary	` + "`" + `xml:"scan,omitempty" json:"scan,omitempty"` + "`" + `
+
+	Total	*Total	` + "`" + `xml:"http://example.com/invoices total,omitempty" json:"total,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	source, err := format.Source([]byte(string(resp["header"]) + string(resp["types"])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"github.com/shopspring/decimal"`,
+		`"example.com/money"`,
+		// restrictions and elements of mapped types keep their methods
+		"type Rate = decimal.Decimal",
+		"type Total = money.XMLAmount",
+	} {
+		if !bytes.Contains(source, []byte(want)) {
+			t.Errorf("source does not contain %q", want)
+		}
+	}
+	for _, unwanted := range []string{
+		// mapped types are not generated
+		"type Money struct",
+		"func (t Rate) Validate() error",
+	} {
+		if bytes.Contains(source, []byte(unwanted)) {
+			t.Errorf("source contains %q", unwanted)
+		}
+	}
+
+	for _, invalid := range []string{
+		`{"{http://example.com/invoices Money": {"type": "money.Amount"}}`,
+		`{"decimal": {"import": "github.com/shopspring/decimal"}}`,
+	} {
+		if _, err := ParseTypeMapping([]byte(invalid)); err == nil {
+			t.Errorf("%s is parsed", invalid)
+		}
+	}
+}
+
+func TestGoPattern(t *testing.T) {
+	for pattern, expected := range map[string]string{
+		`\d{3}`:       `\p{Nd}{3}`,
+		`$\i\c*`:      `\$[\p{L}_:][\p{L}\p{M}\p{N}._:\-]*`,
+		`[^\i-]+\^`:   `[^\p{L}_:-]+\^`,
+		`[a-z]{2,3}`:  `[a-z]{2,3}`,
+		`[a-z-[aeo]]`: "",
+		`\p{IsGreek}`: "",
+	} {
+		actual, err := goPattern(pattern)
+		if expected == "" && err == nil {
+			t.Errorf("%s translated to %s, want an error", pattern, actual)
+		} else if actual != expected {
+			t.Errorf("%s translated to %s, want %s", pattern, actual, expected)
+		}
+	}
+}
+
 func TestVboxGeneratesWithoutSyntaxErrors(t *testing.T) {
 	files, err := filepath.Glob("fixtures/*.wsdl")
 	if err != nil {
@@ -396,3 +2034,85 @@ func getFuncDeclaration(resp map[string][]byte, name string, recv string) (strin
 	}
 	return buf.String(), nil
 }
+
+// buildGenerated writes the code generated for a service as cmd/gowsdl does,
+// and checks that it builds and passes go vet.
+func buildGenerated(t *testing.T, resp map[string][]byte) {
+	t.Helper()
+	buildGeneratedModule(t, "myservice", map[string]map[string][]byte{"": resp})
+}
+
+// testGenerated builds the code generated for a service like buildGenerated
+// and runs the given test file in its package.
+func testGenerated(t *testing.T, resp map[string][]byte, test string) {
+	t.Helper()
+	gocode := map[string][]byte{"test": []byte(test)}
+	for key, code := range resp {
+		gocode[key] = code
+	}
+	buildGeneratedModule(t, "myservice", map[string]map[string][]byte{"": gocode})
+}
+
+// buildGeneratedModule writes the code generated for services in a module of
+// the given path depending on this one, the code of each service in the
+// directory of its key and the namespace packages relative to the module,
+// and checks that the module builds and passes go vet, and its tests if any.
+func buildGeneratedModule(t *testing.T, modulePath string, gocodes map[string]map[string][]byte) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping the build of the generated code in short mode")
+	}
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("skipping the build of the generated code: ", err)
+	}
+	root, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeFile := func(name string, code ...[]byte) {
+		source, err := format.Source(bytes.Join(code, nil))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, source, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	goMod := "module " + modulePath + "\n\ngo 1.15\n\n" +
+		"require github.com/ilmich/gowsdl v0.0.0\n\n" +
+		"replace github.com/ilmich/gowsdl => " + root + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	commands := [][]string{{"build", "./..."}, {"vet", "./..."}}
+	for pkg, gocode := range gocodes {
+		writeFile(path.Join(pkg, "service.go"), gocode["header"], gocode["types"], gocode["operations"], gocode["soap"])
+		writeFile(path.Join(pkg, "server.go"), gocode["server_header"], gocode["server_wsdl"], gocode["server"])
+		if test, ok := gocode["test"]; ok {
+			writeFile(path.Join(pkg, "service_test.go"), test)
+			commands = append(commands[:2], []string{"test", "./..."})
+		}
+		for key, code := range gocode {
+			if nsPkg := strings.TrimPrefix(key, PackagePrefix); nsPkg != key {
+				writeFile(path.Join(nsPkg, path.Base(nsPkg)+".go"), code)
+			}
+		}
+	}
+
+	for _, args := range commands {
+		cmd := exec.Command(goCmd, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s of the generated code failed: %v\n%s", args[0], err, out)
+		}
+	}
+}
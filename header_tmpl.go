@@ -7,7 +7,7 @@ package gowsdl
 var headerTmpl = `
 // Code generated by gowsdl DO NOT EDIT.
 
-package {{.}}
+package {{.Pkg}}
 
 import (
 	"context"
@@ -15,9 +15,9 @@ import (
 	"time"
 	"github.com/ilmich/gowsdl/soap"
 
-	{{/*range .Imports*/}}
-		{{/*.*/}}
-	{{/*end*/}}
+	{{range .Imports}}
+		{{.}}
+	{{end}}
 )
 
 // against "unused imports"
@@ -33,3 +33,33 @@ type AnyURI string
 type NCName string
 
 `
+
+var typesHeaderTmpl = `
+// Code generated by gowsdl DO NOT EDIT.
+
+package {{.Pkg}}
+
+import (
+	"encoding/xml"
+	"time"
+	"github.com/ilmich/gowsdl/soap"
+
+	{{range .Imports}}
+		{{.}}
+	{{end}}
+)
+
+// against "unused imports"
+var _ time.Time
+var _ xml.Name
+var _ soap.XSDDateTime
+
+type AnyType struct {
+	InnerXML string ` + "`" + `xml:",innerxml"` + "`" + `
+}
+
+type AnyURI string
+
+type NCName string
+
+`
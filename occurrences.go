@@ -0,0 +1,56 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import "strings"
+
+// SetZeroValueOptionals generates the optional elements of built-in types as
+// values rather than pointers, an absent element then being decoded as the
+// zero value and the zero value never being marshalled.
+func (g *GoWSDL) SetZeroValueOptionals(zero bool) {
+	g.zeroValueOptionals = zero
+}
+
+// Repeated reports whether the element may occur more than once, its field
+// being a slice.
+func (el *XSDElement) Repeated() bool {
+	o := parseOccurs(el.MinOccurs, el.MaxOccurs)
+	return o.max < 0 || o.max > 1
+}
+
+// elementFieldType returns the Go type of the struct field of an element, as
+// generated by the Element template, or an empty string for anonymous
+// structs.
+func (g *GoWSDL) elementFieldType(el *XSDElement) string {
+	var goType string
+	switch {
+	case el.Ref != "":
+		goType = g.elementGoType(el.Ref)
+	case el.Type != "":
+		goType = g.optionalType(g.toGoType(el.Type, el.Nillable), el)
+	case el.SimpleType == nil:
+		return ""
+	case el.SimpleType.List.ItemType != "":
+		// a single element holds the items of a list
+		return "[]" + g.toGoType(el.SimpleType.List.ItemType, false)
+	default:
+		goType = g.optionalType(g.toGoType(el.SimpleType.Restriction.Base, false), el)
+	}
+	if el.Repeated() {
+		goType = "[]" + goType
+	}
+	return goType
+}
+
+// optionalType returns the Go type of the field of an optional element of
+// a built-in type, which is a pointer so that an absent element is told from
+// one holding the zero value. Slices and pointers are left as they are.
+func (g *GoWSDL) optionalType(goType string, el *XSDElement) string {
+	if g.zeroValueOptionals || el.Repeated() || parseOccurs(el.MinOccurs, el.MaxOccurs).min > 0 ||
+		strings.HasPrefix(goType, "*") || strings.HasPrefix(goType, "[]") {
+		return goType
+	}
+	return "*" + goType
+}
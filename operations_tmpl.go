@@ -6,6 +6,7 @@ package gowsdl
 
 var opsTmpl = `
 {{range .}}
+	{{$portType := .Name}}
 	{{$privateType := .Name | makePrivate}}
 	{{$exportType := .Name | makePublic}}
 
@@ -13,8 +14,8 @@ var opsTmpl = `
 		{{range .Operations}}
 			{{$faults := len .Faults}}
 			{{$soapAction := findSOAPAction .Name $privateType}}
-			{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}}
-			{{$responseType := findType .Output.Message | replaceReservedWords | makePublic}}
+			{{$requestType := findGoType $portType . "input"}}
+			{{$responseType := findGoType $portType . "output"}}
 
 			{{/*if ne $soapAction ""*/}}
 			{{if gt $faults 0}}
@@ -31,21 +32,27 @@ var opsTmpl = `
 
 	type {{$privateType}} struct {
 		client *soap.Client
+		options []soap.Option
 	}
 
 	func New{{$exportType}}(client *soap.Client) {{$exportType}} {
 		return &{{$privateType}}{
 			client: client,
+			options: []soap.Option{
+				{{if eq (findSOAPVersion $privateType) "1.2"}}soap.WithSOAPVersion(soap.SOAP12),
+				{{end}}{{if isSOAPEncoded $privateType}}soap.WithSOAPEncoding(),
+				{{end}}
+			},
 		}
 	}
 
 	{{range .Operations}}
-		{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}}
+		{{$requestType := findGoType $portType . "input"}}
 		{{$soapAction := findSOAPAction .Name $privateType}}
-		{{$responseType := findType .Output.Message | replaceReservedWords | makePublic}}
+		{{$responseType := findGoType $portType . "output"}}
 		func (service *{{$privateType}}) {{makePublic .Name | replaceReservedWords}}Context (ctx context.Context, {{if ne $requestType ""}}request *{{$requestType}}{{end}}) ({{if ne $responseType ""}}*{{$responseType}}, {{end}}error) {
 			{{if ne $responseType ""}}response := new({{$responseType}}){{end}}
-			err := service.client.CallContext(ctx, "{{if ne $soapAction ""}}{{$soapAction}}{{else}}''{{end}}", {{if ne $requestType ""}}request{{else}}nil{{end}}, {{if ne $responseType ""}}response{{else}}struct{}{}{{end}})
+			err := service.client.CallContextWithOptions(ctx, "{{if ne $soapAction ""}}{{$soapAction}}{{else}}''{{end}}", {{if ne $requestType ""}}request{{else}}nil{{end}}, {{if ne $responseType ""}}response{{else}}struct{}{}{{end}}, service.options...)
 			if err != nil {
 				return {{if ne $responseType ""}}nil, {{end}}err
 			}
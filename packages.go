@@ -0,0 +1,432 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PackagePrefix prefixes the keys of the code generated for the types of
+// each XML namespace when generating one package per namespace. It is
+// followed by the directory of the package, relative to the main package, or
+// to the root package when generating several services with StartAll.
+const PackagePrefix = "package:"
+
+// nsPackage is a Go package holding the types of one or more XML namespaces.
+type nsPackage struct {
+	name       string
+	importPath string
+	// dir is the directory of the package relative to the root package, or
+	// an empty string if it is provided by the user and not generated.
+	dir        string
+	namespaces []string
+	schemas    []*XSDSchema
+}
+
+// importSet collects the packages imported by a generated file.
+type importSet map[*nsPackage]bool
+
+// add records the import of p and returns the qualifier of its identifiers.
+func (imports importSet) add(p *nsPackage) string {
+	imports[p] = true
+	return p.name + "."
+}
+
+// specs returns the import specs of the packages, sorted by import path.
+func (imports importSet) specs() []string {
+	var specs []string
+	for p := range imports {
+		spec := strconv.Quote(p.importPath)
+		if path.Base(p.importPath) != p.name {
+			spec = p.name + " " + spec
+		}
+		specs = append(specs, spec)
+	}
+	sort.Strings(specs)
+	return specs
+}
+
+// SetPackagePerNamespace makes the generator emit the types of each XML
+// namespace in their own Go package, in subdirectories of the main package
+// whose import path is importPath. Generated types are always exported.
+//
+// mapping optionally assigns import paths to namespaces. Namespaces mapped
+// outside of importPath are expected to be provided by existing packages and
+// are not generated.
+func (g *GoWSDL) SetPackagePerNamespace(importPath string, mapping map[string]string) {
+	g.importPath = strings.TrimSuffix(importPath, "/")
+	g.packageRoot = g.importPath
+	g.namespaceImports = mapping
+	g.packagePerNamespace = true
+	// types are useless if not exported from their package
+	g.makePublicFn = makePublic
+}
+
+// genPackages groups the schemas in Go packages by target namespace.
+// Namespaces referencing each other share the same package, as Go does not
+// allow import cycles.
+func (g *GoWSDL) genPackages() {
+	g.packages = make(map[string]*nsPackage)
+
+	var namespaces []string
+	schemas := make(map[string][]*XSDSchema)
+	for _, schema := range g.wsdl.Types.Schemas {
+		ns := schema.TargetNamespace
+		if _, ok := schemas[ns]; !ok {
+			namespaces = append(namespaces, ns)
+		}
+		schemas[ns] = append(schemas[ns], schema)
+	}
+
+	deps := make(map[string]map[string]bool)
+	for _, ns := range namespaces {
+		deps[ns] = make(map[string]bool)
+		for _, schema := range schemas[ns] {
+			for dep := range g.schemaDependencies(schema) {
+				if dep != ns {
+					deps[ns][dep] = true
+				}
+			}
+		}
+	}
+
+	usedPaths := make(map[string]bool)
+	// package names must not shadow the imports and unexported identifiers
+	// of the main package
+	usedNames := map[string]bool{
+		"context": true,
+		"errors":  true,
+		"fmt":     true,
+		"http":    true,
+		"reflect": true,
+		"soap":    true,
+		"strings": true,
+		"time":    true,
+		"xml":     true,
+	}
+	for _, pt := range g.wsdl.PortTypes {
+		usedNames[makePrivate(pt.Name)] = true
+	}
+	for _, component := range stronglyConnected(namespaces, deps) {
+		if len(component) > 1 {
+			log.Printf("[WARN] namespaces %s reference each other, generating them in the same package", strings.Join(component, ", "))
+		}
+
+		p := &nsPackage{namespaces: component}
+		for _, ns := range component {
+			p.schemas = append(p.schemas, schemas[ns]...)
+			if importPath, ok := g.namespaceImports[ns]; ok && p.importPath == "" {
+				p.importPath = strings.TrimSuffix(importPath, "/")
+			}
+		}
+
+		if p.importPath == "" {
+			p.importPath = g.importPath + "/" + packageName(component[0])
+			for i := 2; usedPaths[p.importPath]; i++ {
+				p.importPath = g.importPath + "/" + packageName(component[0]) + strconv.Itoa(i)
+			}
+		}
+		usedPaths[p.importPath] = true
+		if strings.HasPrefix(p.importPath, g.packageRoot+"/") {
+			p.dir = strings.TrimPrefix(p.importPath, g.packageRoot+"/")
+		}
+
+		p.name = importName(p.importPath)
+		for i := 2; usedNames[p.name]; i++ {
+			p.name = importName(p.importPath) + strconv.Itoa(i)
+		}
+		usedNames[p.name] = true
+
+		for _, ns := range component {
+			g.packages[ns] = p
+		}
+	}
+}
+
+// packageOf returns the package of the types of a namespace, or nil when
+// all the types are generated in the main package.
+func (g *GoWSDL) packageOf(ns string) *nsPackage {
+	return g.packages[ns]
+}
+
+// qualifier returns the qualifier of identifiers declared in the namespace
+// when used from the package from, recording the import of their package.
+func (g *GoWSDL) qualifier(ns string, from *nsPackage, imports importSet) string {
+	p := g.packageOf(ns)
+	if p == nil || p == from || imports == nil {
+		return ""
+	}
+	return imports.add(p)
+}
+
+// genericSegments are namespace segments that don't tell namespaces apart.
+var genericSegments = map[string]bool{
+	"xsd":        true,
+	"xml":        true,
+	"schema":     true,
+	"schemas":    true,
+	"ns":         true,
+	"namespace":  true,
+	"namespaces": true,
+	"types":      true,
+	"wsdl":       true,
+	"www":        true,
+}
+
+// packageName derives a Go package name from the last meaningful segment of a
+// namespace, e.g. "orders" for "http://example.com/orders/v2".
+func packageName(ns string) string {
+	segments := strings.FieldsFunc(ns, func(r rune) bool {
+		return r == '/' || r == ':' || r == '#'
+	})
+	for i := len(segments) - 1; i >= 0; i-- {
+		segment := strings.ToLower(segments[i])
+		if versionSegment.MatchString(segment) || genericSegments[segment] {
+			continue
+		}
+		switch segment {
+		case "http", "https", "urn":
+			continue
+		}
+
+		// prefer the domain name of hosts, e.g. "virtualbox" for www.virtualbox.org
+		if labels := strings.Split(segment, "."); len(labels) > 2 {
+			segment = labels[len(labels)-2]
+		} else if len(labels) == 2 && labels[0] != "www" {
+			segment = labels[0]
+		}
+
+		if name := sanitizePackageName(segment); name != "" {
+			return name
+		}
+	}
+	return "types"
+}
+
+// importName returns the name of the package imported with importPath,
+// skipping major version suffixes.
+func importName(importPath string) string {
+	base := path.Base(importPath)
+	if versionSegment.MatchString(base) {
+		base = path.Base(path.Dir(importPath))
+	}
+	if name := sanitizePackageName(base); name != "" {
+		return name
+	}
+	return "types"
+}
+
+// sanitizePackageName turns name into a valid Go package name, or returns an
+// empty string when it has no letters nor digits.
+func sanitizePackageName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+	if name == "" {
+		return ""
+	}
+	if unicode.IsDigit(rune(name[0])) {
+		name = "ns" + name
+	}
+	if _, ok := reservedWords[name]; ok {
+		name += "ns"
+	}
+	return name
+}
+
+// stronglyConnected returns the strongly connected components of the
+// namespaces dependency graph, in the order of the namespaces.
+func stronglyConnected(namespaces []string, deps map[string]map[string]bool) [][]string {
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var connect func(ns string)
+	connect = func(ns string) {
+		index[ns] = len(index)
+		lowlink[ns] = index[ns]
+		stack = append(stack, ns)
+		onStack[ns] = true
+
+		var sorted []string
+		for dep := range deps[ns] {
+			sorted = append(sorted, dep)
+		}
+		sort.Strings(sorted)
+		for _, dep := range sorted {
+			if _, ok := deps[dep]; !ok {
+				continue
+			}
+			if _, visited := index[dep]; !visited {
+				connect(dep)
+				if lowlink[dep] < lowlink[ns] {
+					lowlink[ns] = lowlink[dep]
+				}
+			} else if onStack[dep] && index[dep] < lowlink[ns] {
+				lowlink[ns] = index[dep]
+			}
+		}
+
+		if lowlink[ns] == index[ns] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == ns {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for _, ns := range namespaces {
+		if _, visited := index[ns]; !visited {
+			connect(ns)
+		}
+	}
+
+	// keep the namespaces in document order within and across components
+	order := make(map[string]int)
+	for i, ns := range namespaces {
+		order[ns] = i
+	}
+	for _, component := range components {
+		sort.Slice(component, func(i, j int) bool { return order[component[i]] < order[component[j]] })
+	}
+	sort.Slice(components, func(i, j int) bool { return order[components[i][0]] < order[components[j][0]] })
+
+	return components
+}
+
+// schemaDependencies returns the namespaces declaring the types, elements
+// and attributes referenced by the schema.
+func (g *GoWSDL) schemaDependencies(schema *XSDSchema) map[string]bool {
+	deps := make(map[string]bool)
+
+	addType := func(name string) {
+		if name == "" {
+			return
+		}
+		if s := g.symbols.lookupType(resolveQName(name, schema.Xmlns)); s != nil {
+			deps[s.name.Space] = true
+		}
+	}
+	addElement := func(name string) {
+		if name == "" {
+			return
+		}
+		if s := g.symbols.lookupElement(resolveQName(name, schema.Xmlns)); s != nil {
+			deps[s.name.Space] = true
+		}
+	}
+
+	var walkElements func([]*XSDElement)
+	var walkComplexType func(*XSDComplexType)
+	var walkSimpleType func(*XSDSimpleType)
+	walkAttributes := func(attrs []*XSDAttribute) {
+		for _, attr := range attrs {
+			addType(attr.Type)
+			if i := strings.Index(attr.ArrayType, "["); i > 0 {
+				addType(attr.ArrayType[:i])
+			}
+			if attr.SimpleType != nil {
+				walkSimpleType(attr.SimpleType)
+			}
+		}
+	}
+	walkSimpleType = func(st *XSDSimpleType) {
+		addType(st.Restriction.Base)
+		addType(st.List.ItemType)
+		if st.List.SimpleType != nil {
+			walkSimpleType(st.List.SimpleType)
+		}
+		for _, member := range strings.Fields(st.Union.MemberTypes) {
+			addType(member)
+		}
+		for _, member := range st.Union.SimpleType {
+			walkSimpleType(member)
+		}
+	}
+	walkComplexType = func(ct *XSDComplexType) {
+		walkElements(ct.Elements())
+		walkAttributes(ct.Attributes)
+		addType(ct.ComplexContent.Extension.Base)
+		walkElements(ct.ComplexContent.Extension.Elements())
+		walkAttributes(ct.ComplexContent.Extension.Attributes)
+		addType(ct.ComplexContent.Restriction.Base)
+		walkElements(ct.ComplexContent.Restriction.Elements())
+		walkAttributes(ct.ComplexContent.Restriction.Attributes)
+		addType(ct.SimpleContent.Extension.Base)
+		walkAttributes(ct.SimpleContent.Extension.Attributes)
+		addType(ct.SimpleContent.Restriction.Base)
+		walkAttributes(ct.SimpleContent.Restriction.Attributes)
+		if ct.SimpleContent.Restriction.SimpleType != nil {
+			walkSimpleType(ct.SimpleContent.Restriction.SimpleType)
+		}
+	}
+	walkElements = func(elements []*XSDElement) {
+		for _, el := range elements {
+			addType(el.Type)
+			addElement(el.Ref)
+			addElement(el.SubstitutionGroup)
+			if el.ComplexType != nil {
+				walkComplexType(el.ComplexType)
+			}
+			if el.SimpleType != nil {
+				walkSimpleType(el.SimpleType)
+			}
+		}
+	}
+
+	walkElements(schema.Elements)
+	walkAttributes(schema.Attributes)
+	for _, ct := range schema.ComplexTypes {
+		walkComplexType(ct)
+	}
+	for _, st := range schema.SimpleType {
+		walkSimpleType(st)
+	}
+
+	return deps
+}
+
+// packageIdentifiers returns the identifiers of the types and elements of
+// the current package.
+func (g *GoWSDL) packageIdentifiers() map[string]bool {
+	identifiers := make(map[string]bool)
+	for _, index := range []symbolIndex{g.symbols.types, g.symbols.elements} {
+		for _, s := range index.all {
+			if g.packageOf(s.name.Space) == g.currentPackage {
+				identifiers[g.makePublicFn(replaceReservedWords(s.goName))] = true
+			}
+		}
+	}
+	return identifiers
+}
+
+// uniqueIdentifier returns name, suffixed by a number if needed to differ
+// from the types and the other identifiers generated for the current
+// package, as different names may normalize to the same identifier.
+func (g *GoWSDL) uniqueIdentifier(name string) string {
+	unique := name
+	for i := 2; g.identifiers[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	g.identifiers[unique] = true
+	return unique
+}
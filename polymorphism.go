@@ -0,0 +1,158 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import "encoding/xml"
+
+// polymorphicField is an element whose type is abstract or extended by other
+// types, generated as an interface holding a value of any of them. Its values
+// are decoded into the type named by their xsi:type attribute.
+//
+// A reference to the head of a substitution group is generated likewise, its
+// values being decoded into the type of the member element they are named
+// after.
+type polymorphicField struct {
+	Name      string
+	Interface string
+	// Default is the type of the elements without xsi:type, or an empty
+	// string when the type of the element is abstract.
+	Default string
+	Slice   bool
+	Local   string
+	JSON    string
+	Doc     string
+	// Members are the elements of the substitution group.
+	Members []*substitute
+}
+
+// typeHierarchy is the place of a complex type in an extension hierarchy.
+type typeHierarchy struct {
+	TypeName string
+	// Interface is the interface implemented by the type and the types
+	// derived from it, if it is abstract or extended.
+	Interface string
+	// XSIType is the name of the schema type for global types, which declare
+	// it when marshalled and are registered to be decoded by it.
+	XSIType *xml.Name
+	// Derived is set when the type extends another complex type.
+	Derived bool
+	// Registered is set when the type is not abstract.
+	Registered bool
+	// HidesBase is set when the type embeds a base type with its own
+	// MarshalXML method, which must not be promoted to the marshalled struct.
+	HidesBase bool
+	// HidesDecoder is set when the type embeds a base type with an
+	// UnmarshalXML method resolving its QName attributes, which anonymous
+	// structs must hide likewise.
+	HidesDecoder bool
+	// QNames is set when the type has QName attributes, whose namespaces are
+	// declared by its MarshalXML method.
+	QNames bool
+}
+
+// indexDerivedTypes indexes the types extending each global complex type by
+// the symbol of the base type.
+func (st *symbolTable) indexDerivedTypes() {
+	st.derived = make(map[*symbol][]*symbol)
+	for _, s := range st.types.all {
+		if base := st.baseType(s.decl, s.schema); base != nil {
+			st.derived[base] = append(st.derived[base], s)
+		}
+	}
+}
+
+// baseType returns the complex type extended by the declaration of a complex
+// type of the schema, or nil.
+func (st *symbolTable) baseType(decl interface{}, schema *XSDSchema) *symbol {
+	ct, ok := decl.(*XSDComplexType)
+	if !ok || ct.ComplexContent.Extension.Base == "" {
+		return nil
+	}
+	base := st.lookupType(resolveQName(ct.ComplexContent.Extension.Base, schema.Xmlns))
+	if base == nil {
+		return nil
+	}
+	if _, ok := base.decl.(*XSDComplexType); !ok {
+		return nil
+	}
+	return base
+}
+
+// polymorphicType returns the symbol of the type named xsdType in the current
+// schema if it is an abstract or extended complex type, or nil.
+func (g *GoWSDL) polymorphicType(xsdType string) *symbol {
+	s := g.symbols.lookupType(resolveQName(xsdType, g.currentSchema.Xmlns))
+	if s == nil {
+		return nil
+	}
+	ct, ok := s.decl.(*XSDComplexType)
+	if !ok || !ct.Abstract && len(g.symbols.derived[s]) == 0 {
+		return nil
+	}
+	return s
+}
+
+// polymorphicField returns the field generated for the element if its type
+// is abstract or extended, or nil.
+func (g *GoWSDL) polymorphicField(el *XSDElement) *polymorphicField {
+	if el.Type == "" {
+		return nil
+	}
+	s := g.polymorphicType(el.Type)
+	if s == nil {
+		return nil
+	}
+
+	qualifier := g.qualifier(s.name.Space, g.currentPackage, g.typeImports)
+	name := replaceReservedWords(makePublic(s.goName))
+	field := &polymorphicField{
+		Name:      g.elementFieldName(el),
+		Interface: qualifier + "Any" + name,
+		Slice:     el.Repeated(),
+		Local:     el.Name,
+		JSON:      el.Name,
+		Doc:       el.Doc,
+	}
+	if !s.decl.(*XSDComplexType).Abstract {
+		field.Default = qualifier + name
+	}
+	return field
+}
+
+// typeHierarchy returns the place in its extension hierarchy of the complex
+// type, generated as the struct named typeName, or nil if it is neither
+// extended nor extending another type.
+func (g *GoWSDL) typeHierarchy(typeName string, ct *XSDComplexType) *typeHierarchy {
+	h := &typeHierarchy{TypeName: typeName}
+	s := g.symbols.decls[ct]
+	if s != nil && (ct.Abstract || len(g.symbols.derived[s]) > 0) {
+		h.Interface = "Any" + typeName
+	}
+	if base := g.symbols.baseType(ct, g.currentSchema); base != nil {
+		h.Derived = true
+		h.HidesBase = g.symbols.baseType(base.decl, base.schema) != nil ||
+			g.marshalsQNames(ct.ComplexContent.Extension.Base)
+		h.HidesDecoder = g.qnameAttributesOf(ct.ComplexContent.Extension.Base)
+		h.QNames = g.qnameAttributes(ct)
+	}
+	if h.Interface == "" && !h.Derived {
+		return nil
+	}
+	if s != nil {
+		h.XSIType = &s.name
+		h.Registered = !ct.Abstract
+	}
+	return h
+}
+
+// marshalsXSIType reports whether the struct generated for the given type has
+// a MarshalXML method declaring its xsi:type.
+func (g *GoWSDL) marshalsXSIType(xsdType string) bool {
+	if g.usesSOAPEncoding() {
+		return false
+	}
+	s := g.symbols.lookupType(resolveQName(xsdType, g.currentSchema.Xmlns))
+	return s != nil && g.symbols.baseType(s.decl, s.schema) != nil
+}
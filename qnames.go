@@ -0,0 +1,125 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import "encoding/xml"
+
+// qnameMethods are the MarshalXML and UnmarshalXML methods of a struct with
+// attributes of type xs:QName or xs:NOTATION. An attribute cannot declare
+// the prefix of its name, which is declared on the element of the struct when
+// marshalled and resolved against the namespaces in its scope when decoded.
+type qnameMethods struct {
+	TypeName string
+	// Name is the name of the element of structs with an XMLName field,
+	// which encoding/xml ignores for values with a MarshalXML method.
+	Name *xml.Name
+	// Marshal is set unless the struct has another MarshalXML method, which
+	// declares the namespaces instead.
+	Marshal bool
+	// Unmarshal is set unless the struct has an UnmarshalXML method decoding
+	// choices, which resolves the names instead.
+	Unmarshal bool
+	// HidesBase is set when the type embeds a base type with its own
+	// UnmarshalXML method, which must not be promoted to the decoded struct.
+	HidesBase bool
+}
+
+// qnameMethods returns the methods of the struct named typeName generated
+// for the complex type, or nil if it has no QName attributes. local is the
+// name of the element of the struct if it has an XMLName field.
+func (g *GoWSDL) qnameMethods(typeName, local string, content *structContent, ct *XSDComplexType) *qnameMethods {
+	if !g.qnameAttributes(ct) {
+		return nil
+	}
+	m := &qnameMethods{TypeName: typeName, Unmarshal: len(content.Decoded) == 0}
+	if local != "" {
+		m.Name = &xml.Name{Space: g.currentSchema.TargetNamespace, Local: local}
+	}
+	// derived types declare the namespaces in the MarshalXML method declaring
+	// their xsi:type or hiding the one of their base type
+	if h := g.typeHierarchy(typeName, ct); h == nil || !(h.Derived && h.XSIType != nil) && !h.HidesBase {
+		m.Marshal = !g.usesSOAPEncoding()
+	}
+	if base := ct.ComplexContent.Extension.Base; base != "" {
+		m.HidesBase = g.qnameAttributesOf(base)
+	}
+	return m
+}
+
+// qnameAttributes reports whether the struct generated for the complex type
+// has attributes of type xs:QName or xs:NOTATION, including the ones of the
+// base type it embeds.
+func (g *GoWSDL) qnameAttributes(ct *XSDComplexType) bool {
+	var attributes []*XSDAttribute
+	switch {
+	case ct.ComplexContent.Extension.Base != "":
+		attributes = append(g.baseAttributes(ct.ComplexContent.Extension.Base, make(map[*XSDComplexType]bool)),
+			ct.ComplexContent.Extension.Attributes...)
+	case ct.SimpleContent.Extension.Base != "":
+		attributes = ct.SimpleContent.Extension.Attributes
+	default:
+		attributes = g.contentAttributes(ct)
+	}
+	for _, attr := range attributes {
+		if attr.Fixed == "" && g.qnameType(attr.Type, make(map[*symbol]bool)) {
+			return true
+		}
+	}
+	return false
+}
+
+// qnameAttributesOf reports whether the struct generated for the complex type
+// named xsdType has QName attributes.
+func (g *GoWSDL) qnameAttributesOf(xsdType string) bool {
+	if g.isMappedType(xsdType) {
+		return false
+	}
+	s := g.symbols.lookupType(resolveQName(xsdType, g.currentSchema.Xmlns))
+	if s == nil {
+		return false
+	}
+	ct, ok := s.decl.(*XSDComplexType)
+	if !ok {
+		return false
+	}
+
+	schema := g.currentSchema
+	g.currentSchema = s.schema
+	defer func() { g.currentSchema = schema }()
+	return g.qnameAttributes(ct)
+}
+
+// marshalsQNames reports whether the struct generated for the given type has
+// a MarshalXML method declaring the namespaces of its QName attributes.
+func (g *GoWSDL) marshalsQNames(xsdType string) bool {
+	return !g.usesSOAPEncoding() && g.qnameAttributesOf(xsdType)
+}
+
+// qnameType reports whether the simple type named xsdType is xs:QName,
+// xs:NOTATION or a restriction of them, generated as soap.QName.
+func (g *GoWSDL) qnameType(xsdType string, visiting map[*symbol]bool) bool {
+	if xsdType == "" || g.isMappedType(xsdType) {
+		return false
+	}
+	name, resolved := resolveQName(xsdType, g.currentSchema.Xmlns)
+	if !resolved || builtinNamespaces[name.Space] {
+		return removePointerFromType(toGoType(xsdType, false)) == "soap.QName"
+	}
+	s := g.symbols.lookupType(name, resolved)
+	if s == nil || visiting[s] {
+		return false
+	}
+	st, ok := s.decl.(*XSDSimpleType)
+	if !ok || st.List.ItemType != "" || st.Union.MemberTypes != "" || st.Union.SimpleType != nil {
+		return false
+	}
+	visiting[s] = true
+	defer delete(visiting, s)
+
+	schema := g.currentSchema
+	g.currentSchema = s.schema
+	defer func() { g.currentSchema = schema }()
+	return g.qnameType(st.Restriction.Base, visiting)
+}
@@ -0,0 +1,130 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+// contentAttributes returns the attributes of the struct generated for a
+// complex type which doesn't extend another type. A restriction inherits the
+// attributes of its base type, unless it redeclares or prohibits them.
+func (g *GoWSDL) contentAttributes(ct *XSDComplexType) []*XSDAttribute {
+	switch {
+	case ct.ComplexContent.Restriction.Base != "":
+		return g.restrictAttributes(ct.ComplexContent.Restriction.Base, ct.ComplexContent.Restriction.Attributes)
+	case ct.SimpleContent.Restriction.Base != "":
+		return g.restrictAttributes(ct.SimpleContent.Restriction.Base, ct.SimpleContent.Restriction.Attributes)
+	}
+	return ct.Attributes
+}
+
+// restrictAttributes returns the attributes of the base type, replaced by the
+// attributes of the same name declared by a restriction and without the
+// prohibited ones.
+func (g *GoWSDL) restrictAttributes(base string, declared []*XSDAttribute) []*XSDAttribute {
+	restricted := make(map[string]*XSDAttribute)
+	for _, attr := range declared {
+		restricted[attributeXMLName(attr)] = attr
+	}
+
+	var attributes []*XSDAttribute
+	for _, attr := range g.baseAttributes(base, make(map[*XSDComplexType]bool)) {
+		name := attributeXMLName(attr)
+		if r, ok := restricted[name]; ok {
+			attr = r
+			delete(restricted, name)
+		}
+		if attr.Use != "prohibited" {
+			attributes = append(attributes, attr)
+		}
+	}
+	// attributes allowed by an attribute wildcard of the base type
+	for _, attr := range declared {
+		if restricted[attributeXMLName(attr)] != nil && attr.Use != "prohibited" {
+			attributes = append(attributes, attr)
+		}
+	}
+	return attributes
+}
+
+// baseAttributes returns all the attributes of the type named base, including
+// the inherited ones, with their types resolving in the current schema.
+func (g *GoWSDL) baseAttributes(base string, visiting map[*XSDComplexType]bool) []*XSDAttribute {
+	s := g.symbols.lookupType(resolveQName(base, g.currentSchema.Xmlns))
+	if s == nil {
+		return nil
+	}
+	ct, ok := s.decl.(*XSDComplexType)
+	if !ok || visiting[ct] {
+		return nil
+	}
+	visiting[ct] = true
+	defer delete(visiting, ct)
+
+	schema := g.currentSchema
+	g.currentSchema = s.schema
+	var attributes []*XSDAttribute
+	switch {
+	case ct.ComplexContent.Extension.Base != "":
+		attributes = append(g.baseAttributes(ct.ComplexContent.Extension.Base, visiting), ct.ComplexContent.Extension.Attributes...)
+	case ct.SimpleContent.Extension.Base != "":
+		attributes = append(g.baseAttributes(ct.SimpleContent.Extension.Base, visiting), ct.SimpleContent.Extension.Attributes...)
+	default:
+		attributes = g.contentAttributes(ct)
+	}
+	g.currentSchema = schema
+
+	if s.schema == schema {
+		return attributes
+	}
+	localized := make([]*XSDAttribute, len(attributes))
+	for i, attr := range attributes {
+		c := *attr
+		c.Type = localizeQName(attr.Type, s.schema.Xmlns, schema)
+		localized[i] = &c
+	}
+	return localized
+}
+
+// simpleContentType returns the Go type of the value of a complex type with
+// simple content restricting another type.
+func (g *GoWSDL) simpleContentType(ct *XSDComplexType) string {
+	return g.restrictedValueType(ct, make(map[*XSDComplexType]bool))
+}
+
+func (g *GoWSDL) restrictedValueType(ct *XSDComplexType, visiting map[*XSDComplexType]bool) string {
+	restriction := ct.SimpleContent.Restriction
+	if st := restriction.SimpleType; st != nil && st.Restriction.Base != "" {
+		return removePointerFromType(g.toGoType(st.Restriction.Base, false))
+	}
+	return g.valueType(restriction.Base, visiting)
+}
+
+// valueType returns the Go type of the value of the simple type or of the
+// complex type with simple content named xsdType.
+func (g *GoWSDL) valueType(xsdType string, visiting map[*XSDComplexType]bool) string {
+	s := g.symbols.lookupType(resolveQName(xsdType, g.currentSchema.Xmlns))
+	if s == nil {
+		return removePointerFromType(g.toGoType(xsdType, false))
+	}
+	ct, ok := s.decl.(*XSDComplexType)
+	if !ok {
+		return removePointerFromType(g.toGoType(xsdType, false))
+	}
+	if visiting[ct] {
+		return "string"
+	}
+	visiting[ct] = true
+	defer delete(visiting, ct)
+
+	schema := g.currentSchema
+	g.currentSchema = s.schema
+	defer func() { g.currentSchema = schema }()
+	switch {
+	case ct.SimpleContent.Extension.Base != "":
+		return g.valueType(ct.SimpleContent.Extension.Base, visiting)
+	case ct.SimpleContent.Restriction.Base != "":
+		return g.restrictedValueType(ct, visiting)
+	}
+	// complex types with mixed content
+	return "string"
+}
@@ -0,0 +1,172 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/xml"
+	"log"
+	"sort"
+	"strings"
+)
+
+// rpcWrapperKey identifies the input or output of an operation of a port type,
+// which is wrapped in its own element in RPC style even when it shares its
+// message with other operations.
+type rpcWrapperKey struct {
+	portType  string
+	operation string
+	direction string
+}
+
+const (
+	inputDirection  = "input"
+	outputDirection = "output"
+)
+
+// genRPCWrappers synthesizes the wrapper elements of RPC style operations.
+//
+// In RPC style the SOAP body child is not described by the schema: it is an
+// element named after the operation (suffixed with "Response" for the output),
+// qualified by the soap:body namespace, with one child accessor per message
+// part. The wrappers are added as global elements of synthesized schemas so
+// the regular templates generate their Go types, and findType resolves RPC
+// operations to them.
+func (g *GoWSDL) genRPCWrappers() {
+	g.rpcWrappers = make(map[rpcWrapperKey]*XSDElement)
+	schemas := make(map[string]*XSDSchema)
+
+	for _, portType := range g.wsdl.PortTypes {
+		binding := g.findBinding(portType.Name)
+		if binding == nil {
+			continue
+		}
+
+		for _, op := range portType.Operations {
+			bindingOp := findBindingOperation(binding, op.Name)
+			if bindingOp == nil || binding.soapStyle(bindingOp) != "rpc" {
+				continue
+			}
+
+			key := rpcWrapperKey{portType: portType.Name, operation: op.Name, direction: inputDirection}
+			g.addRPCWrapper(schemas, key, op.Input.Message, op.Name, binding.soapInputBody(bindingOp))
+			key.direction = outputDirection
+			g.addRPCWrapper(schemas, key, op.Output.Message, op.Name+"Response", binding.soapOutputBody(bindingOp))
+		}
+	}
+
+	// in a stable order, for the generated code not to change between runs
+	namespaces := make([]string, 0, len(schemas))
+	for ns := range schemas {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	for _, ns := range namespaces {
+		g.wsdl.Types.Schemas = append(g.wsdl.Types.Schemas, schemas[ns])
+	}
+}
+
+func (g *GoWSDL) addRPCWrapper(schemas map[string]*XSDSchema, key rpcWrapperKey, message, name string, body WSDLSOAPBody) {
+	msg := g.findMessage(message)
+	if msg == nil {
+		return
+	}
+
+	ns := body.Namespace
+	if ns == "" {
+		ns = g.wsdl.TargetNamespace
+	}
+
+	schema, ok := schemas[ns]
+	if !ok {
+		schema = &XSDSchema{
+			Xmlns:           make(map[string]string),
+			TargetNamespace: ns,
+			synthesized:     true,
+		}
+		for prefix, namespace := range g.wsdl.Xmlns {
+			schema.Xmlns[prefix] = namespace
+		}
+		schemas[ns] = schema
+	}
+
+	// the operations of several port types bound in RPC style share the
+	// wrapper of their qualified name
+	for _, el := range schema.Elements {
+		if el.Name == name {
+			g.rpcWrappers[key] = el
+			return
+		}
+	}
+
+	for _, s := range g.wsdl.Types.Schemas {
+		for _, el := range s.Elements {
+			if el.Name == name {
+				log.Printf("[WARN] RPC wrapper %s collides with a global element of %s", name, s.TargetNamespace)
+			}
+		}
+	}
+
+	sequence := &XSDModelGroup{XMLName: xml.Name{Space: xmlschema11, Local: "sequence"}}
+	for _, part := range msg.Parts {
+		if !bodyIncludesPart(body, part.Name) {
+			continue
+		}
+		if part.Element != "" {
+			sequence.Particles = append(sequence.Particles, &XSDParticle{Element: &XSDElement{
+				Ref: localizeQName(part.Element, msg.xmlns, schema),
+			}})
+			continue
+		}
+		sequence.Particles = append(sequence.Particles, &XSDParticle{Element: &XSDElement{
+			Name: part.Name,
+			Type: localizeQName(part.Type, msg.xmlns, schema),
+		}})
+	}
+
+	wrapper := &XSDElement{Name: name, ComplexType: new(XSDComplexType)}
+	wrapper.ComplexType.Sequence = sequence
+
+	schema.Elements = append(schema.Elements, wrapper)
+	g.rpcWrappers[key] = wrapper
+}
+
+// rpcWrapper reports whether the elements of the current schema are RPC
+// wrappers, whose part accessors are required and unqualified.
+func (g *GoWSDL) rpcWrapper() bool {
+	return g.currentSchema.synthesized
+}
+
+// bodyIncludesPart reports whether the part is carried by the SOAP body, as
+// restricted by its optional parts attribute.
+func bodyIncludesPart(body WSDLSOAPBody, part string) bool {
+	if body.Parts == "" {
+		return true
+	}
+	for _, p := range strings.Fields(body.Parts) {
+		if p == part {
+			return true
+		}
+	}
+	return false
+}
+
+func findBindingOperation(binding *WSDLBinding, name string) *WSDLOperation {
+	for _, op := range binding.Operations {
+		if op.Name == name {
+			return op
+		}
+	}
+	return nil
+}
+
+func (g *GoWSDL) findMessage(name string) *WSDLMessage {
+	name = stripns(name)
+	for _, msg := range g.wsdl.Messages {
+		if msg.Name == name {
+			return msg
+		}
+	}
+	return nil
+}
@@ -3,7 +3,7 @@ package gowsdl
 var serverHeaderTmpl = `
 // Code generated by gowsdl DO NOT EDIT.
 
-package {{.}}
+package {{.Pkg}}
 
 import (
 	"fmt"
@@ -13,9 +13,11 @@ import (
 	"encoding/xml"
 	"net/http"
 
-	{{/*range .Imports*/}}
-		{{/*.*/}}
-	{{/*end*/}}
+	"github.com/ilmich/gowsdl/soap"
+
+	{{range .Imports}}
+		{{.}}
+	{{end}}
 )
 
 `
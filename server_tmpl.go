@@ -4,17 +4,22 @@ var serverTmpl = `
 
 var WSDLUndefinedError = errors.New("Server was unable to process request. --> Object reference not set to an instance of an object.")
 
+// SOAPEnvelopeRequest is the envelope of SOAP 1.1 and SOAP 1.2 requests
 type SOAPEnvelopeRequest struct {
-	XMLName xml.Name ` + "`" + `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"` + "`" + `
+	XMLName xml.Name ` + "`" + `xml:"Envelope"` + "`" + `
 	Body SOAPBodyRequest
+
+	validate bool
 }
 
 type SOAPBodyRequest struct {
-	XMLName xml.Name ` + "`" + `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"` + "`" + `
+	XMLName xml.Name ` + "`" + `xml:"Body"` + "`" + `
 	{{range .}}
+		{{$portType := .Name}}
 		{{range .Operations}}
-				{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}} ` + `
-  				{{$requestType}} *{{$requestType}} ` + "`" + `xml:,omitempty` + "`" + `
+				{{$requestName := findType $portType . "input" | replaceReservedWords | makePublic}}
+				{{$requestType := findGoType $portType . "input"}} ` + `
+  				{{$requestName}} *{{$requestType}} ` + "`" + `xml:",omitempty"` + "`" + `
 		{{end}}
 	{{end}}
 }
@@ -43,35 +48,80 @@ type Fault struct { ` + `
 	Code   string    ` + "`" + `xml:"faultcode,omitempty"` + "`" + `
 	String string    ` + "`" + `xml:"faultstring,omitempty"` + "`" + `
 	Actor  string 	 ` + "`" + `xml:"faultactor,omitempty"` + "`" + `
-	Detail string    ` + "`" + `xml:"detail,omitempty"` + "`" + `
+	Detail interface{} ` + "`" + `xml:"detail,omitempty"` + "`" + `
+}
+
+
+// Fault12 is the fault of SOAP 1.2 responses
+type Fault12 struct { ` + `
+	XMLName xml.Name ` + "`" + `xml:"soap:Fault"` + "`" + `
+
+	Code   string        ` + "`" + `xml:"soap:Code>soap:Value"` + "`" + `
+	Reason Fault12Reason ` + "`" + `xml:"soap:Reason>soap:Text"` + "`" + `
+	Detail interface{}   ` + "`" + `xml:"soap:Detail,omitempty"` + "`" + `
 }
 
+type Fault12Reason struct { ` + `
+	Lang string ` + "`" + `xml:"xml:lang,attr"` + "`" + `
+	Text string ` + "`" + `xml:",chardata"` + "`" + `
+}
 
 type SOAPBodyResponse struct { ` + `
 	XMLName xml.Name   ` + "`" + `xml:"soap:Body"` + "`" + `
 	Fault   *Fault ` + "`" + `xml:",omitempty"` + "`" + `
+	Fault12 *Fault12 ` + "`" + `xml:",omitempty"` + "`" + `
 {{range .}}
+	{{$portType := .Name}}
 	{{range .Operations}}
-		{{$responseType := findType .Output.Message | replaceReservedWords | makePublic}}
-		{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}} ` + `
-			{{$requestType}} *{{$responseType}} ` + "`" + `xml:",omitempty"` + "`" + `
+		{{$responseType := findGoType $portType . "output"}}
+		{{$requestName := findType $portType . "input" | replaceReservedWords | makePublic}} ` + `
+			{{$requestName}} *{{$responseType}} ` + "`" + `xml:",omitempty"` + "`" + `
 	{{end}}
 {{end}}
 
 }
 
 {{range .}}
+	{{$portType := .Name}}
 	{{range .Operations}}
-		{{$responseType := findType .Output.Message | replaceReservedWords | makePublic}}
-		{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}}
-		{{$requestTypeSource := findType .Input.Message | replaceReservedWords }}
-func (service *SOAPBodyRequest) {{$requestType}}Func(request *{{$requestType}}) (*{{$responseType}}, error) {
+		{{$responseType := findGoType $portType . "output"}}
+		{{$requestName := findType $portType . "input" | replaceReservedWords | makePublic}}
+		{{$requestType := findGoType $portType . "input"}}
+func (service *SOAPBodyRequest) {{$requestName}}Func(request *{{$requestType}}) (*{{$responseType}}, error) {
 	return nil, WSDLUndefinedError
 }
 	{{end}}
 {{end}}
 
 
+// setFault sets the fault of the response in its version of SOAP, caused by
+// the client or by the server
+func (resp *SOAPEnvelopeResponse) setFault(client bool, reason string, detail interface{}) {
+	if resp.PrefixSoap == soap.XmlNsSoap12Env {
+		code := "soap:Receiver"
+		if client {
+			code = "soap:Sender"
+		}
+		resp.Body.Fault12 = &Fault12{
+			Code:   code,
+			Reason: Fault12Reason{Lang: "en", Text: reason},
+			Detail: detail,
+		}
+		return
+	}
+
+	code := "soap:Server"
+	if client {
+		code = "soap:Client"
+	}
+	resp.Body.Fault = &Fault{
+		Space:  soap.XmlNsSoapEnv,
+		Code:   code,
+		String: reason,
+		Detail: detail,
+	}
+}
+
 func (service *SOAPEnvelopeRequest) call(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "text/xml; charset=utf-8")
 	val := reflect.ValueOf(&service.Body).Elem()
@@ -86,23 +136,20 @@ func (service *SOAPEnvelopeRequest) call(w http.ResponseWriter, r *http.Request)
 	}
 
 	resp := NewSOAPEnvelopResponse()
+	// SOAP 1.2 requests are answered in SOAP 1.2
+	if strings.Contains(r.Header.Get("Content-Type"), "application/soap+xml") {
+		resp.PrefixSoap = soap.XmlNsSoap12Env
+		w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	}
 	defer func() {
 		if r := recover(); r != nil {
-			resp.Body.Fault = &Fault{}
-			resp.Body.Fault.Space = "http://schemas.xmlsoap.org/soap/envelope/"
-			resp.Body.Fault.Code = "soap:Server"
-			resp.Body.Fault.Detail = fmt.Sprintf("%v", r)
-			resp.Body.Fault.String = fmt.Sprintf("%v", r)
+			resp.setFault(false, fmt.Sprintf("%v", r), fmt.Sprintf("%v", r))
 		}
 		xml.NewEncoder(w).Encode(resp)
 	}()
 
-	header := r.Header.Get("Content-Type")
-	if strings.Index(header, "application/soap+xml") >= 0 {
-		panic("Could not find an appropriate Transport Binding to invoke.")
-	}
-
-	err := xml.NewDecoder(r.Body).Decode(service)
+	// resolves QNames against the namespaces in scope, as clients do
+	err := soap.NewDecoder(r.Body).Decode(service)
 	if err != nil {
 		panic(err)
 	}
@@ -130,6 +177,13 @@ func (service *SOAPEnvelopeRequest) call(w http.ResponseWriter, r *http.Request)
 			panic(WSDLUndefinedError)
 		}
 
+		if service.validate {
+			if err := soap.ValidateContent(field.Interface()); err != nil {
+				resp.setFault(true, "invalid request: "+err.Error(), err)
+				return
+			}
+		}
+
 		vals := m.Call([]reflect.Value{field})
 		if vals[1].IsNil() {
 			reflect.ValueOf(&resp.Body).Elem().FieldByName(name).Set(vals[0])
@@ -145,4 +199,12 @@ func Endpoint(w http.ResponseWriter, r *http.Request) {
 	request.call(w, r)
 }
 
+// ValidatingEndpoint is like Endpoint, but rejects the requests which do not
+// satisfy the constraints of their schema with a soap:Client fault whose
+// detail lists the violations, without calling the handler.
+func ValidatingEndpoint(w http.ResponseWriter, r *http.Request) {
+	request := SOAPEnvelopeRequest{validate: true}
+	request.call(w, r)
+}
+
 `
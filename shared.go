@@ -0,0 +1,142 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StartAll generates the code of several services at once. Each service is
+// generated in its own package, whose import path is importPath followed by
+// the package name of the service, with one package per XML namespace.
+//
+// Namespaces whose schemas are identical in all the services declaring them
+// are generated once, in packages directly under importPath, and imported by
+// every service, so that their types are the same Go types. Namespaces whose
+// schemas differ are generated in each service package.
+//
+// The code of each service is returned in the order of the services, keyed
+// as by Start. PackagePrefix keys are relative to importPath, and a shared
+// package is only returned with the first service using it.
+func StartAll(importPath string, services ...*GoWSDL) ([]map[string][]byte, error) {
+	importPath = strings.TrimSuffix(importPath, "/")
+
+	var namespaces []string
+	fingerprints := make(map[string]map[string]bool)
+	declaredBy := make(map[string]int)
+	for _, g := range services {
+		err := g.unmarshal()
+		if err != nil {
+			return nil, err
+		}
+		g.genRPCWrappers()
+
+		for ns, fingerprint := range namespaceFingerprints(g.wsdl.Types.Schemas) {
+			if _, ok := fingerprints[ns]; !ok {
+				namespaces = append(namespaces, ns)
+				fingerprints[ns] = make(map[string]bool)
+			}
+			fingerprints[ns][fingerprint] = true
+			declaredBy[ns]++
+		}
+	}
+	sort.Strings(namespaces)
+
+	usedPaths := make(map[string]bool)
+	for _, g := range services {
+		usedPaths[importPath+"/"+g.pkg] = true
+	}
+
+	shared := make(map[string]string)
+	for _, ns := range namespaces {
+		if declaredBy[ns] < 2 {
+			continue
+		}
+		if len(fingerprints[ns]) > 1 {
+			log.Printf("[WARN] schemas of namespace %q differ across services, generating them in each service package", ns)
+			continue
+		}
+
+		sharedPath := importPath + "/" + packageName(ns)
+		for i := 2; usedPaths[sharedPath]; i++ {
+			sharedPath = importPath + "/" + packageName(ns) + strconv.Itoa(i)
+		}
+		usedPaths[sharedPath] = true
+		shared[ns] = sharedPath
+	}
+
+	generated := make(map[string]bool)
+	var gocodes []map[string][]byte
+	for _, g := range services {
+		mapping := make(map[string]string, len(shared))
+		for ns, sharedPath := range shared {
+			mapping[ns] = sharedPath
+		}
+		// the user mapping takes precedence
+		for ns, userPath := range g.namespaceImports {
+			mapping[ns] = userPath
+		}
+		g.SetPackagePerNamespace(importPath+"/"+g.pkg, mapping)
+		g.packageRoot = importPath
+
+		gocode, err := g.generate()
+		if err != nil {
+			return nil, err
+		}
+
+		for key := range gocode {
+			if !strings.HasPrefix(key, PackagePrefix) {
+				continue
+			}
+			if generated[key] {
+				delete(gocode, key)
+			}
+			generated[key] = true
+		}
+		gocodes = append(gocodes, gocode)
+	}
+
+	return gocodes, nil
+}
+
+// namespaceFingerprints returns a digest of the content of the schemas of
+// each target namespace, regardless of where they were loaded from.
+func namespaceFingerprints(schemas []*XSDSchema) map[string]string {
+	digests := make(map[string][]string)
+	for _, schema := range schemas {
+		content := *schema
+		// included schemas are part of the namespace already, and imports
+		// may locate the same schema differently
+		content.Includes = nil
+		content.Imports = nil
+		for _, imp := range schema.Imports {
+			content.Imports = append(content.Imports, &XSDImport{Namespace: imp.Namespace})
+		}
+
+		data, err := json.Marshal(content)
+		if err != nil {
+			// never shared
+			log.Printf("[WARN] cannot fingerprint schema of namespace %q: %v", schema.TargetNamespace, err)
+			data = []byte(fmt.Sprintf("%p", schema))
+		}
+		digest := sha256.Sum256(data)
+		digests[schema.TargetNamespace] = append(digests[schema.TargetNamespace], hex.EncodeToString(digest[:]))
+	}
+
+	fingerprints := make(map[string]string, len(digests))
+	for ns, nsDigests := range digests {
+		sort.Strings(nsDigests)
+		digest := sha256.Sum256([]byte(strings.Join(nsDigests, "")))
+		fingerprints[ns] = hex.EncodeToString(digest[:])
+	}
+	return fingerprints
+}
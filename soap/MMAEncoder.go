@@ -119,7 +119,7 @@ func (d *mmaDecoder) Decode(v interface{}) error {
 		contentType := p.Header.Get("Content-Type")
 		if contentType == "text/xml;charset=UTF-8" {
 			// decode SOAP part
-			err := xml.NewDecoder(p).Decode(v)
+			err := newNamespaceDecoder(p).Decode(v)
 			if err != nil {
 				return err
 			}
@@ -1,6 +1,7 @@
 package soap
 
 import (
+	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -37,6 +38,9 @@ func NewBinary(v []byte) *Binary {
 
 // Bytes returns a slice backed by the content of the field
 func (b *Binary) Bytes() []byte {
+	if b.content == nil {
+		return nil
+	}
 	return *b.content
 }
 
@@ -77,13 +81,13 @@ func (b *Binary) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
 			},
 		}, start)
 	}
-	return enc.EncodeElement(b.Bytes, start)
+	return enc.EncodeElement(base64.StdEncoding.EncodeToString(b.Bytes()), start)
 }
 
 // UnmarshalXML implements the xml.Unmarshaler interface to decode a Binary form XML
 func (b *Binary) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	ref := struct {
-		Content []byte          `xml:",innerxml"`
+		Content string          `xml:",chardata"`
 		Include *xopPlaceholder `xml:"http://www.w3.org/2004/08/xop/include Include"`
 	}{}
 
@@ -91,12 +95,18 @@ func (b *Binary) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 		return err
 	}
 
-	b.content = &ref.Content
-
 	if ref.Include != nil {
 		b.packageID = strings.TrimPrefix(ref.Include.Href, "cid:")
 		b.useMTOM = true
+		return nil
+	}
+
+	// base64 content may be split in lines
+	content, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(ref.Content), ""))
+	if err != nil {
+		return err
 	}
+	b.content = &content
 	return nil
 }
 
@@ -243,7 +253,7 @@ func (d *mtomDecoder) Decode(v interface{}) error {
 		}
 		contentType := p.Header.Get("Content-Type")
 		if strings.HasPrefix(contentType, "application/xop+xml") {
-			err := xml.NewDecoder(p).Decode(v)
+			err := newNamespaceDecoder(p).Decode(v)
 			if err != nil {
 				return err
 			}
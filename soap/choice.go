@@ -0,0 +1,13 @@
+package soap
+
+import "encoding/xml"
+
+// UnmarshalFunc is an xml.Unmarshaler calling a function for each element it
+// is decoded from. Generated code uses fields of this type to decode the
+// alternatives of repeated choices in document order.
+type UnmarshalFunc func(d *xml.Decoder, start xml.StartElement) error
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (f UnmarshalFunc) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return f(d, start)
+}
@@ -0,0 +1,69 @@
+package soap
+
+import (
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SetDefault sets the field pointed to by field to the value whose lexical
+// representation is the default or fixed value declared by the schema,
+// allocating pointers. Values which are not valid for the type of the field
+// are ignored, leaving the field unchanged.
+func SetDefault(field interface{}, value string) {
+	rv := reflect.ValueOf(field)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return
+	}
+	rv = rv.Elem()
+
+	t := rv.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	parsed := reflect.New(t)
+	if err := parseLexical(parsed, value); err != nil {
+		return
+	}
+
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	rv.Set(parsed.Elem())
+}
+
+// parseLexical sets the value pointed to by ptr to the value of its lexical
+// representation.
+func parseLexical(ptr reflect.Value, text string) error {
+	switch u := ptr.Interface().(type) {
+	case encoding.TextUnmarshaler:
+		return u.UnmarshalText([]byte(text))
+	case xml.UnmarshalerAttr:
+		return u.UnmarshalXMLAttr(xml.Attr{Value: text})
+	}
+
+	rv := ptr.Elem()
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return ParseNumber([]byte(text), ptr.Interface())
+	default:
+		return fmt.Errorf("no lexical representation of %s", rv.Type())
+	}
+	return nil
+}
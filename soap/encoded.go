@@ -0,0 +1,600 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+const (
+	XmlNsSoapEnc   string = "http://schemas.xmlsoap.org/soap/encoding/"
+	XmlNsSoap12Enc string = "http://www.w3.org/2003/05/soap-encoding"
+	XmlNsXsi       string = "http://www.w3.org/2001/XMLSchema-instance"
+	XmlNsXsd       string = "http://www.w3.org/2001/XMLSchema"
+)
+
+// XSITyper is implemented by types that know the name of the schema type
+// they were generated from. With the SOAP encoding the name is sent as the
+// xsi:type attribute of every value.
+type XSITyper interface {
+	XSIType() xml.Name
+}
+
+var (
+	xsiTyperType         = reflect.TypeOf((*XSITyper)(nil)).Elem()
+	marshalerType        = reflect.TypeOf((*xml.Marshaler)(nil)).Elem()
+	marshalerAttrType    = reflect.TypeOf((*xml.MarshalerAttr)(nil)).Elem()
+	soapEncArrayTypeName = xml.Name{Space: XmlNsSoapEnc, Local: "Array"}
+)
+
+// xsdTypes maps the runtime types of this package to their XSD type.
+var xsdTypes = map[reflect.Type]string{
+	reflect.TypeOf(XSDDateTime{}):   "dateTime",
+	reflect.TypeOf(XSDDate{}):       "date",
+	reflect.TypeOf(XSDTime{}):       "time",
+	reflect.TypeOf(XSDDuration{}):   "duration",
+	reflect.TypeOf(XSDGYear{}):      "gYear",
+	reflect.TypeOf(XSDGYearMonth{}): "gYearMonth",
+	reflect.TypeOf(XSDGMonth{}):     "gMonth",
+	reflect.TypeOf(XSDGMonthDay{}):  "gMonthDay",
+	reflect.TypeOf(XSDGDay{}):       "gDay",
+	reflect.TypeOf(XSDDecimal{}):    "decimal",
+	reflect.TypeOf(XSDInteger{}):    "integer",
+	reflect.TypeOf(XSDList{}):       "NMTOKENS",
+	reflect.TypeOf(QName{}):         "QName",
+	reflect.TypeOf(Binary{}):        "base64Binary",
+}
+
+// xsdKinds maps Go kinds to the XSD type of their values.
+var xsdKinds = map[reflect.Kind]string{
+	reflect.String:  "string",
+	reflect.Bool:    "boolean",
+	reflect.Int:     "long",
+	reflect.Int8:    "byte",
+	reflect.Int16:   "short",
+	reflect.Int32:   "int",
+	reflect.Int64:   "long",
+	reflect.Uint:    "unsignedLong",
+	reflect.Uint8:   "unsignedByte",
+	reflect.Uint16:  "unsignedShort",
+	reflect.Uint32:  "unsignedInt",
+	reflect.Uint64:  "unsignedLong",
+	reflect.Float32: "float",
+	reflect.Float64: "double",
+}
+
+// encodedContent marshals the body content of a request following the SOAP
+// Section 5 encoding rules: the encodingStyle is declared on the body child and
+// every value carries its xsi:type.
+type encodedContent struct {
+	content       interface{}
+	encodingStyle string
+}
+
+// MarshalXML implements xml.Marshaler on encodedContent
+func (c encodedContent) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	v := reflect.ValueOf(c.content)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if xmlName, ok := structXMLName(v); ok {
+		start.Name = xmlName
+	}
+	PrefixRPCWrapper(&start)
+	start.Attr = append(start.Attr,
+		xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: XmlNsXsi},
+		xml.Attr{Name: xml.Name{Local: "xmlns:xsd"}, Value: XmlNsXsd},
+		xml.Attr{Name: xml.Name{Local: "xmlns:soapenc"}, Value: XmlNsSoapEnc},
+		xml.Attr{Name: xml.Name{Local: "soap:encodingStyle"}, Value: c.encodingStyle},
+	)
+
+	// The body child is the RPC wrapper, only its accessors are typed.
+	if v.Kind() != reflect.Struct {
+		return encodeValue(e, v, start, false)
+	}
+	return encodeStruct(e, v, start)
+}
+
+// structXMLName returns the element name declared by the XMLName field of v.
+func structXMLName(v reflect.Value) (xml.Name, bool) {
+	if v.Kind() != reflect.Struct {
+		return xml.Name{}, false
+	}
+	f, ok := v.Type().FieldByName("XMLName")
+	if !ok || f.Type != reflect.TypeOf(xml.Name{}) {
+		return xml.Name{}, false
+	}
+	if name := v.FieldByIndex(f.Index).Interface().(xml.Name); name.Local != "" {
+		return name, true
+	}
+	ns, local, _ := parseXMLTag(f.Tag.Get("xml"))
+	if local == "" {
+		return xml.Name{}, false
+	}
+	return xml.Name{Space: ns, Local: local}, true
+}
+
+// encodeValue writes v as an element named after start, adding its xsi:type
+// when typed is set.
+func encodeValue(e *xml.Encoder, v reflect.Value, start xml.StartElement, typed bool) error {
+	for v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xsi:nil"}, Value: "true"})
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	}
+
+	if typed {
+		start.Attr = append(start.Attr, xsiTypeAttrs(v)...)
+	}
+
+	if v.Type().Implements(marshalerType) || reflect.PtrTo(v.Type()).Implements(marshalerType) {
+		return e.EncodeElement(v.Interface(), start)
+	}
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return encodeStruct(e, v, start)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return e.EncodeElement(base64.StdEncoding.EncodeToString(v.Bytes()), start)
+		}
+	}
+	return e.EncodeElement(v.Interface(), start)
+}
+
+// xsiTypeName returns the schema type name of the values of type t.
+func xsiTypeName(t reflect.Type) (xml.Name, bool) {
+	if t.Implements(xsiTyperType) {
+		return reflect.Zero(t).Interface().(XSITyper).XSIType(), true
+	}
+	if t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(xsiTyperType) {
+		return reflect.New(t).Interface().(XSITyper).XSIType(), true
+	}
+	if t.Kind() == reflect.Ptr {
+		return xsiTypeName(t.Elem())
+	}
+	if name, ok := xsdTypes[t]; ok {
+		return xml.Name{Space: XmlNsXsd, Local: name}, true
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return xml.Name{Space: XmlNsXsd, Local: "base64Binary"}, true
+	}
+	if name, ok := xsdKinds[t.Kind()]; ok {
+		return xml.Name{Space: XmlNsXsd, Local: name}, true
+	}
+	return xml.Name{}, false
+}
+
+// xsiTypeAttrs returns the xsi:type attribute of v, along with the namespace
+// declaration of the type prefix when needed.
+func xsiTypeAttrs(v reflect.Value) []xml.Attr {
+	name, ok := xsiTypeName(v.Type())
+	if !ok {
+		return nil
+	}
+	var attrs []xml.Attr
+	value := qualify(name, "tns", &attrs)
+	attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: value})
+	if name == soapEncArrayTypeName {
+		if items, ok := arrayItems(v); ok {
+			itemType, _ := xsiTypeName(items.Type().Elem())
+			attrs = append(attrs, xml.Attr{
+				Name:  xml.Name{Local: "soapenc:arrayType"},
+				Value: fmt.Sprintf("%s[%d]", qualify(itemType, "itns", &attrs), items.Len()),
+			})
+		}
+	}
+	return attrs
+}
+
+// qualify returns the prefixed form of a type name. Unless the namespace is
+// one declared on the body child, the given prefix is declared in attrs.
+func qualify(name xml.Name, prefix string, attrs *[]xml.Attr) string {
+	switch name.Space {
+	case XmlNsXsd:
+		return "xsd:" + name.Local
+	case XmlNsSoapEnc:
+		return "soapenc:" + name.Local
+	case "":
+		return name.Local
+	}
+	*attrs = append(*attrs, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: name.Space})
+	return prefix + ":" + name.Local
+}
+
+// arrayItems returns the items of a soapenc:Array struct, held by its
+// `xml:",any"` slice field.
+func arrayItems(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	for i := 0; i < v.NumField(); i++ {
+		_, _, flags := parseXMLTag(v.Type().Field(i).Tag.Get("xml"))
+		if flags["any"] && v.Field(i).Kind() == reflect.Slice {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// encodeStruct writes the fields of v, flattening embedded structs, within
+// the element start.
+func encodeStruct(e *xml.Encoder, v reflect.Value, start xml.StartElement) error {
+	var children []func() error
+	isArray := false
+	if name, ok := xsiTypeName(v.Type()); ok && name == soapEncArrayTypeName {
+		isArray = true
+	}
+
+	var walk func(v reflect.Value) error
+	walk = func(v reflect.Value) error {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			fv := v.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			tag := field.Tag.Get("xml")
+			if tag == "-" || field.Name == "XMLName" {
+				continue
+			}
+			ns, name, flags := parseXMLTag(tag)
+
+			if field.Anonymous && tag == "" {
+				for fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						break
+					}
+					fv = fv.Elem()
+				}
+				if fv.Kind() == reflect.Struct {
+					if err := walk(fv); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if flags["omitempty"] && isEmptyValue(fv) {
+				continue
+			}
+
+			switch {
+			case flags["attr"]:
+				if isArray && name == "arrayType" {
+					continue
+				}
+				attr, err := marshalAttr(xml.Name{Space: ns, Local: name}, fv)
+				if err != nil {
+					return err
+				}
+				if attr.Name.Local != "" {
+					start.Attr = append(start.Attr, attr)
+				}
+				if fv.Kind() == reflect.Struct && fv.Type().ConvertibleTo(qnameType) {
+					declareQName(&start, fv.Convert(qnameType).Interface().(QName))
+				}
+			case flags["chardata"]:
+				fv := fv
+				children = append(children, func() error {
+					return e.EncodeToken(xml.CharData(fmt.Sprint(fv.Interface())))
+				})
+			case flags["innerxml"]:
+				continue
+			default:
+				if name == "" {
+					name = field.Name
+				}
+				if isArray && flags["any"] {
+					name = "item"
+				}
+				elementName := xml.Name{Space: ns, Local: name}
+				fv := fv
+				children = append(children, func() error {
+					return encodeField(e, fv, elementName)
+				})
+			}
+		}
+		return nil
+	}
+	if err := walk(v); err != nil {
+		return err
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := child(); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeField writes a struct field, repeating the element for slices.
+func encodeField(e *xml.Encoder, v reflect.Value, name xml.Name) error {
+	path := strings.Split(name.Local, ">")
+	for _, parent := range path[:len(path)-1] {
+		if err := e.EncodeToken(xml.StartElement{Name: xml.Name{Space: name.Space, Local: parent}}); err != nil {
+			return err
+		}
+	}
+	start := xml.StartElement{Name: xml.Name{Space: name.Space, Local: path[len(path)-1]}}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(e, v.Index(i), start, true); err != nil {
+				return err
+			}
+		}
+	} else if err := encodeValue(e, v, start, true); err != nil {
+		return err
+	}
+
+	for i := len(path) - 2; i >= 0; i-- {
+		if err := e.EncodeToken(xml.EndElement{Name: xml.Name{Space: name.Space, Local: path[i]}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func marshalAttr(name xml.Name, v reflect.Value) (xml.Attr, error) {
+	if v.Type().Implements(marshalerAttrType) {
+		return v.Interface().(xml.MarshalerAttr).MarshalXMLAttr(name)
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return xml.Attr{}, nil
+		}
+		v = v.Elem()
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(marshalerAttrType) {
+		return v.Addr().Interface().(xml.MarshalerAttr).MarshalXMLAttr(name)
+	}
+	return xml.Attr{Name: name, Value: fmt.Sprint(v.Interface())}, nil
+}
+
+// parseXMLTag splits an encoding/xml struct tag into namespace, name and flags.
+func parseXMLTag(tag string) (string, string, map[string]bool) {
+	flags := make(map[string]bool)
+	tokens := strings.Split(tag, ",")
+	for _, flag := range tokens[1:] {
+		flags[flag] = true
+	}
+	name := tokens[0]
+	if i := strings.LastIndex(name, " "); i >= 0 {
+		return name[:i], name[i+1:], flags
+	}
+	return "", name, flags
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// xmlNode is a raw, prefix preserving, XML element tree used to resolve
+// multi-reference values.
+type xmlNode struct {
+	start    xml.StartElement
+	children []interface{} // *xmlNode or xml.Token
+}
+
+func (n *xmlNode) attr(local string) (string, bool) {
+	for _, a := range n.start.Attr {
+		if a.Name.Local == local && a.Name.Space != "xmlns" {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// resolveMultiRefs inlines the SOAP encoding multi-reference values of an
+// envelope: every accessor carrying an href (SOAP 1.1) or ref (SOAP 1.2)
+// attribute receives the attributes and content of the element with the
+// matching id, and the referenced serialization roots are removed from the
+// body, leaving the single body child expected by document decoding.
+func resolveMultiRefs(data []byte) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var (
+		root   *xmlNode
+		stack  []*xmlNode
+		prolog []xml.Token
+	)
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{start: t.Copy()}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unexpected end element %s", t.Name.Local)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			tok = xml.CopyToken(tok)
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, tok)
+			} else if root == nil {
+				prolog = append(prolog, tok)
+			}
+		}
+	}
+	if root == nil {
+		return data, nil
+	}
+
+	ids := make(map[string]*xmlNode)
+	var index func(n *xmlNode)
+	index = func(n *xmlNode) {
+		if id, ok := n.attr("id"); ok {
+			ids[id] = n
+		}
+		for _, c := range n.children {
+			if child, ok := c.(*xmlNode); ok {
+				index(child)
+			}
+		}
+	}
+	index(root)
+	if len(ids) == 0 {
+		return data, nil
+	}
+
+	referenced := make(map[string]bool)
+	var expand func(n *xmlNode, visiting map[*xmlNode]bool) *xmlNode
+	expand = func(n *xmlNode, visiting map[*xmlNode]bool) *xmlNode {
+		refAttr, id := "href", ""
+		if ref, ok := n.attr("href"); ok && strings.HasPrefix(ref, "#") {
+			id = ref[1:]
+		} else if ref, ok := n.attr("ref"); ok {
+			refAttr, id = "ref", ref
+		}
+
+		// unknown or cyclic references are left as is
+		if target, ok := ids[id]; ok && !visiting[target] {
+			referenced[id] = true
+			visiting[target] = true
+			resolved := expand(target, visiting)
+			delete(visiting, target)
+
+			out := &xmlNode{start: n.start.Copy(), children: resolved.children}
+			out.start.Attr = out.start.Attr[:0]
+			for _, a := range n.start.Attr {
+				if a.Name.Local != refAttr {
+					out.start.Attr = append(out.start.Attr, a)
+				}
+			}
+			for _, a := range resolved.start.Attr {
+				if a.Name.Local != "id" && a.Name.Local != "root" {
+					out.start.Attr = append(out.start.Attr, a)
+				}
+			}
+			return out
+		}
+
+		out := &xmlNode{start: n.start.Copy()}
+		for _, c := range n.children {
+			if child, ok := c.(*xmlNode); ok {
+				out.children = append(out.children, expand(child, visiting))
+			} else {
+				out.children = append(out.children, c)
+			}
+		}
+		return out
+	}
+	root = expand(root, make(map[*xmlNode]bool))
+
+	// drop the serialization roots which have been inlined
+	for _, c := range root.children {
+		body, ok := c.(*xmlNode)
+		if !ok || body.start.Name.Local != "Body" {
+			continue
+		}
+		children := body.children[:0]
+		for _, bc := range body.children {
+			if n, ok := bc.(*xmlNode); ok {
+				if id, ok := n.attr("id"); ok && referenced[id] {
+					continue
+				}
+			}
+			children = append(children, bc)
+		}
+		body.children = children
+	}
+
+	buf := new(bytes.Buffer)
+	for _, tok := range prolog {
+		writeToken(buf, tok)
+	}
+	writeNode(buf, root)
+	return buf.Bytes(), nil
+}
+
+func rawName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+func writeNode(buf *bytes.Buffer, n *xmlNode) {
+	buf.WriteString("<" + rawName(n.start.Name))
+	for _, a := range n.start.Attr {
+		buf.WriteString(" " + rawName(a.Name) + `="`)
+		xml.EscapeText(buf, []byte(a.Value))
+		buf.WriteString(`"`)
+	}
+	buf.WriteString(">")
+	for _, c := range n.children {
+		if child, ok := c.(*xmlNode); ok {
+			writeNode(buf, child)
+		} else {
+			writeToken(buf, c.(xml.Token))
+		}
+	}
+	buf.WriteString("</" + rawName(n.start.Name) + ">")
+}
+
+func writeToken(buf *bytes.Buffer, tok xml.Token) {
+	switch t := tok.(type) {
+	case xml.CharData:
+		xml.EscapeText(buf, t)
+	case xml.Comment:
+		buf.WriteString("<!--" + string(t) + "-->")
+	case xml.ProcInst:
+		buf.WriteString("<?" + t.Target + " " + string(t.Inst) + "?>")
+	case xml.Directive:
+		buf.WriteString("<!" + string(t) + ">")
+	}
+}
@@ -0,0 +1,61 @@
+package soap
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnumerationError is returned by the text methods of the strict enumerations
+// generated from a schema, when marshalling or unmarshalling a value which is
+// not enumerated by the schema.
+type EnumerationError struct {
+	Type  string
+	Value string
+}
+
+func (e *EnumerationError) Error() string {
+	return fmt.Sprintf("%q is not a value of the enumeration %s", e.Value, e.Type)
+}
+
+// FormatNumber returns the lexical representation of a value of a numeric
+// type, such as a generated enumeration of numbers.
+func FormatNumber(value interface{}) string {
+	text, _ := basicText(reflect.ValueOf(value))
+	return text
+}
+
+// ParseNumber parses the lexical representation of a number, surrounded by
+// optional whitespace, into the value of numeric type pointed to by value.
+func ParseNumber(text []byte, value interface{}) error {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("ParseNumber of non-pointer %T", value)
+	}
+	rv = rv.Elem()
+	s := strings.TrimSpace(string(text))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	default:
+		return fmt.Errorf("ParseNumber of non-numeric %T", value)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+	// qnamePrefix is the prefix declared by the elements of qualified names
+	qnamePrefix = "qn"
+)
+
+// QName is a type for representing xsd:QName and xsd:NOTATION in Golang, a
+// local name qualified by the namespace bound to its prefix where it occurs,
+// e.g. {http://schemas.xmlsoap.org/soap/envelope/}Server for "soap:Server".
+//
+// The prefix of an element is resolved against the namespaces in its scope
+// when decoded by a Client or by a decoder returned by NewDecoder, and against
+// the namespaces declared by the element itself otherwise. A name whose prefix
+// is not declared keeps its prefix in Local.
+//
+// An attribute has no scope of its own: the prefix of a QName attribute is
+// resolved, and its namespace declared, by the element owning it, see
+// DecodeQNameAttrs and DeclareQNameAttrs.
+type QName xml.Name
+
+var qnameType = reflect.TypeOf(QName{})
+
+// String returns the name in the {namespace}local notation, or the local
+// name if it has no namespace
+func (q QName) String() string {
+	if q.Space == "" {
+		return q.Local
+	}
+	return "{" + q.Space + "}" + q.Local
+}
+
+// MarshalXML implements xml.Marshaler on QName, declaring the prefix of the
+// namespace of the name on its element
+func (q QName) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if q.Space == "" || strings.Contains(q.Local, ":") {
+		return e.EncodeElement(q.Local, start)
+	}
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + qnamePrefix}, Value: q.Space})
+	return e.EncodeElement(qnamePrefix+":"+q.Local, start)
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr on QName. An attribute cannot
+// declare a prefix: the prefix of the namespace of the name, derived from the
+// namespace, is declared on the element owning the attribute by
+// DeclareQNameAttrs. An unset name is omitted.
+func (q QName) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	switch {
+	case q.Local == "":
+		return xml.Attr{}, nil
+	case q.Space == "" || strings.Contains(q.Local, ":"):
+		return xml.Attr{Name: name, Value: q.Local}, nil
+	case q.Space == xmlNamespace:
+		return xml.Attr{Name: name, Value: "xml:" + q.Local}, nil
+	}
+	return xml.Attr{Name: name, Value: attrPrefix(q.Space) + ":" + q.Local}, nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler on QName
+func (q *QName) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	lookup := namespaceLookup(d, start)
+	var content string
+	if err := d.DecodeElement(&content, &start); err != nil {
+		return err
+	}
+	*q = resolveQName(strings.TrimSpace(content), lookup)
+	return nil
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr on QName. Only names in the
+// XML namespace are resolved, the others keeping their prefix in Local until
+// resolved by DecodeQNameAttrs against the namespaces in the scope of the
+// element owning the attribute.
+func (q *QName) UnmarshalXMLAttr(attr xml.Attr) error {
+	*q = resolveQName(strings.TrimSpace(attr.Value), func(string) (string, bool) { return "", false })
+	return nil
+}
+
+// attrPrefix returns the prefix of the namespace of QName attributes, which
+// is derived from the namespace so that attributes and the element owning
+// them agree on it.
+func attrPrefix(space string) string {
+	h := fnv.New32a()
+	h.Write([]byte(space))
+	return fmt.Sprintf("%s%08x", qnamePrefix, h.Sum32())
+}
+
+// DeclareQNameAttrs declares on the element start the prefixes of the
+// namespaces of the QName attributes of v, the struct marshalled as the
+// element, including those of the structs it embeds. It is called by the
+// MarshalXML methods of the generated types with QName attributes.
+func DeclareQNameAttrs(start *xml.StartElement, v interface{}) {
+	qnameAttrs(reflect.ValueOf(v), func(field reflect.Value) {
+		declareQName(start, field.Convert(qnameType).Interface().(QName))
+	})
+}
+
+// declareQName declares on the element start the prefix of the namespace of
+// a QName attribute, unless declared already.
+func declareQName(start *xml.StartElement, q QName) {
+	if q.Space == "" || q.Space == xmlNamespace || strings.Contains(q.Local, ":") {
+		return
+	}
+	name := xml.Name{Local: "xmlns:" + attrPrefix(q.Space)}
+	for _, attr := range start.Attr {
+		if attr.Name == name {
+			return
+		}
+	}
+	start.Attr = append(start.Attr, xml.Attr{Name: name, Value: q.Space})
+}
+
+// DecodeQNameAttrs decodes the element start into v like DecodeElement, then
+// resolves the QName attributes of v, including those of the structs it
+// embeds, against the namespaces in the scope of the element. It is called by
+// the UnmarshalXML methods of the generated types with QName attributes.
+func DecodeQNameAttrs(d *xml.Decoder, v interface{}, start *xml.StartElement) error {
+	lookup := namespaceLookup(d, *start)
+	if err := d.DecodeElement(v, start); err != nil {
+		return err
+	}
+	qnameAttrs(reflect.ValueOf(v), func(field reflect.Value) {
+		q := field.Convert(qnameType).Interface().(QName)
+		if q.Space == "" && q.Local != "" {
+			field.Set(reflect.ValueOf(resolveQName(q.Local, lookup)).Convert(field.Type()))
+		}
+	})
+	return nil
+}
+
+// qnameAttrs calls f with the QName attributes of the struct v, and of the
+// structs it embeds.
+func qnameAttrs(v reflect.Value, f func(field reflect.Value)) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag := field.Tag.Get("xml")
+		if field.Anonymous && tag == "" {
+			qnameAttrs(v.Field(i), f)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if _, _, flags := parseXMLTag(tag); !flags["attr"] {
+			continue
+		}
+		fv := v.Field(i)
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && fv.Type().ConvertibleTo(qnameType) {
+			f(fv)
+		}
+	}
+}
+
+// resolveQName resolves a qualified name with the namespace bound to its
+// prefix by lookup. Unprefixed names are in the default namespace.
+func resolveQName(name string, lookup func(prefix string) (string, bool)) QName {
+	prefix, local := "", name
+	if i := strings.Index(name, ":"); i >= 0 {
+		prefix, local = name[:i], name[i+1:]
+	}
+	if prefix == "xml" {
+		return QName{Space: xmlNamespace, Local: local}
+	}
+	if space, ok := lookup(prefix); ok {
+		return QName{Space: space, Local: local}
+	}
+	return QName{Local: name}
+}
+
+// namespaceLookup returns the lookup of the namespaces in the scope of an
+// element being decoded, which are those tracked by the decoder when it
+// decodes an envelope, or else those declared by the element.
+func namespaceLookup(d *xml.Decoder, start xml.StartElement) func(prefix string) (string, bool) {
+	scopes := [][]xml.Attr{start.Attr}
+	if s, ok := decoderScopes.Load(d); ok {
+		// the declarations of the element and of its ancestors, which are left
+		// as they are when the element is decoded, as it has no child element
+		scopes = s.(*namespaceScopes).scopes
+	}
+	return func(prefix string) (string, bool) {
+		for i := len(scopes) - 1; i >= 0; i-- {
+			for _, attr := range scopes[i] {
+				if prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns" ||
+					prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix {
+					return attr.Value, true
+				}
+			}
+		}
+		return "", false
+	}
+}
+
+// decoderScopes are the namespace scopes of the decoders of envelopes while
+// they decode them.
+var decoderScopes sync.Map
+
+// namespaceScopes reads the tokens of a decoder, keeping the namespace
+// declarations of the elements in scope.
+type namespaceScopes struct {
+	d      *xml.Decoder
+	scopes [][]xml.Attr
+}
+
+// Token implements xml.TokenReader on namespaceScopes. The tokens are read
+// raw, their names being translated by the decoder reading them.
+func (s *namespaceScopes) Token() (xml.Token, error) {
+	t, err := s.d.RawToken()
+	switch t := t.(type) {
+	case xml.StartElement:
+		var declarations []xml.Attr
+		for _, attr := range t.Attr {
+			if attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+				declarations = append(declarations, attr)
+			}
+		}
+		s.scopes = append(s.scopes, declarations)
+	case xml.EndElement:
+		if len(s.scopes) > 0 {
+			s.scopes = s.scopes[:len(s.scopes)-1]
+		}
+	}
+	return t, err
+}
+
+// namespaceDecoder is a SOAPDecoder resolving the QNames it decodes against
+// the namespaces in their scope.
+type namespaceDecoder struct {
+	*xml.Decoder
+	scopes *namespaceScopes
+}
+
+// NewDecoder returns a decoder of the SOAP envelopes read from r resolving
+// the QNames it decodes against the namespaces in their scope, as a Client
+// does. The generated servers decode their requests with it.
+func NewDecoder(r io.Reader) SOAPDecoder {
+	return newNamespaceDecoder(r)
+}
+
+func newNamespaceDecoder(r io.Reader) *namespaceDecoder {
+	s := &namespaceScopes{d: xml.NewDecoder(r)}
+	return &namespaceDecoder{Decoder: xml.NewTokenDecoder(s), scopes: s}
+}
+
+// Decode implements SOAPDecoder on namespaceDecoder
+func (d *namespaceDecoder) Decode(v interface{}) error {
+	decoderScopes.Store(d.Decoder, d.scopes)
+	defer decoderScopes.Delete(d.Decoder)
+	return d.Decoder.Decode(v)
+}
@@ -0,0 +1,18 @@
+package soap
+
+import "encoding/xml"
+
+// rpcPrefix is the prefix declared by the wrappers of RPC style operations
+const rpcPrefix = "rpc"
+
+// PrefixRPCWrapper qualifies the wrapper element of an RPC style operation by
+// a prefix bound to its namespace. encoding/xml qualifies elements by a default
+// namespace instead, which the part accessors would inherit while they must be
+// unqualified (WS-I Basic Profile R2735).
+func PrefixRPCWrapper(start *xml.StartElement) {
+	if start.Name.Space == "" {
+		return
+	}
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + rpcPrefix}, Value: start.Name.Space})
+	start.Name = xml.Name{Local: rpcPrefix + ":" + start.Name.Local}
+}
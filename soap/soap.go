@@ -26,7 +26,6 @@ type SoapResponseBodyInterface interface {
 	SetContent(interface{})
 }
 
-
 type SOAPEncoder interface {
 	Encode(v interface{}) error
 	Flush() error
@@ -67,7 +66,6 @@ func (s *SOAPEnvelopeResponse) GetAttachments() []MIMEMultipartAttachment {
 	return s.Attachments
 }
 
-
 type SOAPEnvelope struct {
 	XMLName xml.Name `xml:"soap:Envelope"`
 	XmlNS   string   `xml:"xmlns:soap,attr"`
@@ -76,7 +74,6 @@ type SOAPEnvelope struct {
 	Body   SOAPBody
 }
 
-
 type SOAPHeader struct {
 	XMLName xml.Name `xml:"soap:Header"`
 
@@ -127,16 +124,29 @@ func (b *SOAPBodyResponse) UnmarshalXML(d *xml.Decoder, _ xml.StartElement) erro
 		return xml.UnmarshalError("Content must be a pointer to a struct")
 	}
 
+	faultName := xml.Name{Space: XmlNsSoapEnv, Local: "Fault"}
+	faultOccurred, err := decodeBody(d, b.Content, faultName, b.Fault)
+	if faultOccurred {
+		b.Content = nil
+		b.faultOccurred = true
+	}
+	return err
+}
+
+// decodeBody decodes the single element found inside a SOAP body either into
+// content or, when it is named faultName, into fault.
+func decodeBody(d *xml.Decoder, content interface{}, faultName xml.Name, fault interface{}) (bool, error) {
 	var (
-		token    xml.Token
-		err      error
-		consumed bool
+		token         xml.Token
+		err           error
+		consumed      bool
+		faultOccurred bool
 	)
 
 Loop:
 	for {
 		if token, err = d.Token(); err != nil {
-			return err
+			return faultOccurred, err
 		}
 
 		if token == nil {
@@ -146,20 +156,18 @@ Loop:
 		switch se := token.(type) {
 		case xml.StartElement:
 			if consumed {
-				return xml.UnmarshalError("Found multiple elements inside SOAP body; not wrapped-document/literal WS-I compliant")
-			} else if se.Name.Space == "http://schemas.xmlsoap.org/soap/envelope/" && se.Name.Local == "Fault" {
-				b.Content = nil
-
-				b.faultOccurred = true
-				err = d.DecodeElement(b.Fault, &se)
+				return faultOccurred, xml.UnmarshalError("Found multiple elements inside SOAP body; not wrapped-document/literal WS-I compliant")
+			} else if se.Name == faultName {
+				faultOccurred = true
+				err = d.DecodeElement(fault, &se)
 				if err != nil {
-					return err
+					return faultOccurred, err
 				}
 
 				consumed = true
 			} else {
-				if err = d.DecodeElement(b.Content, &se); err != nil {
-					return err
+				if err = d.DecodeElement(content, &se); err != nil {
+					return faultOccurred, err
 				}
 
 				consumed = true
@@ -169,7 +177,7 @@ Loop:
 		}
 	}
 
-	return nil
+	return faultOccurred, nil
 }
 
 func (b *SOAPBody) ErrorFromFault() error {
@@ -205,7 +213,7 @@ type FaultError interface {
 type SOAPFault struct {
 	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault"`
 
-	Code   string     `xml:"faultcode,omitempty"`
+	Code   QName      `xml:"faultcode,omitempty"`
 	String string     `xml:"faultstring,omitempty"`
 	Actor  string     `xml:"faultactor,omitempty"`
 	Detail FaultError `xml:"detail,omitempty"`
@@ -298,6 +306,9 @@ type options struct {
 	httpHeaders      map[string]string
 	mtom             bool
 	mma              bool
+	version          SOAPVersion
+	encoded          bool
+	validate         bool
 }
 
 var defaultOptions = options{
@@ -379,6 +390,34 @@ func WithMIMEMultipartAttachments() Option {
 	}
 }
 
+// WithSOAPVersion is an Option to set the SOAP protocol version used for
+// envelopes, HTTP headers and faults. SOAP 1.1 is used by default.
+func WithSOAPVersion(version SOAPVersion) Option {
+	return func(o *options) {
+		o.version = version
+	}
+}
+
+// WithSOAPEncoding is an Option to serialize requests following the SOAP
+// Section 5 encoding rules (use="encoded"), as expected by RPC/encoded services.
+// Values are sent with their xsi:type and multi-reference values of responses
+// are resolved before decoding.
+func WithSOAPEncoding() Option {
+	return func(o *options) {
+		o.encoded = true
+	}
+}
+
+// WithValidation is an Option to validate requests before sending them and
+// responses after decoding them, when their types implement Validator. The
+// violations are returned as a *ValidationError wrapped in the error of the
+// call.
+func WithValidation() Option {
+	return func(o *options) {
+		o.validate = true
+	}
+}
+
 // Client is soap client
 type Client struct {
 	url         string
@@ -423,6 +462,16 @@ func (s *Client) SetHeaders(headers ...interface{}) {
 	s.headers = headers
 }
 
+// SetSOAPVersion sets the SOAP protocol version used by subsequent calls.
+func (s *Client) SetSOAPVersion(version SOAPVersion) {
+	s.opts.version = version
+}
+
+// SetSOAPEncoding enables or disables the SOAP encoding for subsequent calls.
+func (s *Client) SetSOAPEncoding(encoded bool) {
+	s.opts.encoded = encoded
+}
+
 // Get all currently available http headers from  client
 // Use case: For setting authentication header
 func (s *Client) GetHttpClientHeaders() map[string]string {
@@ -439,6 +488,23 @@ func (s *Client) CallContext(ctx context.Context, soapAction string, request, re
 	return s.call(ctx, soapAction, nil, request, response, nil, nil, nil)
 }
 
+// CallContextWithOptions performs HTTP POST request with a context, applying
+// the given options to this call only, on top of those of the client. Services
+// sharing a client use it to speak the SOAP version and encoding of their
+// binding without changing the client of the others.
+func (s *Client) CallContextWithOptions(ctx context.Context, soapAction string, request, response interface{}, opt ...Option) error {
+	if len(opt) == 0 {
+		return s.call(ctx, soapAction, nil, request, response, nil, nil, nil)
+	}
+	opts := *s.opts
+	for _, o := range opt {
+		o(&opts)
+	}
+	c := *s
+	c.opts = &opts
+	return c.call(ctx, soapAction, nil, request, response, nil, nil, nil)
+}
+
 // Call performs HTTP POST request.
 // Note that if the server returns a status code >= 400, a HTTPError will be returned
 func (s *Client) Call(soapAction string, request, response interface{}) error {
@@ -474,12 +540,21 @@ func (s *Client) CallWithEnvelope(ctx context.Context, soapAction string, envelo
 
 func (s *Client) call(ctx context.Context, soapAction string, requestEnvelope, request, response interface{}, responseEnvelope SOAPResponseEnvelopeInterface, faultDetail FaultError,
 	retAttachments *[]MIMEMultipartAttachment) error {
+	if s.opts.validate && request != nil {
+		if err := ValidateContent(request); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+	}
+
 	// SOAP envelope capable of namespace prefixes
 	if requestEnvelope == nil {
 		// SOAP envelope capable of namespace prefixes
 		soapEnvelope := SOAPEnvelope{
 			XmlNS: XmlNsSoapEnv,
 		}
+		if s.opts.version == SOAP12 {
+			soapEnvelope.XmlNS = XmlNsSoap12Env
+		}
 		soapEnvelope.Body.Content = request
 		if s.headers != nil && len(s.headers) > 0 {
 			soapEnvelope.Header = &SOAPHeader{
@@ -487,9 +562,21 @@ func (s *Client) call(ctx context.Context, soapAction string, requestEnvelope, r
 			}
 		}
 		soapEnvelope.Body.Content = request
+		if s.opts.encoded {
+			encodingStyle := XmlNsSoapEnc
+			if s.opts.version == SOAP12 {
+				encodingStyle = XmlNsSoap12Enc
+			}
+			soapEnvelope.Body.Content = encodedContent{
+				content:       request,
+				encodingStyle: encodingStyle,
+			}
+		} else if _, ok := request.(xml.Marshaler); ok {
+			soapEnvelope.Body.Content = literalContent{content: request}
+		}
 		requestEnvelope = soapEnvelope
- 	}
-	
+	}
+
 	buffer := new(bytes.Buffer)
 	var encoder SOAPEncoder
 	if s.opts.mtom && s.opts.mma {
@@ -520,14 +607,26 @@ func (s *Client) call(ctx context.Context, soapAction string, requestEnvelope, r
 
 	req = req.WithContext(ctx)
 
+	var contentType string
 	if s.opts.mtom {
-		req.Header.Add("Content-Type", fmt.Sprintf(mtomContentType, encoder.(*mtomEncoder).Boundary()))
+		contentType = fmt.Sprintf(mtomContentType, encoder.(*mtomEncoder).Boundary())
 	} else if s.opts.mma {
-		req.Header.Add("Content-Type", fmt.Sprintf(mmaContentType, encoder.(*mmaEncoder).Boundary()))
+		contentType = fmt.Sprintf(mmaContentType, encoder.(*mmaEncoder).Boundary())
+	} else if s.opts.version == SOAP12 {
+		contentType = soap12ContentType
+	} else {
+		contentType = "text/xml; charset=\"utf-8\""
+	}
+	if s.opts.version == SOAP12 {
+		// SOAP 1.2 carries the action as a media type parameter instead of
+		// the SOAPAction header.
+		if soapAction != "" {
+			contentType += fmt.Sprintf(soap12ActionParam, soapAction)
+		}
 	} else {
-		req.Header.Add("Content-Type", "text/xml; charset=\"utf-8\"")
+		req.Header.Add("SOAPAction", soapAction)
 	}
-	req.Header.Add("SOAPAction", soapAction)
+	req.Header.Add("Content-Type", contentType)
 	req.Header.Set("User-Agent", "gowsdl/0.1")
 	if s.opts.httpHeaders != nil {
 		for k, v := range s.opts.httpHeaders {
@@ -555,7 +654,9 @@ func (s *Client) call(ctx context.Context, soapAction string, requestEnvelope, r
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode >= 400 && res.StatusCode != 500 {
+	// SOAP 1.2 sends the faults of the sender with HTTP 400
+	faultStatus := res.StatusCode == 500 || res.StatusCode == 400 && s.opts.version == SOAP12
+	if res.StatusCode >= 400 && !faultStatus {
 		body, _ := io.ReadAll(res.Body)
 		return &HTTPError{
 			StatusCode:   res.StatusCode,
@@ -573,13 +674,24 @@ func (s *Client) call(ctx context.Context, soapAction string, requestEnvelope, r
 		// 		Detail: faultDetail,
 		// 	},
 		// }
-		responseEnvelope = &SOAPEnvelopeResponse{
-			Body: &SOAPBodyResponse{
-				Content: response,
-				Fault: &SOAPFault{
-					Detail: faultDetail,
+		if s.opts.version == SOAP12 {
+			responseEnvelope = &SOAP12EnvelopeResponse{
+				Body: &SOAP12BodyResponse{
+					Content: response,
+					Fault: &SOAP12Fault{
+						Detail: faultDetail,
+					},
 				},
-			},
+			}
+		} else {
+			responseEnvelope = &SOAPEnvelopeResponse{
+				Body: &SOAPBodyResponse{
+					Content: response,
+					Fault: &SOAPFault{
+						Detail: faultDetail,
+					},
+				},
+			}
 		}
 	}
 
@@ -589,7 +701,7 @@ func (s *Client) call(ctx context.Context, soapAction string, requestEnvelope, r
 	}
 
 	var mmaBoundary string
-	if s.opts.mma{
+	if s.opts.mma {
 		mmaBoundary, err = getMmaHeader(res.Header.Get("Content-Type"))
 		if err != nil {
 			return err
@@ -600,7 +712,7 @@ func (s *Client) call(ctx context.Context, soapAction string, requestEnvelope, r
 	// to return the right HTTPError/ResponseBody
 	body := res.Body
 	var cachedErrorBody []byte
-	if res.StatusCode == 500 {
+	if faultStatus {
 		cachedErrorBody, err = io.ReadAll(res.Body)
 		if err != nil {
 			return err
@@ -613,13 +725,22 @@ func (s *Client) call(ctx context.Context, soapAction string, requestEnvelope, r
 		dec = newMtomDecoder(body, mtomBoundary)
 	} else if mmaBoundary != "" {
 		dec = newMmaDecoder(body, mmaBoundary)
+	} else if s.opts.encoded {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		if data, err = resolveMultiRefs(data); err != nil {
+			return err
+		}
+		dec = newNamespaceDecoder(bytes.NewReader(data))
 	} else {
-		dec = xml.NewDecoder(body)
+		dec = newNamespaceDecoder(body)
 	}
 
 	if err := dec.Decode(responseEnvelope); err != nil {
 		// the response doesn't contain a Fault/SOAPBody, so we return the original body
-		if res.StatusCode == 500 {
+		if faultStatus {
 			return &HTTPError{
 				StatusCode:   res.StatusCode,
 				ResponseBody: cachedErrorBody,
@@ -631,5 +752,19 @@ func (s *Client) call(ctx context.Context, soapAction string, requestEnvelope, r
 	if responseEnvelope.GetAttachments() != nil {
 		*retAttachments = responseEnvelope.GetAttachments()
 	}
-	return responseEnvelope.GetBody().ErrorFromFault()
+	if err := responseEnvelope.GetBody().ErrorFromFault(); err != nil {
+		return err
+	}
+	if res.StatusCode == 400 {
+		return &HTTPError{
+			StatusCode:   res.StatusCode,
+			ResponseBody: cachedErrorBody,
+		}
+	}
+	if s.opts.validate && response != nil {
+		if err := ValidateContent(response); err != nil {
+			return fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,159 @@
+package soap
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// SOAPVersion identifies the version of the SOAP protocol spoken by a Client.
+type SOAPVersion int
+
+const (
+	// SOAP11 selects SOAP 1.1 envelopes, the text/xml content type and the
+	// SOAPAction HTTP header. It is the default.
+	SOAP11 SOAPVersion = iota
+	// SOAP12 selects SOAP 1.2 envelopes and the application/soap+xml content
+	// type carrying the action as a media type parameter.
+	SOAP12
+)
+
+const (
+	XmlNsSoap12Env    string = "http://www.w3.org/2003/05/soap-envelope"
+	soap12ContentType string = `application/soap+xml; charset="utf-8"`
+	soap12ActionParam string = `; action="%s"`
+)
+
+// SOAP12EnvelopeResponse is the SOAP 1.2 counterpart of SOAPEnvelopeResponse.
+type SOAP12EnvelopeResponse struct {
+	XMLName     xml.Name `xml:"http://www.w3.org/2003/05/soap-envelope Envelope"`
+	Header      *SOAPHeaderResponse
+	Body        SoapResponseBodyInterface
+	Attachments []MIMEMultipartAttachment `xml:"attachments,omitempty"`
+}
+
+func (s *SOAP12EnvelopeResponse) GetBody() SoapResponseBodyInterface {
+	return s.Body
+}
+
+func (s *SOAP12EnvelopeResponse) GetHeader() interface{} {
+	return s.Header
+}
+
+func (s *SOAP12EnvelopeResponse) SetBody(body SoapResponseBodyInterface) {
+	s.Body = body
+}
+
+func (s *SOAP12EnvelopeResponse) SetHeader(header interface{}) {
+	s.Header = header.(*SOAPHeaderResponse)
+}
+
+func (s *SOAP12EnvelopeResponse) SetXMLName(xmlName xml.Name) {
+	s.XMLName = xmlName
+}
+
+func (s *SOAP12EnvelopeResponse) GetAttachments() []MIMEMultipartAttachment {
+	return s.Attachments
+}
+
+// SOAP12BodyResponse is the SOAP 1.2 counterpart of SOAPBodyResponse.
+type SOAP12BodyResponse struct {
+	XMLName xml.Name `xml:"Body"`
+
+	Content interface{} `xml:",omitempty"`
+
+	// faultOccurred indicates whether the XML body included a fault;
+	// we cannot simply store SOAP12Fault as a pointer to indicate this, since
+	// fault is initialized to non-nil with user-provided detail type.
+	faultOccurred bool
+	Fault         *SOAP12Fault `xml:",omitempty"`
+}
+
+func (b *SOAP12BodyResponse) SetContent(content interface{}) {
+	b.Content = content
+}
+
+// UnmarshalXML unmarshals SOAP12BodyResponse xml
+func (b *SOAP12BodyResponse) UnmarshalXML(d *xml.Decoder, _ xml.StartElement) error {
+	if b.Content == nil {
+		return xml.UnmarshalError("Content must be a pointer to a struct")
+	}
+
+	faultName := xml.Name{Space: XmlNsSoap12Env, Local: "Fault"}
+	faultOccurred, err := decodeBody(d, b.Content, faultName, b.Fault)
+	if faultOccurred {
+		b.Content = nil
+		b.faultOccurred = true
+	}
+	return err
+}
+
+func (b *SOAP12BodyResponse) ErrorFromFault() error {
+	if b.faultOccurred {
+		return b.Fault
+	}
+	b.Fault = nil
+	return nil
+}
+
+// SOAP12Fault represents a SOAP 1.2 fault. It is returned as error by the
+// Client when the server answers a SOAP 1.2 request with a fault.
+type SOAP12Fault struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2003/05/soap-envelope Fault"`
+
+	Code   SOAP12FaultCode   `xml:"http://www.w3.org/2003/05/soap-envelope Code"`
+	Reason SOAP12FaultReason `xml:"http://www.w3.org/2003/05/soap-envelope Reason"`
+	Node   string            `xml:"http://www.w3.org/2003/05/soap-envelope Node,omitempty"`
+	Role   string            `xml:"http://www.w3.org/2003/05/soap-envelope Role,omitempty"`
+	Detail FaultError        `xml:"http://www.w3.org/2003/05/soap-envelope Detail,omitempty"`
+}
+
+// SOAP12FaultCode holds the mandatory fault code value and the optional chain
+// of application defined subcodes.
+type SOAP12FaultCode struct {
+	Value   string           `xml:"http://www.w3.org/2003/05/soap-envelope Value"`
+	Subcode *SOAP12FaultCode `xml:"http://www.w3.org/2003/05/soap-envelope Subcode,omitempty"`
+}
+
+// Subcodes returns the values of the subcode chain, outermost first.
+func (c SOAP12FaultCode) Subcodes() []string {
+	var codes []string
+	for sc := c.Subcode; sc != nil; sc = sc.Subcode {
+		codes = append(codes, sc.Value)
+	}
+	return codes
+}
+
+// SOAP12FaultReason holds the human readable fault explanations, one per language.
+type SOAP12FaultReason struct {
+	Text []SOAP12FaultText `xml:"http://www.w3.org/2003/05/soap-envelope Text"`
+}
+
+// SOAP12FaultText is a fault explanation in the language given by Lang.
+type SOAP12FaultText struct {
+	Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+// String returns the explanation in the given language, falling back to the
+// first one when there is no match.
+func (r SOAP12FaultReason) String(lang string) string {
+	for _, t := range r.Text {
+		if strings.EqualFold(t.Lang, lang) {
+			return t.Value
+		}
+	}
+	if len(r.Text) > 0 {
+		return r.Text[0].Value
+	}
+	return ""
+}
+
+func (f *SOAP12Fault) Error() string {
+	if f.Detail != nil && f.Detail.HasData() {
+		return f.Detail.ErrorString()
+	}
+	if reason := f.Reason.String("en"); reason != "" {
+		return reason
+	}
+	return f.Code.Value
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -204,6 +206,20 @@ func TestClient_MTOM(t *testing.T) {
 	}
 }
 
+func TestBinary(t *testing.T) {
+	type document struct {
+		Scan *Binary `xml:"scan"`
+	}
+	out, err := xml.Marshal(document{Scan: NewBinary([]byte("scanned"))})
+	assert.NoError(t, err)
+	assert.Equal(t, "<document><scan>c2Nhbm5lZA==</scan></document>", string(out))
+
+	var in document
+	assert.NoError(t, xml.Unmarshal([]byte("<document><scan>c2Nh\n bm5lZA==</scan></document>"), &in))
+	assert.Equal(t, "scanned", string(in.Scan.Bytes()))
+	assert.Error(t, xml.Unmarshal([]byte("<document><scan>#</scan></document>"), &in))
+}
+
 type SimpleNode struct {
 	Detail string      `xml:"Detail,omitempty"`
 	Num    float64     `xml:"Num,omitempty"`
@@ -775,6 +791,979 @@ func TestXsdTime(t *testing.T) {
 	}
 }
 
+func TestClient_SOAP12(t *testing.T) {
+	var gotHeaders http.Header
+	var gotEnvelope xml.Name
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header
+		var envelope struct {
+			XMLName xml.Name
+		}
+		xml.NewDecoder(r.Body).Decode(&envelope)
+		gotEnvelope = envelope.XMLName
+		rsp := `<?xml version="1.0" encoding="utf-8"?>
+		<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope">
+			<env:Body>
+				<PingResponse xmlns="http://example.com/service.xsd">
+					<PingResult>
+						<Message>Pong hi</Message>
+					</PingResult>
+				</PingResponse>
+			</env:Body>
+		</env:Envelope>`
+		w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+		w.Write([]byte(rsp))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, WithSOAPVersion(SOAP12))
+	req := &Ping{Request: &PingRequest{Message: "Hi"}}
+	reply := &PingResponse{}
+	if err := client.Call("GetData", req, reply); err != nil {
+		t.Fatalf("couln't call service: %v", err)
+	}
+
+	assert.Equal(t, xml.Name{Space: XmlNsSoap12Env, Local: "Envelope"}, gotEnvelope)
+	assert.Equal(t, `application/soap+xml; charset="utf-8"; action="GetData"`, gotHeaders.Get("Content-Type"))
+	assert.Empty(t, gotHeaders.Get("SOAPAction"))
+	assert.Equal(t, "Pong hi", reply.PingResult.Message)
+}
+
+func TestClient_CallContextWithOptions(t *testing.T) {
+	var gotEnvelopes []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var envelope struct {
+			XMLName xml.Name
+		}
+		xml.NewDecoder(r.Body).Decode(&envelope)
+		gotEnvelopes = append(gotEnvelopes, envelope.XMLName.Space)
+		w.Write([]byte(`<Envelope xmlns="` + envelope.XMLName.Space + `"><Body>
+			<PingResponse xmlns="http://example.com/service.xsd"/>
+		</Body></Envelope>`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	reply := &PingResponse{}
+	assert.NoError(t, client.CallContextWithOptions(context.TODO(), "GetData", &Ping{}, reply, WithSOAPVersion(SOAP12)))
+	// the options of a call don't change the client
+	assert.NoError(t, client.CallContext(context.TODO(), "GetData", &Ping{}, reply))
+	assert.Equal(t, []string{XmlNsSoap12Env, XmlNsSoapEnv}, gotEnvelopes)
+}
+
+// nodeDetail is a fault detail which has data once a SimpleNode is decoded
+type nodeDetail struct {
+	Node *SimpleNode `xml:"SimpleNode"`
+}
+
+func (d *nodeDetail) HasData() bool {
+	return d.Node != nil
+}
+
+func (d *nodeDetail) ErrorString() string {
+	return d.Node.ErrorString()
+}
+
+func Test_Client_SOAP12Fault(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rsp := `<?xml version="1.0" encoding="utf-8"?>
+<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:m="http://example.com/timeouts">
+	<env:Body>
+		<env:Fault>
+			<env:Code>
+				<env:Value>env:Sender</env:Value>
+				<env:Subcode>
+					<env:Value>m:MessageTimeout</env:Value>
+				</env:Subcode>
+			</env:Code>
+			<env:Reason>
+				<env:Text xml:lang="de">Zeitüberschreitung</env:Text>
+				<env:Text xml:lang="en">Sender Timeout</env:Text>
+			</env:Reason>
+			<env:Detail>
+				<SimpleNode><Detail>detail message</Detail><Num>7.7</Num></SimpleNode>
+			</env:Detail>
+		</env:Fault>
+	</env:Body>
+</env:Envelope>`
+		// SOAP 1.2 sends the faults of the sender with HTTP 400
+		w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(rsp))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	client.SetSOAPVersion(SOAP12)
+	fault := Wrapper{Item: &SimpleNode{}}
+	err := client.CallWithFaultDetail("GetData", &Ping{}, &PingResponse{}, &fault)
+	if err == nil {
+		t.Fatalf("call should have failed, but succeeded.")
+	}
+	soapFault, ok := err.(*SOAP12Fault)
+	if !ok {
+		t.Fatalf("Expected a SOAP12Fault.  Received: %T %v", err, err)
+	}
+	assert.EqualError(t, err, "Sender Timeout")
+	assert.Equal(t, "env:Sender", soapFault.Code.Value)
+	assert.Equal(t, []string{"m:MessageTimeout"}, soapFault.Code.Subcodes())
+	assert.Equal(t, "Zeitüberschreitung", soapFault.Reason.String("de"))
+	assert.EqualValues(t, &SimpleNode{Detail: "detail message", Num: 7.7}, fault.Item)
+
+	// a detail with data takes precedence over the reason
+	err = client.CallWithFaultDetail("GetData", &Ping{}, &PingResponse{}, &nodeDetail{})
+	if _, ok := err.(*SOAP12Fault); !ok {
+		t.Fatalf("Expected a SOAP12Fault.  Received: %T %v", err, err)
+	}
+	assert.EqualError(t, err, "7.70: detail message")
+
+	// SOAP 1.1 does not send faults with HTTP 400
+	client = NewClient(ts.URL)
+	err = client.Call("GetData", &Ping{}, &PingResponse{})
+	httpErr, ok := err.(*HTTPError)
+	if !ok {
+		t.Fatalf("Expected an HTTPError.  Received: %T %v", err, err)
+	}
+	assert.Equal(t, http.StatusBadRequest, httpErr.StatusCode)
+}
+
+type EncodedQuote struct {
+	Symbol string  `xml:"symbol,omitempty"`
+	Price  float64 `xml:"price,omitempty"`
+}
+
+func (t *EncodedQuote) XSIType() xml.Name {
+	return xml.Name{Space: "urn:quotes", Local: "Quote"}
+}
+
+type EncodedArrayOfString struct {
+	Items []string `xml:",any"`
+}
+
+func (t *EncodedArrayOfString) XSIType() xml.Name {
+	return xml.Name{Space: XmlNsSoapEnc, Local: "Array"}
+}
+
+type EncodedGetQuotes struct {
+	XMLName xml.Name `xml:"urn:quotes:rpc getQuotes"`
+
+	Symbols *EncodedArrayOfString `xml:"symbols,omitempty"`
+	Limit   int32                 `xml:"limit,omitempty"`
+	Market  QName                 `xml:"market,attr,omitempty"`
+}
+
+type EncodedGetQuotesResponse struct {
+	XMLName xml.Name `xml:"urn:quotes:rpc getQuotesResponse"`
+
+	First  *EncodedQuote `xml:"first,omitempty"`
+	Second *EncodedQuote `xml:"second,omitempty"`
+}
+
+func TestClient_SOAPEncoding(t *testing.T) {
+	var gotBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		gotBody = string(body)
+		rsp := `<?xml version="1.0" encoding="utf-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/">
+	<soapenv:Body>
+		<ns1:getQuotesResponse soapenv:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/" xmlns:ns1="urn:quotes:rpc">
+			<first href="#id0"/>
+			<second href="#id0"/>
+		</ns1:getQuotesResponse>
+		<multiRef id="id0" soapenc:root="0" xsi:type="ns2:Quote" xmlns:ns2="urn:quotes">
+			<symbol xsi:type="xsd:string">ACME</symbol>
+			<price xsi:type="xsd:double">12.5</price>
+		</multiRef>
+	</soapenv:Body>
+</soapenv:Envelope>`
+		w.Write([]byte(rsp))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, WithSOAPEncoding())
+	req := &EncodedGetQuotes{
+		Symbols: &EncodedArrayOfString{Items: []string{"ACME", "INITECH"}},
+		Limit:   2,
+		Market:  QName{Space: "urn:markets", Local: "NYSE"},
+	}
+	reply := &EncodedGetQuotesResponse{}
+	if err := client.Call("getQuotes", req, reply); err != nil {
+		t.Fatalf("couln't call service: %v", err)
+	}
+
+	for _, want := range []string{
+		// the accessors are unqualified
+		`<rpc:getQuotes xmlns:rpc="urn:quotes:rpc" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/" soap:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"`,
+		`market="` + attrPrefix("urn:markets") + `:NYSE" xmlns:` + attrPrefix("urn:markets") + `="urn:markets">`,
+		`<symbols xsi:type="soapenc:Array" soapenc:arrayType="xsd:string[2]">`,
+		`<item xsi:type="xsd:string">ACME</item>`,
+		`<limit xsi:type="xsd:int">2</limit></rpc:getQuotes>`,
+	} {
+		if !strings.Contains(gotBody, want) {
+			t.Errorf("request is missing %s\n%s", want, gotBody)
+		}
+	}
+
+	want := &EncodedQuote{Symbol: "ACME", Price: 12.5}
+	assert.Equal(t, want, reply.First)
+	assert.Equal(t, want, reply.Second)
+
+	// a client shared with literal services encodes only the calls asking for it
+	client = NewClient(ts.URL)
+	if err := client.CallContextWithOptions(context.TODO(), "getQuotes", req, reply, WithSOAPEncoding()); err != nil {
+		t.Fatalf("couln't call service: %v", err)
+	}
+	assert.Contains(t, gotBody, `soap:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"`)
+	client.Call("getQuotes", req, &EncodedGetQuotesResponse{})
+	assert.NotContains(t, gotBody, "encodingStyle")
+}
+
+func TestResolveMultiRefs_Cycle(t *testing.T) {
+	input := `<Envelope><Body><op><a href="#id0"/></op><multiRef id="id0"><next href="#id0"/></multiRef></Body></Envelope>`
+	data, err := resolveMultiRefs([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<Envelope><Body><op><a><next href="#id0"></next></a></op></Body></Envelope>`, string(data))
+}
+
+func TestUnmarshalFunc(t *testing.T) {
+	var order []string
+	record := func(d *xml.Decoder, start xml.StartElement) error {
+		var value string
+		if err := d.DecodeElement(&value, &start); err != nil {
+			return err
+		}
+		order = append(order, start.Name.Local+"="+value)
+		return nil
+	}
+	v := struct {
+		A UnmarshalFunc `xml:"a"`
+		B UnmarshalFunc `xml:"b"`
+	}{A: record, B: record}
+
+	if err := xml.Unmarshal([]byte(`<v><a>1</a><b>2</b><a>3</a></v>`), &v); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"a=1", "b=2", "a=3"}, order)
+}
+
+type xsiVehicle interface {
+	wheels() int
+}
+
+type xsiCar struct {
+	Seats int `xml:"seats"`
+}
+
+func (xsiCar) wheels() int { return 4 }
+
+type xsiBike struct {
+	Gears int `xml:"gears"`
+}
+
+func (xsiBike) wheels() int { return 2 }
+
+func (b xsiBike) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type plain xsiBike
+	return EncodeXSIType(e, start, xml.Name{Space: "urn:garage", Local: "Bike"}, plain(b))
+}
+
+func TestXSIType(t *testing.T) {
+	RegisterType(xml.Name{Space: "urn:garage", Local: "Bike"}, func() interface{} { return new(xsiBike) })
+	// not a vehicle
+	RegisterType(xml.Name{Space: "urn:other", Local: "Car"}, func() interface{} { return new(string) })
+	RegisterType(xml.Name{Space: "urn:garage", Local: "Car"}, func() interface{} { return new(xsiCar) })
+
+	var vehicles []xsiVehicle
+	decode := func(d *xml.Decoder, start xml.StartElement) error {
+		var v xsiVehicle = new(xsiCar)
+		if err := DecodeXSIType(d, start, &v); err != nil {
+			return err
+		}
+		vehicles = append(vehicles, v)
+		return nil
+	}
+	v := struct {
+		Vehicle UnmarshalFunc `xml:"vehicle"`
+	}{Vehicle: decode}
+
+	data := `<garage xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+		<vehicle xmlns:g="urn:garage" xsi:type="g:Bike"><gears>21</gears></vehicle>
+		<vehicle xsi:type="Car"><seats>5</seats></vehicle>
+		<vehicle><seats>2</seats></vehicle>
+	</garage>`
+	if err := xml.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []xsiVehicle{&xsiBike{Gears: 21}, &xsiCar{Seats: 5}, &xsiCar{Seats: 2}}, vehicles)
+
+	out, err := xml.Marshal(struct {
+		XMLName xml.Name   `xml:"garage"`
+		Vehicle xsiVehicle `xml:"vehicle"`
+	}{Vehicle: xsiBike{Gears: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<garage><vehicle xmlns:tns="urn:garage" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="tns:Bike"><gears>3</gears></vehicle></garage>`, string(out))
+
+	// prefixes declared by ancestors are resolved by the decoders of responses
+	RegisterType(xml.Name{Space: "urn:rental", Local: "Car"}, func() interface{} { return new(xsiBike) })
+	vehicles = nil
+	data = `<garage xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:r="urn:rental">
+		<vehicle xsi:type="r:Car"><gears>4</gears></vehicle>
+	</garage>`
+	if err := newNamespaceDecoder(strings.NewReader(data)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []xsiVehicle{&xsiBike{Gears: 4}}, vehicles)
+}
+
+// xsiTruck is also the type of the global truck element
+type xsiTruck struct {
+	XMLName xml.Name `xml:"urn:garage truck"`
+	Axles   int      `xml:"axles"`
+}
+
+func (xsiTruck) wheels() int { return 6 }
+
+// Truck is exported, as the base types embedded by the generated types are
+type Truck = xsiTruck
+
+// xsiTrailer embeds the type of the global truck element
+type xsiTrailer struct {
+	*Truck
+	Length int `xml:"length"`
+}
+
+func TestDecodeXSITypeOfGlobalElement(t *testing.T) {
+	RegisterType(xml.Name{Space: "urn:garage", Local: "Truck"}, func() interface{} { return new(xsiTruck) })
+	RegisterType(xml.Name{Space: "urn:garage", Local: "Trailer"}, func() interface{} { return new(xsiTrailer) })
+
+	data := `<garage xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:g="urn:garage">
+		<vehicle xsi:type="g:Truck"><axles>3</axles></vehicle>
+		<vehicle xsi:type="g:Trailer"><axles>2</axles><length>12</length></vehicle>
+	</garage>`
+	var vehicles []xsiVehicle
+	v := struct {
+		Vehicle UnmarshalFunc `xml:"vehicle"`
+	}{Vehicle: func(d *xml.Decoder, start xml.StartElement) error {
+		var v xsiVehicle = new(xsiCar)
+		if err := DecodeXSIType(d, start, &v); err != nil {
+			return err
+		}
+		vehicles = append(vehicles, v)
+		return nil
+	}}
+	if err := xml.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	// the XMLName field holds the name of the decoded element
+	assert.Equal(t, []xsiVehicle{
+		&xsiTruck{XMLName: xml.Name{Local: "vehicle"}, Axles: 3},
+		&xsiTrailer{Truck: &xsiTruck{Axles: 2}, Length: 12},
+	}, vehicles)
+}
+
+func TestDecodeXSITypeWithoutDefault(t *testing.T) {
+	var v xsiVehicle
+	d := xml.NewDecoder(strings.NewReader(`<vehicle><seats>5</seats></vehicle>`))
+	start, err := d.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, DecodeXSIType(d, start.(xml.StartElement), &v))
+}
+
+type xsiPark struct {
+	XMLName xml.Name `xml:"urn:garage park"`
+	Gears   int      `xml:"gears"`
+}
+
+func (p xsiPark) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type plain xsiPark
+	return EncodeXSIType(e, start, xml.Name{Space: "urn:garage", Local: "Park"}, plain(p))
+}
+
+func TestClient_CallMarshaler(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body/></soap:Envelope>`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	if err := client.Call("Park", &xsiPark{Gears: 3}, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	// the request is named after its XMLName field rather than Content
+	assert.Contains(t, string(body), `<park xmlns="urn:garage" xmlns:tns="urn:garage" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="tns:Park"><gears>3</gears></park>`)
+}
+
+type substShape interface {
+	SubstitutesShape()
+}
+
+type substCircle struct {
+	Radius int `xml:"radius"`
+}
+
+func (substCircle) SubstitutesShape() {}
+
+type substSquare struct {
+	Side int `xml:"side"`
+}
+
+func (substSquare) SubstitutesShape() {}
+
+func init() {
+	RegisterElement(xml.Name{Space: "urn:drawing", Local: "circle"}, func() interface{} { return new(substCircle) })
+	RegisterElement(xml.Name{Space: "urn:drawing", Local: "square"}, func() interface{} { return new(substSquare) })
+}
+
+func TestDecodeSubstitution(t *testing.T) {
+	var shapes []substShape
+	d := xml.NewDecoder(strings.NewReader(`<draw xmlns="urn:drawing"><square><side>2</side></square><circle><radius>3</radius></circle><triangle/></draw>`))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local == "draw" {
+			continue
+		}
+		var shape substShape
+		err = DecodeSubstitution(d, start, &shape)
+		if start.Name.Local == "triangle" {
+			// no element is registered as triangle
+			assert.Error(t, err)
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		shapes = append(shapes, shape)
+	}
+	assert.Equal(t, []substShape{&substSquare{Side: 2}, &substCircle{Radius: 3}}, shapes)
+}
+
+type validCode string
+
+func (c validCode) Validate() error {
+	v := new(Validation)
+	v.Facets("", c, Facets{Pattern: `[A-Z]+`, MaxLength: "3"})
+	return v.Err()
+}
+
+type validItem struct {
+	Code     *validCode
+	Quantity int
+	Unit     string
+}
+
+func (t *validItem) Validate() error {
+	v := new(Validation)
+	v.Required("code", t.Code)
+	v.Validate("code", &t.Code)
+	v.Facets("quantity", t.Quantity, Facets{MinInclusive: "1", MaxInclusive: "10"})
+	v.Facets("@unit", t.Unit, Facets{Enumeration: []string{"kg", "lb"}})
+	return v.Err()
+}
+
+func TestValidation(t *testing.T) {
+	good, bad := validCode("AB"), validCode("abcd")
+	items := []validItem{
+		{Code: &good, Quantity: 2, Unit: "kg"},
+		{Code: &bad, Quantity: 20, Unit: "g"},
+		{},
+	}
+
+	v := new(Validation)
+	v.Occurs("item", len(items), 1, 2)
+	v.Validate("item", items)
+	err := v.Err()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("got %v, want a *ValidationError", err)
+	}
+	assert.Equal(t, []Violation{
+		{Path: "item", Message: "occurs 3 times, at most 2 allowed"},
+		{Path: "item[2]/code", Message: `value "abcd" does not match pattern [A-Z]+`},
+		{Path: "item[2]/code", Message: "length 4 is greater than 3"},
+		{Path: "item[2]/quantity", Message: "value 20 is greater than 10"},
+		{Path: "item[2]/@unit", Message: `value "g" is not one of kg, lb`},
+		{Path: "item[3]/code", Message: "required element is missing"},
+	}, verr.Violations)
+	assert.Contains(t, err.Error(), "item[3]/code: required element is missing")
+
+	assert.NoError(t, new(Validation).Err())
+	assert.NoError(t, items[0].Validate())
+}
+
+func TestValidationNumericEnumeration(t *testing.T) {
+	v := new(Validation)
+	v.Facets("rate", 1.5, Facets{Enumeration: []string{"1.50", "2"}})
+	v.Facets("count", int32(2), Facets{Enumeration: []string{"1.50", "2.0"}})
+	assert.NoError(t, v.Err())
+
+	v.Facets("count", uint8(3), Facets{Enumeration: []string{"1", "2"}})
+	assert.Error(t, v.Err())
+}
+
+func TestValidationPointedZeroValue(t *testing.T) {
+	var absent *int32
+	zero := int32(0)
+	v := new(Validation)
+	v.Facets("discount", absent, Facets{MinInclusive: "1"})
+	v.Facets("discount", zero, Facets{MinInclusive: "1"})
+	assert.NoError(t, v.Err())
+
+	v.Facets("discount", &zero, Facets{MinInclusive: "1"})
+	assert.EqualError(t, v.Err(), "discount: value 0 is less than 1")
+}
+
+type validRequest struct {
+	XMLName xml.Name    `xml:"http://example.com/service.xsd order"`
+	Item    []validItem `xml:"item"`
+}
+
+func (t *validRequest) Validate() error {
+	v := new(Validation)
+	v.Occurs("item", len(t.Item), 1, -1)
+	v.Validate("item", t.Item)
+	return v.Err()
+}
+
+type validResponse struct {
+	XMLName xml.Name   `xml:"http://example.com/service.xsd orderResponse"`
+	Code    *validCode `xml:"code"`
+}
+
+func (t *validResponse) Validate() error {
+	v := new(Validation)
+	v.Validate("code", t.Code)
+	return v.Err()
+}
+
+func TestClient_Validation(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+		<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+			<soap:Body>
+				<orderResponse xmlns="http://example.com/service.xsd"><code>abc</code></orderResponse>
+			</soap:Body>
+		</soap:Envelope>`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, WithValidation())
+	err := client.Call("GetData", &validRequest{}, &validResponse{})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v, want a *ValidationError", err)
+	}
+	assert.Equal(t, "invalid request: order/item: occurs 0 times, at least 1 required", err.Error())
+	assert.Equal(t, 0, calls)
+
+	code := validCode("AB")
+	err = client.Call("GetData", &validRequest{Item: []validItem{{Code: &code, Quantity: 1}}}, &validResponse{})
+	assert.EqualError(t, err, `invalid response: orderResponse/code: value "abc" does not match pattern [A-Z]+`)
+	assert.Equal(t, 1, calls)
+
+	err = NewClient(ts.URL).Call("GetData", &validRequest{}, &validResponse{})
+	assert.NoError(t, err)
+}
+
+func TestClient_ValidationFaultDetail(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+		<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+			<soap:Body>
+				<soap:Fault>
+					<faultcode>soap:Client</faultcode>
+					<faultstring>invalid request</faultstring>
+					<detail>
+						<violation path="order/item[1]/code">required element is missing</violation>
+						<violation path="order/item[2]/quantity">value 20 is greater than 10</violation>
+					</detail>
+				</soap:Fault>
+			</soap:Body>
+		</soap:Envelope>`))
+	}))
+	defer ts.Close()
+
+	detail := new(ValidationError)
+	err := NewClient(ts.URL).CallWithFaultDetail("GetData", &validRequest{}, &validResponse{}, detail)
+	assert.EqualError(t, err, "invalid request: order/item[1]/code: required element is missing; order/item[2]/quantity: value 20 is greater than 10")
+	assert.Equal(t, []Violation{
+		{Path: "order/item[1]/code", Message: "required element is missing"},
+		{Path: "order/item[2]/quantity", Message: "value 20 is greater than 10"},
+	}, detail.Violations)
+}
+
+func TestParseNumber(t *testing.T) {
+	type priority int16
+	var p priority
+	assert.NoError(t, ParseNumber([]byte(" 03\n"), &p))
+	assert.Equal(t, priority(3), p)
+	assert.Equal(t, "3", FormatNumber(p))
+	assert.Error(t, ParseNumber([]byte("40000"), &p))
+
+	type rate float64
+	var r rate
+	assert.NoError(t, ParseNumber([]byte("1.50"), &r))
+	assert.Equal(t, "1.5", FormatNumber(r))
+	assert.NoError(t, ParseNumber([]byte("-INF"), &r))
+	assert.Equal(t, "-INF", FormatNumber(r))
+
+	assert.Error(t, ParseNumber([]byte("1"), new(string)))
+}
+
+func TestSetDefault(t *testing.T) {
+	var retries *int32
+	SetDefault(&retries, " 3 ")
+	if assert.NotNil(t, retries) {
+		assert.Equal(t, int32(3), *retries)
+	}
+
+	verbose := false
+	SetDefault(&verbose, "true")
+	assert.True(t, verbose)
+
+	// invalid values leave the field unchanged
+	SetDefault(&verbose, "yes please")
+	assert.True(t, verbose)
+
+	var level *float64
+	SetDefault(&level, "high")
+	assert.Nil(t, level)
+
+	var created time.Time
+	SetDefault(&created, "2006-01-02T15:04:05Z")
+	assert.Equal(t, 2006, created.Year())
+}
+
+func TestXSDDecimal(t *testing.T) {
+	type rate XSDDecimal
+	var v struct {
+		XMLName xml.Name    `xml:"order"`
+		Price   XSDDecimal  `xml:"price"`
+		Rate    rate        `xml:"rate,attr"`
+		Tax     *XSDDecimal `xml:"tax"`
+		Total   XSDDecimal  `xml:"total"`
+	}
+	err := xml.Unmarshal([]byte(`<order rate="0.5"><price> 10.50 </price><tax>-.25</tax></order>`), &v)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.50", v.Price.String())
+	assert.Equal(t, "0.5", v.Rate.String())
+	assert.Equal(t, big.NewRat(-1, 4), v.Tax.Rat())
+	assert.Equal(t, 10.5, v.Price.Float64())
+
+	out, err := xml.Marshal(v)
+	assert.NoError(t, err)
+	assert.Equal(t, `<order rate="0.5"><price>10.50</price><tax>-.25</tax></order>`, string(out))
+
+	_, err = ParseXsdDecimal("1e3")
+	assert.Error(t, err)
+	assert.Equal(t, "3.33", CreateXsdDecimal(big.NewRat(10, 3), 2).String())
+
+	validation := new(Validation)
+	validation.Facets("rate", v.Rate, Facets{Enumeration: []string{"0.50"}, MaxInclusive: "1"})
+	assert.NoError(t, validation.Err())
+}
+
+func TestXSDInteger(t *testing.T) {
+	var v struct {
+		XMLName xml.Name   `xml:"order"`
+		Count   XSDInteger `xml:"count,attr"`
+		Serial  XSDInteger `xml:"serial"`
+		Batch   XSDInteger `xml:"batch"`
+	}
+	err := xml.Unmarshal([]byte(`<order count="+3"><serial>123456789012345678901234567890</serial></order>`), &v)
+	assert.NoError(t, err)
+	n, ok := v.Count.Int64()
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), n)
+	_, ok = v.Serial.Int64()
+	assert.False(t, ok)
+	assert.Equal(t, "123456789012345678901234567890", v.Serial.BigInt().String())
+
+	out, err := xml.Marshal(v)
+	assert.NoError(t, err)
+	assert.Equal(t, `<order count="3"><serial>123456789012345678901234567890</serial></order>`, string(out))
+
+	_, err = ParseXsdInteger("1.0")
+	assert.Error(t, err)
+	i := big.NewInt(7)
+	x := CreateXsdInteger(i)
+	i.SetInt64(8)
+	assert.Equal(t, "7", x.String())
+
+	validation := new(Validation)
+	validation.Facets("serial", v.Serial, Facets{MaxInclusive: "1000"})
+	assert.EqualError(t, validation.Err(), "serial: value 123456789012345678901234567890 is greater than 1000")
+}
+
+func TestXSDDuration(t *testing.T) {
+	tests := []struct {
+		lexical   string
+		canonical string
+		duration  time.Duration
+		fixed     bool
+	}{
+		{"P1Y2M3DT4H5M6.5S", "P1Y2M3DT4H5M6.5S", 0, false},
+		{"-PT90M", "-PT90M", -90 * time.Minute, true},
+		{"P2DT0.000000001S", "P2DT0.000000001S", 48*time.Hour + 1, true},
+		{"PT0S", "PT0S", 0, true},
+		{"P0Y", "PT0S", 0, true},
+	}
+	for _, test := range tests {
+		d, err := ParseXsdDuration(test.lexical)
+		if !assert.NoError(t, err, test.lexical) {
+			continue
+		}
+		assert.Equal(t, test.canonical, d.String())
+		goDuration, err := d.ToGoDuration()
+		if test.fixed {
+			assert.NoError(t, err, test.lexical)
+			assert.Equal(t, test.duration, goDuration, test.lexical)
+		} else {
+			assert.Error(t, err, test.lexical)
+		}
+	}
+
+	for _, invalid := range []string{"P", "-P", "PT", "P1DT", "1D", "P-1D", "PT1.S", "P1H"} {
+		_, err := ParseXsdDuration(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	d := CreateXsdDuration(0, -1, 0, -36*time.Hour-time.Second)
+	assert.Equal(t, "-P1M1DT12H1S", d.String())
+	assert.True(t, d.Negative())
+	start := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC), d.AddTo(start))
+
+	var v struct {
+		XMLName xml.Name    `xml:"lease"`
+		Term    XSDDuration `xml:"term,attr"`
+		Notice  XSDDuration `xml:"notice"`
+		Renewal XSDDuration `xml:"renewal"`
+	}
+	assert.NoError(t, xml.Unmarshal([]byte(`<lease term="P1Y"><notice> P3M </notice></lease>`), &v))
+	out, err := xml.Marshal(v)
+	assert.NoError(t, err)
+	assert.Equal(t, `<lease term="P1Y"><notice>P3M</notice></lease>`, string(out))
+}
+
+func TestXSDGregorian(t *testing.T) {
+	year, err := ParseXsdGYear("-0044Z")
+	assert.NoError(t, err)
+	assert.Equal(t, -44, year.Year())
+	assert.Equal(t, time.UTC, year.Location())
+	assert.Equal(t, "-0044Z", year.String())
+
+	yearMonth, err := ParseXsdGYearMonth("2024-05+02:00")
+	assert.NoError(t, err)
+	assert.Equal(t, time.May, yearMonth.Month())
+	assert.Equal(t, time.Date(2024, 4, 30, 22, 0, 0, 0, time.UTC), yearMonth.ToGoTime().UTC())
+	assert.Equal(t, "2024-05+02:00", yearMonth.String())
+
+	month, err := ParseXsdGMonth("--12--")
+	assert.NoError(t, err)
+	assert.Equal(t, "--12", month.String())
+
+	monthDay, err := ParseXsdGMonthDay("--02-29-05:30")
+	assert.NoError(t, err)
+	assert.Equal(t, 29, monthDay.Day())
+	assert.Equal(t, "--02-29-05:30", monthDay.String())
+	assert.Equal(t, time.March, monthDay.In(2023).Month())
+
+	day, err := ParseXsdGDay("---07")
+	assert.NoError(t, err)
+	assert.Nil(t, day.Location())
+	assert.Equal(t, "---07", day.String())
+
+	for _, invalid := range []string{"24", "2024-13", "--02-30", "---32", "--13", "2024+15:00", "2024-5"} {
+		_, err1 := ParseXsdGYear(invalid)
+		_, err2 := ParseXsdGYearMonth(invalid)
+		_, err3 := ParseXsdGMonth(invalid)
+		_, err4 := ParseXsdGMonthDay(invalid)
+		_, err5 := ParseXsdGDay(invalid)
+		assert.True(t, err1 != nil && err2 != nil && err3 != nil && err4 != nil && err5 != nil, invalid)
+	}
+
+	var v struct {
+		XMLName   xml.Name      `xml:"event"`
+		Year      XSDGYear      `xml:"year,attr"`
+		Month     XSDGYearMonth `xml:"month"`
+		Birthday  XSDGMonthDay  `xml:"birthday"`
+		Recurring XSDGDay       `xml:"recurring"`
+	}
+	v.Year = CreateXsdGYear(2024, nil)
+	v.Birthday = CreateXsdGMonthDay(time.June, 1, time.UTC)
+	out, err := xml.Marshal(v)
+	assert.NoError(t, err)
+	assert.Equal(t, `<event year="2024"><birthday>--06-01Z</birthday></event>`, string(out))
+	assert.NoError(t, xml.Unmarshal([]byte(`<event year="1999"><month>2024-05</month></event>`), &v))
+	assert.Equal(t, 1999, v.Year.Year())
+	assert.Equal(t, 2024, v.Month.Year())
+}
+
+func TestQName(t *testing.T) {
+	var v struct {
+		XMLName xml.Name `xml:"fault"`
+		Code    QName    `xml:"code"`
+		Subcode QName    `xml:"subcode"`
+		Lang    QName    `xml:"lang,attr"`
+	}
+	err := xml.Unmarshal([]byte(`<fault lang="xml:space"><code xmlns:env="urn:env"> env:Server </code><subcode>app:Busy</subcode></fault>`), &v)
+	assert.NoError(t, err)
+	assert.Equal(t, QName{Space: "urn:env", Local: "Server"}, v.Code)
+	assert.Equal(t, "{urn:env}Server", v.Code.String())
+	// undeclared prefixes are kept
+	assert.Equal(t, QName{Local: "app:Busy"}, v.Subcode)
+	assert.Equal(t, QName{Space: xmlNamespace, Local: "space"}, v.Lang)
+
+	out, err := xml.Marshal(v)
+	assert.NoError(t, err)
+	assert.Equal(t, `<fault lang="xml:space"><code xmlns:qn="urn:env">qn:Server</code><subcode>app:Busy</subcode></fault>`, string(out))
+
+}
+
+// typedRef has QName attributes, marshalled like those of the generated types
+type typedRef struct {
+	XMLName xml.Name `xml:"ref"`
+	Kind    QName    `xml:"kind,attr"`
+	Lang    QName    `xml:"lang,attr,omitempty"`
+	Value   string   `xml:",chardata"`
+}
+
+func (t typedRef) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type plain typedRef
+	start.Name = xml.Name{Local: "ref"}
+	DeclareQNameAttrs(&start, t)
+	return e.EncodeElement(plain(t), start)
+}
+
+func (t *typedRef) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain typedRef
+	return DecodeQNameAttrs(d, (*plain)(t), &start)
+}
+
+func TestQNameAttrs(t *testing.T) {
+	var v struct {
+		XMLName xml.Name   `xml:"refs"`
+		Refs    []typedRef `xml:"ref"`
+	}
+	data := `<refs xmlns:tns="urn:types" xmlns="urn:default">
+		<ref kind="tns:Part" lang="xml:en">a</ref>
+		<ref kind="tns:Part" xmlns:tns="urn:local">b</ref>
+		<ref kind="Part">c</ref>
+		<ref kind="app:Part">d</ref>
+	</refs>`
+	assert.NoError(t, NewDecoder(strings.NewReader(data)).Decode(&v))
+	assert.Equal(t, []typedRef{
+		{XMLName: xml.Name{Space: "urn:default", Local: "ref"}, Kind: QName{Space: "urn:types", Local: "Part"}, Lang: QName{Space: xmlNamespace, Local: "en"}, Value: "a"},
+		{XMLName: xml.Name{Space: "urn:default", Local: "ref"}, Kind: QName{Space: "urn:local", Local: "Part"}, Value: "b"},
+		// unprefixed names are in the default namespace
+		{XMLName: xml.Name{Space: "urn:default", Local: "ref"}, Kind: QName{Space: "urn:default", Local: "Part"}, Value: "c"},
+		// undeclared prefixes are kept
+		{XMLName: xml.Name{Space: "urn:default", Local: "ref"}, Kind: QName{Local: "app:Part"}, Value: "d"},
+	}, v.Refs)
+
+	// without the scope tracked by NewDecoder, the declarations of the element
+	// owning the attribute are in scope
+	var ref typedRef
+	assert.NoError(t, xml.Unmarshal([]byte(`<ref kind="tns:Part" xmlns:tns="urn:types"/>`), &ref))
+	assert.Equal(t, QName{Space: "urn:types", Local: "Part"}, ref.Kind)
+
+	// the owner of the attributes declares their namespaces
+	prefix := attrPrefix("urn:types")
+	out, err := xml.Marshal(v.Refs[0])
+	assert.NoError(t, err)
+	assert.Equal(t, `<ref xmlns:`+prefix+`="urn:types" kind="`+prefix+`:Part" lang="xml:en">a</ref>`, string(out))
+	ref = typedRef{}
+	assert.NoError(t, xml.Unmarshal(out, &ref))
+	assert.Equal(t, v.Refs[0].Kind, ref.Kind)
+	assert.Equal(t, v.Refs[0].Lang, ref.Lang)
+
+	// unset optional attributes are omitted
+	out, err = xml.Marshal(v.Refs[1])
+	assert.NoError(t, err)
+	assert.Equal(t, `<ref xmlns:`+attrPrefix("urn:local")+`="urn:local" kind="`+attrPrefix("urn:local")+`:Part">b</ref>`, string(out))
+}
+
+type qnameResponse struct {
+	XMLName xml.Name `xml:"http://example.com/service.xsd qnameResponse"`
+
+	Ref   QName `xml:"ref"`
+	Local QName `xml:"local"`
+}
+
+func TestClient_QNameInScope(t *testing.T) {
+	responses := []string{`<?xml version="1.0" encoding="utf-8"?>
+		<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:tns="http://example.com/service.xsd">
+			<soap:Body>
+				<tns:qnameResponse xmlns="urn:default">
+					<ref>tns:Ping</ref>
+					<local xmlns:tns="urn:local">tns:Ping</local>
+				</tns:qnameResponse>
+			</soap:Body>
+		</soap:Envelope>`, `<?xml version="1.0" encoding="utf-8"?>
+		<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+			<soap:Body>
+				<soap:Fault>
+					<faultcode>soap:Server</faultcode>
+					<faultstring>server error</faultstring>
+				</soap:Fault>
+			</soap:Body>
+		</soap:Envelope>`}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(responses[0]))
+		responses = responses[1:]
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	var response qnameResponse
+	assert.NoError(t, client.Call("GetData", &Ping{}, &response))
+	assert.Equal(t, QName{Space: "http://example.com/service.xsd", Local: "Ping"}, response.Ref)
+	assert.Equal(t, QName{Space: "urn:local", Local: "Ping"}, response.Local)
+
+	err := client.Call("GetData", &Ping{}, &response)
+	fault, ok := err.(*SOAPFault)
+	if !ok {
+		t.Fatalf("Expected a SOAPFault. Received: %T %v", err, err)
+	}
+	assert.Equal(t, QName{Space: "http://schemas.xmlsoap.org/soap/envelope/", Local: "Server"}, fault.Code)
+}
+
+func TestXSDList(t *testing.T) {
+	var v struct {
+		XMLName xml.Name `xml:"tokens"`
+		Refs    XSDList  `xml:"refs,attr"`
+		Names   XSDList  `xml:"names"`
+	}
+	err := xml.Unmarshal([]byte(`<tokens refs=" a  b "><names>x
+		y z</names></tokens>`), &v)
+	assert.NoError(t, err)
+	assert.Equal(t, XSDList{"a", "b"}, v.Refs)
+	assert.Equal(t, XSDList{"x", "y", "z"}, v.Names)
+
+	out, err := xml.Marshal(v)
+	assert.NoError(t, err)
+	assert.Equal(t, `<tokens refs="a b"><names>x y z</names></tokens>`, string(out))
+
+	validation := new(Validation)
+	validation.Facets("names", v.Names, Facets{MaxLength: "2"})
+	assert.EqualError(t, validation.Err(), "names: length 3 is greater than 2")
+}
+
 func TestHTTPError(t *testing.T) {
 	type httpErrorTest struct {
 		name         string
@@ -0,0 +1,27 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+)
+
+// RegisterElement registers the Go type generated for the global element
+// named name, whose values are returned by new. Members of a substitution
+// group are decoded into such values by DecodeSubstitution.
+func RegisterElement(name xml.Name, new func() interface{}) {
+	elements.register(name, new)
+}
+
+// DecodeSubstitution decodes the element start into the interface pointed to
+// by v, implemented by the elements of a substitution group. The element is
+// decoded into a new value of the type registered for its name.
+func DecodeSubstitution(d *xml.Decoder, start xml.StartElement, v interface{}) error {
+	target := reflect.ValueOf(v).Elem()
+	value, ok := elements.lookup(start.Name, target.Type())
+	if !ok {
+		return fmt.Errorf("no element implementing %s is registered as %s", target.Type(), start.Name.Local)
+	}
+	target.Set(value)
+	return d.DecodeElement(value.Interface(), &start)
+}
@@ -0,0 +1,384 @@
+package soap
+
+import (
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Validator is implemented by the types generated from a schema, which check
+// their value against the constraints of their schema type.
+type Validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// Violation is a constraint of the schema which a value does not satisfy.
+type Violation struct {
+	// Path is the path of the invalid element or attribute from the
+	// validated value, e.g. item[2]/@code, or an empty string for the value
+	// itself.
+	Path    string `xml:"path,attr,omitempty"`
+	Message string `xml:",chardata"`
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// ValidationError is returned by the Validate methods of generated types,
+// listing every violation of the constraints of the schema. It is marshalled
+// as the detail of the faults of generated servers rejecting invalid
+// requests, and implements FaultError to decode them.
+type ValidationError struct {
+	Violations []Violation `xml:"violation"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.String()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ErrorString implements FaultError.
+func (e *ValidationError) ErrorString() string {
+	return "invalid request: " + e.Error()
+}
+
+// HasData implements FaultError.
+func (e *ValidationError) HasData() bool {
+	return len(e.Violations) > 0
+}
+
+// ValidateContent validates the content of a SOAP body if it implements
+// Validator, the paths of the violations starting with the name of its
+// element.
+func ValidateContent(content interface{}) error {
+	v := new(Validation)
+	name, _ := structXMLName(reflect.Indirect(reflect.ValueOf(content)))
+	v.Validate(name.Local, content)
+	return v.Err()
+}
+
+// Facets are the constraining facets of a restriction of a simple type, with
+// the values declared by the schema.
+type Facets struct {
+	Enumeration []string
+	// Pattern is a regular expression of package regexp matching the whole
+	// lexical representation of valid values.
+	Pattern      string
+	MinInclusive string
+	MaxInclusive string
+	Length       string
+	MinLength    string
+	MaxLength    string
+}
+
+// Validation records the violations found by the Validate method of a
+// generated type. The zero value is ready to use.
+type Validation struct {
+	path       string
+	violations *[]Violation
+}
+
+func (v *Validation) list() *[]Violation {
+	if v.violations == nil {
+		v.violations = new([]Violation)
+	}
+	return v.violations
+}
+
+// At returns a Validation recording the violations of the element or
+// attribute named name along with the ones of v.
+func (v *Validation) At(name string) *Validation {
+	return &Validation{path: joinPath(v.path, name), violations: v.list()}
+}
+
+// Index returns the name of the i-th occurrence of the element named name,
+// counting from zero.
+func Index(name string, i int) string {
+	if name == "" {
+		return ""
+	}
+	return name + "[" + strconv.Itoa(i+1) + "]"
+}
+
+func joinPath(path, name string) string {
+	switch {
+	case path == "":
+		return name
+	case name == "":
+		return path
+	}
+	return path + "/" + name
+}
+
+// Add records a violation of the element or attribute named name.
+func (v *Validation) Add(name, format string, args ...interface{}) {
+	list := v.list()
+	*list = append(*list, Violation{Path: joinPath(v.path, name), Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns a *ValidationError listing the recorded violations, or nil.
+func (v *Validation) Err() error {
+	if v.violations == nil || len(*v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: append([]Violation(nil), *v.violations...)}
+}
+
+// Required records a violation when the value of the required element or
+// attribute named name is the zero value, which is not marshalled.
+func (v *Validation) Required(name string, value interface{}) {
+	if value != nil && !reflect.ValueOf(value).IsZero() {
+		return
+	}
+	if strings.HasPrefix(name, "@") {
+		v.Add(name, "required attribute is missing")
+	} else {
+		v.Add(name, "required element is missing")
+	}
+}
+
+// Occurs records a violation when the element named name occurs n times
+// while the schema requires between min and max occurrences, max being
+// negative when unbounded.
+func (v *Validation) Occurs(name string, n, min, max int) {
+	switch {
+	case n < min:
+		v.Add(name, "occurs %d times, at least %d required", n, min)
+	case max >= 0 && n > max:
+		v.Add(name, "occurs %d times, at most %d allowed", n, max)
+	}
+}
+
+// Validate records the violations found by validating the value of the
+// element or attribute named name, if it implements Validator. Pointers and
+// interfaces are followed, and each item of a slice is validated as an
+// occurrence of the element.
+func (v *Validation) Validate(name string, value interface{}) {
+	v.validate(name, reflect.ValueOf(value))
+}
+
+func (v *Validation) validate(name string, rv reflect.Value) {
+	for rv.IsValid() {
+		switch {
+		case (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil():
+			return
+		case rv.Type().Implements(validatorType):
+			v.merge(name, rv.Interface().(Validator).Validate())
+			return
+		case rv.CanAddr() && rv.Addr().Type().Implements(validatorType):
+			v.merge(name, rv.Addr().Interface().(Validator).Validate())
+			return
+		}
+
+		switch rv.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			rv = rv.Elem()
+		case reflect.Slice, reflect.Array:
+			if rv.Type().Elem().Kind() == reflect.Uint8 {
+				return
+			}
+			for i := 0; i < rv.Len(); i++ {
+				v.validate(Index(name, i), rv.Index(i))
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
+// merge records the violations of the value of the element or attribute
+// named name.
+func (v *Validation) merge(name string, err error) {
+	if err == nil {
+		return
+	}
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		v.Add(name, "%v", err)
+		return
+	}
+	list := v.list()
+	for _, violation := range verr.Violations {
+		violation.Path = joinPath(joinPath(v.path, name), violation.Path)
+		*list = append(*list, violation)
+	}
+}
+
+// Facets records the violations of the facets by the value of the element
+// or attribute named name. Zero values, which are not marshalled, are not
+// checked unless pointed to.
+func (v *Validation) Facets(name string, value interface{}, f Facets) {
+	rv := reflect.ValueOf(value)
+	pointed := false
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return
+		}
+		pointed = pointed || rv.Kind() == reflect.Ptr
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || !pointed && rv.IsZero() {
+		return
+	}
+
+	text, ok := lexical(rv)
+	if ok && len(f.Enumeration) > 0 && !enumerates(rv, text, f.Enumeration) {
+		v.Add(name, "value %q is not one of %s", text, strings.Join(f.Enumeration, ", "))
+	}
+	if ok && f.Pattern != "" {
+		re, err := compilePattern(f.Pattern)
+		if err != nil {
+			v.Add(name, "invalid pattern %s: %v", f.Pattern, err)
+		} else if !re.MatchString(text) {
+			v.Add(name, "value %q does not match pattern %s", text, f.Pattern)
+		}
+	}
+
+	if n, ok := length(rv); ok {
+		if l, err := strconv.Atoi(f.Length); err == nil && n != l {
+			v.Add(name, "length %d is not %d", n, l)
+		}
+		if l, err := strconv.Atoi(f.MinLength); err == nil && n < l {
+			v.Add(name, "length %d is less than %d", n, l)
+		}
+		if l, err := strconv.Atoi(f.MaxLength); err == nil && n > l {
+			v.Add(name, "length %d is greater than %d", n, l)
+		}
+	}
+
+	if f.MinInclusive == "" && f.MaxInclusive == "" {
+		return
+	}
+	x, ok := number(rv, text)
+	if !ok {
+		return
+	}
+	if min, ok := new(big.Rat).SetString(f.MinInclusive); ok && x.Cmp(min) < 0 {
+		v.Add(name, "value %s is less than %s", text, f.MinInclusive)
+	}
+	if max, ok := new(big.Rat).SetString(f.MaxInclusive); ok && x.Cmp(max) > 0 {
+		v.Add(name, "value %s is greater than %s", text, f.MaxInclusive)
+	}
+}
+
+var patterns sync.Map
+
+// compilePattern returns the compiled pattern, anchored to match whole
+// values.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// lexical returns the lexical representation of a value of a simple type.
+func lexical(rv reflect.Value) (string, bool) {
+	if m, ok := rv.Interface().(encoding.TextMarshaler); ok {
+		// strict enumerations refuse to marshal the values they do not
+		// enumerate
+		if text, err := m.MarshalText(); err == nil {
+			return string(text), true
+		}
+	}
+	if m, ok := rv.Interface().(xml.MarshalerAttr); ok {
+		attr, err := m.MarshalXMLAttr(xml.Name{})
+		return attr.Value, err == nil
+	}
+	if s, ok := rv.Interface().(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	return basicText(rv)
+}
+
+// basicText returns the lexical representation of a value of a basic kind.
+func basicText(rv reflect.Value) (string, bool) {
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case math.IsInf(f, 1):
+			return "INF", true
+		case math.IsInf(f, -1):
+			return "-INF", true
+		case math.IsNaN(f):
+			return "NaN", true
+		}
+		return strconv.FormatFloat(f, 'g', -1, rv.Type().Bits()), true
+	}
+	return "", false
+}
+
+// length returns the length of a value in characters for strings, in octets
+// for binary values and in items for lists.
+func length(rv reflect.Value) (int, bool) {
+	switch rv.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(rv.String()), true
+	case reflect.Slice, reflect.Array:
+		return rv.Len(), true
+	}
+	return 0, false
+}
+
+// number returns the numeric value of a value, or of its lexical
+// representation for non-numeric types.
+func number(rv reflect.Value, text string) (*big.Rat, bool) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(rv.Uint())), true
+	case reflect.Float32, reflect.Float64:
+		x := new(big.Rat).SetFloat64(rv.Float())
+		return x, x != nil
+	}
+	return new(big.Rat).SetString(text)
+}
+
+// enumerates reports whether the value is one of the enumerated values,
+// which are compared numerically for numbers.
+func enumerates(rv reflect.Value, text string, values []string) bool {
+	x, numeric := number(rv, text)
+	numeric = numeric && rv.Kind() != reflect.String
+	for _, value := range values {
+		if value == text {
+			return true
+		}
+		if y, ok := new(big.Rat).SetString(value); numeric && ok && x.Cmp(y) == 0 {
+			return true
+		}
+	}
+	return false
+}
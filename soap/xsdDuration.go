@@ -0,0 +1,217 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationPattern is the lexical space of xsd:duration.
+var durationPattern = regexp.MustCompile(`^(-)?P(?:([0-9]+)Y)?(?:([0-9]+)M)?(?:([0-9]+)D)?` +
+	`(T(?:([0-9]+)H)?(?:([0-9]+)M)?(?:([0-9]+)(?:\.([0-9]+))?S)?)?$`)
+
+//
+// Duration struct
+//
+
+// XSDDuration is a type for representing xsd:duration in Golang. It keeps the
+// years, months, days, hours, minutes and seconds of the duration, as the
+// length of years and months varies. The zero value is unset, and is not
+// marshalled.
+//
+// The methods are those of the embedded type, so that the types declared with
+// XSDDuration as underlying type have them too.
+type XSDDuration struct {
+	duration
+}
+
+type duration struct {
+	set      bool
+	negative bool
+	years    int
+	months   int
+	days     int
+	hours    int
+	minutes  int
+	seconds  int
+	nanos    int
+}
+
+// ParseXsdDuration parses the lexical representation of a xsd:duration, e.g.
+// "P1Y2M3DT4H5M6.5S" or "-PT30M"
+func ParseXsdDuration(s string) (XSDDuration, error) {
+	var d XSDDuration
+	err := d.UnmarshalText([]byte(s))
+	return d, err
+}
+
+// CreateXsdDuration creates an object represent xsd:duration object in Golang
+// of the given years, months and days, followed by d. The duration is
+// negative if any of them is negative, all of them being then subtracted.
+func CreateXsdDuration(years, months, days int, d time.Duration) XSDDuration {
+	x := duration{set: true, negative: years < 0 || months < 0 || days < 0 || d < 0}
+	x.years, x.months, x.days = abs(years), abs(months), abs(days)
+	u := uint64(d)
+	if d < 0 {
+		u = uint64(-d)
+	}
+	x.days += int(u / uint64(24*time.Hour))
+	x.hours = int(u / uint64(time.Hour) % 24)
+	x.minutes = int(u / uint64(time.Minute) % 60)
+	x.seconds = int(u / uint64(time.Second) % 60)
+	x.nanos = int(u % uint64(time.Second))
+	return XSDDuration{x}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Negative reports whether the duration is negative
+func (d duration) Negative() bool {
+	return d.negative
+}
+
+// ToGoDuration converts the duration to time.Duration. Durations of years or
+// months, whose length varies, and durations out of the range of
+// time.Duration are not converted.
+func (d duration) ToGoDuration() (time.Duration, error) {
+	if d.years != 0 || d.months != 0 {
+		return 0, errors.New("xsd:duration of years or months has no fixed length")
+	}
+	total := float64(d.days)*float64(24*time.Hour) + float64(d.hours)*float64(time.Hour) +
+		float64(d.minutes)*float64(time.Minute) + float64(d.seconds)*float64(time.Second)
+	if total+float64(d.nanos) >= math.MaxInt64 {
+		return 0, fmt.Errorf("xsd:duration %s is out of range", d.String())
+	}
+	goDuration := time.Duration(d.days)*24*time.Hour + time.Duration(d.hours)*time.Hour +
+		time.Duration(d.minutes)*time.Minute + time.Duration(d.seconds)*time.Second + time.Duration(d.nanos)
+	if d.negative {
+		goDuration = -goDuration
+	}
+	return goDuration, nil
+}
+
+// AddTo returns the time t plus the duration, adding its years, months and
+// days to the date of t, as time.AddDate does, and then the rest.
+func (d duration) AddTo(t time.Time) time.Time {
+	sign := 1
+	if d.negative {
+		sign = -1
+	}
+	t = t.AddDate(sign*d.years, sign*d.months, sign*d.days)
+	rest := time.Duration(d.hours)*time.Hour + time.Duration(d.minutes)*time.Minute +
+		time.Duration(d.seconds)*time.Second + time.Duration(d.nanos)
+	return t.Add(time.Duration(sign) * rest)
+}
+
+// String returns the lexical representation of the duration, or an empty
+// string if it is unset.
+func (d duration) String() string {
+	if !d.set {
+		return ""
+	}
+	var b strings.Builder
+	if d.negative {
+		b.WriteByte('-')
+	}
+	b.WriteByte('P')
+	date := d.years != 0 || d.months != 0 || d.days != 0
+	if d.years != 0 {
+		b.WriteString(strconv.Itoa(d.years) + "Y")
+	}
+	if d.months != 0 {
+		b.WriteString(strconv.Itoa(d.months) + "M")
+	}
+	if d.days != 0 {
+		b.WriteString(strconv.Itoa(d.days) + "D")
+	}
+	if d.hours != 0 || d.minutes != 0 || d.seconds != 0 || d.nanos != 0 || !date {
+		b.WriteByte('T')
+		if d.hours != 0 {
+			b.WriteString(strconv.Itoa(d.hours) + "H")
+		}
+		if d.minutes != 0 {
+			b.WriteString(strconv.Itoa(d.minutes) + "M")
+		}
+		if d.seconds != 0 || d.nanos != 0 || d.hours == 0 && d.minutes == 0 {
+			b.WriteString(strconv.Itoa(d.seconds))
+			if d.nanos != 0 {
+				b.WriteString(strings.TrimRight(fmt.Sprintf(".%09d", d.nanos), "0"))
+			}
+			b.WriteByte('S')
+		}
+	}
+	return b.String()
+}
+
+// MarshalText implements encoding.TextMarshaler on XSDDuration
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler on XSDDuration. An empty
+// text leaves the duration unset. Fractions of seconds are truncated to
+// nanoseconds.
+func (d *duration) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s == "" {
+		*d = duration{}
+		return nil
+	}
+	m := durationPattern.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "-P" || m[5] == "T" {
+		return fmt.Errorf("invalid xsd:duration %q", s)
+	}
+
+	x := duration{set: true, negative: m[1] != ""}
+	for i, n := range []*int{&x.years, &x.months, &x.days, nil, &x.hours, &x.minutes, &x.seconds} {
+		if n == nil || m[i+2] == "" {
+			continue
+		}
+		var err error
+		if *n, err = strconv.Atoi(m[i+2]); err != nil {
+			return fmt.Errorf("invalid xsd:duration %q: %v", s, err)
+		}
+	}
+	if fraction := m[9]; fraction != "" {
+		if len(fraction) > 9 {
+			fraction = fraction[:9]
+		}
+		x.nanos, _ = strconv.Atoi(fraction + strings.Repeat("0", 9-len(fraction)))
+	}
+	*d = x
+	return nil
+}
+
+// MarshalXML implements xml.Marshaler on XSDDuration
+func (d duration) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalLexical(e, start, d.String())
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr on XSDDuration
+func (d duration) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalLexicalAttr(name, d.String()), nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler on XSDDuration
+func (d *duration) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	return unmarshalLexical(dec, start, d)
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr on XSDDuration
+func (d *duration) UnmarshalXMLAttr(attr xml.Attr) error {
+	return d.UnmarshalText([]byte(attr.Value))
+}
@@ -0,0 +1,541 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gregorianFormat is the lexical representation of a Gregorian type. Its
+// pattern matches the year, month, day and timezone, in that order, some of
+// them being always empty.
+type gregorianFormat struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+const timezonePattern = `(Z|[+-][0-9]{2}:[0-9]{2})?$`
+
+var (
+	gYearFormat      = gregorianFormat{"gYear", regexp.MustCompile(`^(-?[0-9]{4,})()()` + timezonePattern)}
+	gYearMonthFormat = gregorianFormat{"gYearMonth", regexp.MustCompile(`^(-?[0-9]{4,})-([0-9]{2})()` + timezonePattern)}
+	// gMonth allows the "--MM--" form of the first edition of XML Schema
+	gMonthFormat    = gregorianFormat{"gMonth", regexp.MustCompile(`^()--([0-9]{2})()(?:--)?` + timezonePattern)}
+	gMonthDayFormat = gregorianFormat{"gMonthDay", regexp.MustCompile(`^()--([0-9]{2})-([0-9]{2})` + timezonePattern)}
+	gDayFormat      = gregorianFormat{"gDay", regexp.MustCompile(`^()()---([0-9]{2})` + timezonePattern)}
+)
+
+// gregorian is the value of a Gregorian type, whose fields which are not part
+// of its format are left zero.
+type gregorian struct {
+	set   bool
+	year  int
+	month time.Month
+	day   int
+	// loc is the timezone, nil when it is not specified
+	loc *time.Location
+}
+
+func (g gregorian) format(f gregorianFormat) string {
+	if !g.set {
+		return ""
+	}
+	var s string
+	switch f {
+	case gYearFormat:
+		s = formatYear(g.year)
+	case gYearMonthFormat:
+		s = fmt.Sprintf("%s-%02d", formatYear(g.year), g.month)
+	case gMonthFormat:
+		s = fmt.Sprintf("--%02d", g.month)
+	case gMonthDayFormat:
+		s = fmt.Sprintf("--%02d-%02d", g.month, g.day)
+	case gDayFormat:
+		s = fmt.Sprintf("---%02d", g.day)
+	}
+	if g.loc == nil {
+		return s
+	}
+	// the offset of the timezone at the start of the period, in a leap year
+	// when the year is not part of the format
+	year := 2000
+	if f == gYearFormat || f == gYearMonthFormat {
+		year = g.year
+	}
+	_, offset := g.date(year).Zone()
+	if offset == 0 {
+		return s + "Z"
+	}
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("%s%c%02d:%02d", s, sign, offset/3600, offset/60%60)
+}
+
+func formatYear(year int) string {
+	if year < 0 {
+		return fmt.Sprintf("-%04d", -year)
+	}
+	return fmt.Sprintf("%04d", year)
+}
+
+func (g *gregorian) parse(text []byte, f gregorianFormat) error {
+	s := strings.TrimSpace(string(text))
+	if s == "" {
+		*g = gregorian{}
+		return nil
+	}
+	m := f.pattern.FindStringSubmatch(s)
+	if m == nil {
+		return fmt.Errorf("invalid xsd:%s %q", f.name, s)
+	}
+
+	// year 2000 is a leap year, allowing --02-29
+	x := gregorian{set: true, year: 2000, month: time.January, day: 1}
+	var err error
+	if m[1] != "" {
+		if x.year, err = strconv.Atoi(m[1]); err != nil {
+			return fmt.Errorf("invalid xsd:%s %q: %v", f.name, s, err)
+		}
+	}
+	if m[2] != "" {
+		month, _ := strconv.Atoi(m[2])
+		x.month = time.Month(month)
+	}
+	if m[3] != "" {
+		x.day, _ = strconv.Atoi(m[3])
+	}
+	if x.month < time.January || x.month > time.December || x.day < 1 || x.day > daysIn(x.month) {
+		return fmt.Errorf("invalid xsd:%s %q: day or month out of range", f.name, s)
+	}
+	switch tz := m[4]; {
+	case tz == "Z":
+		x.loc = time.UTC
+	case tz != "":
+		hours, _ := strconv.Atoi(tz[1:3])
+		minutes, _ := strconv.Atoi(tz[4:])
+		if hours > 14 || minutes > 59 || hours == 14 && minutes > 0 {
+			return fmt.Errorf("invalid xsd:%s %q: timezone out of range", f.name, s)
+		}
+		offset := (hours*60 + minutes) * 60
+		if tz[0] == '-' {
+			offset = -offset
+		}
+		x.loc = time.FixedZone("", offset)
+	}
+
+	if m[1] == "" {
+		x.year = 0
+	}
+	if m[2] == "" {
+		x.month = 0
+	}
+	if m[3] == "" {
+		x.day = 0
+	}
+	*g = x
+	return nil
+}
+
+// daysIn returns the maximum number of days of the month.
+func daysIn(month time.Month) int {
+	return time.Date(2000, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// date returns the start of the period in the given year, in the local
+// timezone when it is not specified.
+func (g gregorian) date(year int) time.Time {
+	month, day := g.month, g.day
+	if month == 0 {
+		month = time.January
+	}
+	if day == 0 {
+		day = 1
+	}
+	loc := g.loc
+	if loc == nil {
+		loc = time.Local
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// Location returns the TZ information of the value, or nil if it is not
+// specified
+func (g gregorian) Location() *time.Location {
+	return g.loc
+}
+
+func createGregorian(year int, month time.Month, day int, loc *time.Location) gregorian {
+	return gregorian{set: true, year: year, month: month, day: day, loc: loc}
+}
+
+//
+// GYear struct
+//
+
+// XSDGYear is a type for representing xsd:gYear in Golang, e.g. "2024". The
+// zero value is unset, and is not marshalled.
+type XSDGYear struct {
+	gYear
+}
+
+type gYear struct {
+	gregorian
+}
+
+// CreateXsdGYear creates an object represent xsd:gYear object in Golang,
+// without timezone if loc is nil
+func CreateXsdGYear(year int, loc *time.Location) XSDGYear {
+	return XSDGYear{gYear{createGregorian(year, 0, 0, loc)}}
+}
+
+// ParseXsdGYear parses the lexical representation of a xsd:gYear
+func ParseXsdGYear(s string) (XSDGYear, error) {
+	var g XSDGYear
+	err := g.UnmarshalText([]byte(s))
+	return g, err
+}
+
+// Year returns the year of the xsd:gYear
+func (g gYear) Year() int {
+	return g.year
+}
+
+// ToGoTime converts the year to the time.Time of its start. If there is a TZ,
+// that TZ is used, otherwise local TZ is used
+func (g gYear) ToGoTime() time.Time {
+	return g.date(g.year)
+}
+
+// String returns the lexical representation of the xsd:gYear
+func (g gYear) String() string {
+	return g.format(gYearFormat)
+}
+
+// MarshalText implements encoding.TextMarshaler on XSDGYear
+func (g gYear) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler on XSDGYear
+func (g *gYear) UnmarshalText(text []byte) error {
+	return g.parse(text, gYearFormat)
+}
+
+// MarshalXML implements xml.Marshaler on XSDGYear
+func (g gYear) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalLexical(e, start, g.String())
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr on XSDGYear
+func (g gYear) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalLexicalAttr(name, g.String()), nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler on XSDGYear
+func (g *gYear) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalLexical(d, start, g)
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr on XSDGYear
+func (g *gYear) UnmarshalXMLAttr(attr xml.Attr) error {
+	return g.UnmarshalText([]byte(attr.Value))
+}
+
+//
+// GYearMonth struct
+//
+
+// XSDGYearMonth is a type for representing xsd:gYearMonth in Golang, e.g.
+// "2024-05". The zero value is unset, and is not marshalled.
+type XSDGYearMonth struct {
+	gYearMonth
+}
+
+type gYearMonth struct {
+	gregorian
+}
+
+// CreateXsdGYearMonth creates an object represent xsd:gYearMonth object in
+// Golang, without timezone if loc is nil
+func CreateXsdGYearMonth(year int, month time.Month, loc *time.Location) XSDGYearMonth {
+	return XSDGYearMonth{gYearMonth{createGregorian(year, month, 0, loc)}}
+}
+
+// ParseXsdGYearMonth parses the lexical representation of a xsd:gYearMonth
+func ParseXsdGYearMonth(s string) (XSDGYearMonth, error) {
+	var g XSDGYearMonth
+	err := g.UnmarshalText([]byte(s))
+	return g, err
+}
+
+// Year returns the year of the xsd:gYearMonth
+func (g gYearMonth) Year() int {
+	return g.year
+}
+
+// Month returns the month of the xsd:gYearMonth
+func (g gYearMonth) Month() time.Month {
+	return g.month
+}
+
+// ToGoTime converts the month to the time.Time of its start. If there is a
+// TZ, that TZ is used, otherwise local TZ is used
+func (g gYearMonth) ToGoTime() time.Time {
+	return g.date(g.year)
+}
+
+// String returns the lexical representation of the xsd:gYearMonth
+func (g gYearMonth) String() string {
+	return g.format(gYearMonthFormat)
+}
+
+// MarshalText implements encoding.TextMarshaler on XSDGYearMonth
+func (g gYearMonth) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler on XSDGYearMonth
+func (g *gYearMonth) UnmarshalText(text []byte) error {
+	return g.parse(text, gYearMonthFormat)
+}
+
+// MarshalXML implements xml.Marshaler on XSDGYearMonth
+func (g gYearMonth) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalLexical(e, start, g.String())
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr on XSDGYearMonth
+func (g gYearMonth) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalLexicalAttr(name, g.String()), nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler on XSDGYearMonth
+func (g *gYearMonth) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalLexical(d, start, g)
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr on XSDGYearMonth
+func (g *gYearMonth) UnmarshalXMLAttr(attr xml.Attr) error {
+	return g.UnmarshalText([]byte(attr.Value))
+}
+
+//
+// GMonth struct
+//
+
+// XSDGMonth is a type for representing xsd:gMonth in Golang, e.g. "--05".
+// The zero value is unset, and is not marshalled.
+type XSDGMonth struct {
+	gMonth
+}
+
+type gMonth struct {
+	gregorian
+}
+
+// CreateXsdGMonth creates an object represent xsd:gMonth object in Golang,
+// without timezone if loc is nil
+func CreateXsdGMonth(month time.Month, loc *time.Location) XSDGMonth {
+	return XSDGMonth{gMonth{createGregorian(0, month, 0, loc)}}
+}
+
+// ParseXsdGMonth parses the lexical representation of a xsd:gMonth
+func ParseXsdGMonth(s string) (XSDGMonth, error) {
+	var g XSDGMonth
+	err := g.UnmarshalText([]byte(s))
+	return g, err
+}
+
+// Month returns the month of the xsd:gMonth
+func (g gMonth) Month() time.Month {
+	return g.month
+}
+
+// String returns the lexical representation of the xsd:gMonth
+func (g gMonth) String() string {
+	return g.format(gMonthFormat)
+}
+
+// MarshalText implements encoding.TextMarshaler on XSDGMonth
+func (g gMonth) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler on XSDGMonth
+func (g *gMonth) UnmarshalText(text []byte) error {
+	return g.parse(text, gMonthFormat)
+}
+
+// MarshalXML implements xml.Marshaler on XSDGMonth
+func (g gMonth) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalLexical(e, start, g.String())
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr on XSDGMonth
+func (g gMonth) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalLexicalAttr(name, g.String()), nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler on XSDGMonth
+func (g *gMonth) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalLexical(d, start, g)
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr on XSDGMonth
+func (g *gMonth) UnmarshalXMLAttr(attr xml.Attr) error {
+	return g.UnmarshalText([]byte(attr.Value))
+}
+
+//
+// GMonthDay struct
+//
+
+// XSDGMonthDay is a type for representing xsd:gMonthDay in Golang, e.g.
+// "--05-17". The zero value is unset, and is not marshalled.
+type XSDGMonthDay struct {
+	gMonthDay
+}
+
+type gMonthDay struct {
+	gregorian
+}
+
+// CreateXsdGMonthDay creates an object represent xsd:gMonthDay object in
+// Golang, without timezone if loc is nil
+func CreateXsdGMonthDay(month time.Month, day int, loc *time.Location) XSDGMonthDay {
+	return XSDGMonthDay{gMonthDay{createGregorian(0, month, day, loc)}}
+}
+
+// ParseXsdGMonthDay parses the lexical representation of a xsd:gMonthDay
+func ParseXsdGMonthDay(s string) (XSDGMonthDay, error) {
+	var g XSDGMonthDay
+	err := g.UnmarshalText([]byte(s))
+	return g, err
+}
+
+// Month returns the month of the xsd:gMonthDay
+func (g gMonthDay) Month() time.Month {
+	return g.month
+}
+
+// Day returns the day of the month of the xsd:gMonthDay
+func (g gMonthDay) Day() int {
+	return g.day
+}
+
+// In returns the time.Time of the start of the day in the given year. If
+// there is a TZ, that TZ is used, otherwise local TZ is used
+func (g gMonthDay) In(year int) time.Time {
+	return g.date(year)
+}
+
+// String returns the lexical representation of the xsd:gMonthDay
+func (g gMonthDay) String() string {
+	return g.format(gMonthDayFormat)
+}
+
+// MarshalText implements encoding.TextMarshaler on XSDGMonthDay
+func (g gMonthDay) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler on XSDGMonthDay
+func (g *gMonthDay) UnmarshalText(text []byte) error {
+	return g.parse(text, gMonthDayFormat)
+}
+
+// MarshalXML implements xml.Marshaler on XSDGMonthDay
+func (g gMonthDay) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalLexical(e, start, g.String())
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr on XSDGMonthDay
+func (g gMonthDay) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalLexicalAttr(name, g.String()), nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler on XSDGMonthDay
+func (g *gMonthDay) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalLexical(d, start, g)
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr on XSDGMonthDay
+func (g *gMonthDay) UnmarshalXMLAttr(attr xml.Attr) error {
+	return g.UnmarshalText([]byte(attr.Value))
+}
+
+//
+// GDay struct
+//
+
+// XSDGDay is a type for representing xsd:gDay in Golang, e.g. "---17". The
+// zero value is unset, and is not marshalled.
+type XSDGDay struct {
+	gDay
+}
+
+type gDay struct {
+	gregorian
+}
+
+// CreateXsdGDay creates an object represent xsd:gDay object in Golang,
+// without timezone if loc is nil
+func CreateXsdGDay(day int, loc *time.Location) XSDGDay {
+	return XSDGDay{gDay{createGregorian(0, 0, day, loc)}}
+}
+
+// ParseXsdGDay parses the lexical representation of a xsd:gDay
+func ParseXsdGDay(s string) (XSDGDay, error) {
+	var g XSDGDay
+	err := g.UnmarshalText([]byte(s))
+	return g, err
+}
+
+// Day returns the day of the month of the xsd:gDay
+func (g gDay) Day() int {
+	return g.day
+}
+
+// String returns the lexical representation of the xsd:gDay
+func (g gDay) String() string {
+	return g.format(gDayFormat)
+}
+
+// MarshalText implements encoding.TextMarshaler on XSDGDay
+func (g gDay) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler on XSDGDay
+func (g *gDay) UnmarshalText(text []byte) error {
+	return g.parse(text, gDayFormat)
+}
+
+// MarshalXML implements xml.Marshaler on XSDGDay
+func (g gDay) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalLexical(e, start, g.String())
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr on XSDGDay
+func (g gDay) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalLexicalAttr(name, g.String()), nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler on XSDGDay
+func (g *gDay) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalLexical(d, start, g)
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr on XSDGDay
+func (g *gDay) UnmarshalXMLAttr(attr xml.Attr) error {
+	return g.UnmarshalText([]byte(attr.Value))
+}
@@ -0,0 +1,51 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// XSDList is a type for representing the built-in list types xsd:NMTOKENS,
+// xsd:IDREFS and xsd:ENTITIES in Golang. Its items are marshalled in a single
+// element or attribute, separated by spaces.
+type XSDList []string
+
+// String returns the lexical representation of the list
+func (l XSDList) String() string {
+	return strings.Join(l, " ")
+}
+
+// MarshalText implements encoding.TextMarshaler on XSDList
+func (l XSDList) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler on XSDList
+func (l *XSDList) UnmarshalText(text []byte) error {
+	*l = strings.Fields(string(text))
+	return nil
+}
+
+// MarshalXML implements xml.Marshaler on XSDList
+func (l XSDList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(l.String(), start)
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr on XSDList
+func (l XSDList) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: l.String()}, nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler on XSDList
+func (l *XSDList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalLexical(d, start, l)
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr on XSDList
+func (l *XSDList) UnmarshalXMLAttr(attr xml.Attr) error {
+	return l.UnmarshalText([]byte(attr.Value))
+}
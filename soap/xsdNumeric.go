@@ -0,0 +1,234 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// decimalPattern is the lexical space of xsd:decimal.
+var decimalPattern = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+
+//
+// Decimal struct
+//
+
+// XSDDecimal is a type for representing xsd:decimal in Golang. It holds the
+// exact lexical representation of the value, e.g. "10.50", rather than a
+// binary approximation. The zero value is unset, and is not marshalled.
+//
+// The methods are those of the embedded type, so that the types declared with
+// XSDDecimal as underlying type, such as the restrictions of xsd:decimal,
+// have them too.
+type XSDDecimal struct {
+	decimal
+}
+
+type decimal struct {
+	lexical string
+}
+
+// ParseXsdDecimal parses the lexical representation of a xsd:decimal
+func ParseXsdDecimal(s string) (XSDDecimal, error) {
+	var d XSDDecimal
+	err := d.UnmarshalText([]byte(s))
+	return d, err
+}
+
+// CreateXsdDecimal creates an object represent xsd:decimal object in Golang,
+// with the given number of digits after the decimal point. The last digit is
+// rounded to the nearest, away from zero.
+func CreateXsdDecimal(r *big.Rat, scale int) XSDDecimal {
+	return XSDDecimal{decimal{lexical: r.FloatString(scale)}}
+}
+
+// String returns the lexical representation of the decimal, or an empty
+// string if it is unset.
+func (d decimal) String() string {
+	return d.lexical
+}
+
+// Rat returns the value of the decimal, which is zero if it is unset.
+func (d decimal) Rat() *big.Rat {
+	r := new(big.Rat)
+	if d.lexical != "" {
+		r.SetString(d.lexical)
+	}
+	return r
+}
+
+// Float64 returns the nearest float64 value of the decimal.
+func (d decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// MarshalText implements encoding.TextMarshaler on XSDDecimal
+func (d decimal) MarshalText() ([]byte, error) {
+	return []byte(d.lexical), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler on XSDDecimal. An empty
+// text leaves the decimal unset.
+func (d *decimal) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s != "" && !decimalPattern.MatchString(s) {
+		return fmt.Errorf("invalid xsd:decimal %q", s)
+	}
+	d.lexical = s
+	return nil
+}
+
+// MarshalXML implements xml.Marshaler on XSDDecimal
+func (d decimal) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalLexical(e, start, d.lexical)
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr on XSDDecimal
+func (d decimal) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalLexicalAttr(name, d.lexical), nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler on XSDDecimal
+func (d *decimal) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	return unmarshalLexical(dec, start, d)
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr on XSDDecimal
+func (d *decimal) UnmarshalXMLAttr(attr xml.Attr) error {
+	return d.UnmarshalText([]byte(attr.Value))
+}
+
+//
+// Integer struct
+//
+
+// XSDInteger is a type for representing xsd:integer, and the types derived
+// from it which are not bounded, in Golang. The zero value is unset, and is
+// not marshalled. Integers are compared with the Cmp method of their BigInt.
+//
+// The methods are those of the embedded type, so that the types declared with
+// XSDInteger as underlying type have them too.
+type XSDInteger struct {
+	integer
+}
+
+type integer struct {
+	value *big.Int
+}
+
+// ParseXsdInteger parses the lexical representation of a xsd:integer
+func ParseXsdInteger(s string) (XSDInteger, error) {
+	var i XSDInteger
+	err := i.UnmarshalText([]byte(s))
+	return i, err
+}
+
+// CreateXsdInteger creates an object represent xsd:integer object in Golang,
+// holding a copy of the given integer.
+func CreateXsdInteger(i *big.Int) XSDInteger {
+	return XSDInteger{integer{value: new(big.Int).Set(i)}}
+}
+
+// String returns the decimal representation of the integer, or an empty
+// string if it is unset.
+func (i integer) String() string {
+	if i.value == nil {
+		return ""
+	}
+	return i.value.String()
+}
+
+// BigInt returns a copy of the value of the integer, which is zero if it is
+// unset.
+func (i integer) BigInt() *big.Int {
+	if i.value == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(i.value)
+}
+
+// Int64 returns the value of the integer, and whether it is within the range
+// of int64.
+func (i integer) Int64() (int64, bool) {
+	if i.value == nil {
+		return 0, true
+	}
+	return i.value.Int64(), i.value.IsInt64()
+}
+
+// MarshalText implements encoding.TextMarshaler on XSDInteger
+func (i integer) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler on XSDInteger. An empty
+// text leaves the integer unset.
+func (i *integer) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s == "" {
+		i.value = nil
+		return nil
+	}
+	value, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("invalid xsd:integer %q", s)
+	}
+	i.value = value
+	return nil
+}
+
+// MarshalXML implements xml.Marshaler on XSDInteger
+func (i integer) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalLexical(e, start, i.String())
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr on XSDInteger
+func (i integer) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalLexicalAttr(name, i.String()), nil
+}
+
+// UnmarshalXML implements xml.Unmarshaler on XSDInteger
+func (i *integer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalLexical(d, start, i)
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr on XSDInteger
+func (i *integer) UnmarshalXMLAttr(attr xml.Attr) error {
+	return i.UnmarshalText([]byte(attr.Value))
+}
+
+// marshalLexical encodes the element of a value of the given lexical
+// representation, skipping unset values, whose representation is empty.
+func marshalLexical(e *xml.Encoder, start xml.StartElement, lexical string) error {
+	if lexical == "" {
+		return nil
+	}
+	return e.EncodeElement(lexical, start)
+}
+
+// marshalLexicalAttr returns the attribute of a value of the given lexical
+// representation, which is empty, and not marshalled, for unset values.
+func marshalLexicalAttr(name xml.Name, lexical string) xml.Attr {
+	if lexical == "" {
+		return xml.Attr{}
+	}
+	return xml.Attr{Name: name, Value: lexical}
+}
+
+// unmarshalLexical decodes the content of an element into a value parsing
+// its lexical representation.
+func unmarshalLexical(d *xml.Decoder, start xml.StartElement, v encoding.TextUnmarshaler) error {
+	var content string
+	if err := d.DecodeElement(&content, &start); err != nil {
+		return err
+	}
+	return v.UnmarshalText([]byte(content))
+}
@@ -0,0 +1,188 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// registeredType is a Go type registered for a schema type or element.
+type registeredType struct {
+	name xml.Name
+	new  func() interface{}
+}
+
+// registry holds the Go types registered for schema types or elements, by the
+// local name of the schema component.
+type registry struct {
+	sync.RWMutex
+	byLocal map[string][]registeredType
+}
+
+var (
+	xsiTypes = &registry{byLocal: make(map[string][]registeredType)}
+	elements = &registry{byLocal: make(map[string][]registeredType)}
+)
+
+func (r *registry) register(name xml.Name, new func() interface{}) {
+	r.Lock()
+	defer r.Unlock()
+	r.byLocal[name.Local] = append(r.byLocal[name.Local], registeredType{name: name, new: new})
+}
+
+// lookup returns a new value of the first type registered for the name which
+// implements the interface t. A name without namespace matches the types of
+// any namespace.
+func (r *registry) lookup(name xml.Name, t reflect.Type) (reflect.Value, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, registered := range r.byLocal[name.Local] {
+		if name.Space != "" && name.Space != registered.name.Space {
+			continue
+		}
+		v := reflect.ValueOf(registered.new())
+		if v.Type().Implements(t) {
+			return v, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// RegisterType registers the Go type generated for the schema type named
+// name, whose values are returned by new. Elements declaring the schema type
+// with their xsi:type attribute are decoded into such values by
+// DecodeXSIType.
+func RegisterType(name xml.Name, new func() interface{}) {
+	xsiTypes.register(name, new)
+}
+
+// DecodeXSIType decodes the element start into the interface pointed to by
+// v. When the element has an xsi:type attribute naming a registered type
+// implementing the interface, a new value of that type replaces the one held
+// by the interface, otherwise the element is decoded into the held value.
+//
+// The prefix of the xsi:type attribute is resolved against the namespaces in
+// the scope of the element, as QNames are. When it is not declared, the type
+// is looked up by its local name.
+func DecodeXSIType(d *xml.Decoder, start xml.StartElement, v interface{}) error {
+	target := reflect.ValueOf(v).Elem()
+	if name, ok := xsiType(d, start); ok {
+		if value, ok := xsiTypes.lookup(name, target.Type()); ok {
+			target.Set(value)
+		}
+	}
+	if target.IsNil() {
+		return fmt.Errorf("no type implementing %s is registered for element %s", target.Type(), start.Name.Local)
+	}
+
+	// the XMLName field of a type also declared by a global element holds the
+	// name of that element, which encoding/xml checks, while the element
+	// declaring the type has the name of its own declaration
+	value := target.Elem()
+	decoded := start
+	if name, ok := typeXMLName(value.Type()); ok {
+		decoded.Name = name
+	}
+	if err := d.DecodeElement(value.Interface(), &decoded); err != nil {
+		return err
+	}
+	if value.Kind() == reflect.Ptr && value.Elem().Kind() == reflect.Struct {
+		if f, ok := value.Elem().Type().FieldByName("XMLName"); ok && len(f.Index) == 1 && f.Type == reflect.TypeOf(xml.Name{}) {
+			value.Elem().Field(f.Index[0]).Set(reflect.ValueOf(start.Name))
+		}
+	}
+	return nil
+}
+
+// typeXMLName returns the element name required by the XMLName field of the
+// struct type t, or of the first struct it embeds declaring one, as
+// encoding/xml looks it up.
+func typeXMLName(t reflect.Type) (xml.Name, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return xml.Name{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Name == "XMLName" && f.Type == reflect.TypeOf(xml.Name{}) {
+			ns, local, _ := parseXMLTag(f.Tag.Get("xml"))
+			return xml.Name{Space: ns, Local: local}, local != ""
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Tag.Get("xml") == "" {
+			if name, ok := typeXMLName(f.Type); ok {
+				return name, true
+			}
+		}
+	}
+	return xml.Name{}, false
+}
+
+// xsiType returns the type name declared by the xsi:type attribute of start.
+func xsiType(d *xml.Decoder, start xml.StartElement) (xml.Name, bool) {
+	for _, attr := range start.Attr {
+		if attr.Name.Local != "type" || attr.Name.Space != XmlNsXsi && attr.Name.Space != "xsi" {
+			continue
+		}
+		prefix, local := "", strings.TrimSpace(attr.Value)
+		if i := strings.Index(local, ":"); i >= 0 {
+			prefix, local = local[:i], local[i+1:]
+		}
+		space, _ := namespaceLookup(d, start)(prefix)
+		return xml.Name{Space: space, Local: local}, true
+	}
+	return xml.Name{}, false
+}
+
+// EncodeXSIType encodes v as the element start, declaring the schema type
+// named name with the xsi:type attribute unless start already declares one.
+func EncodeXSIType(e *xml.Encoder, start xml.StartElement, name xml.Name, v interface{}) error {
+	declared := false
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "xsi:type" || attr.Name.Space == XmlNsXsi && attr.Name.Local == "type" {
+			declared = true
+		}
+	}
+	if !declared {
+		value := name.Local
+		if name.Space != "" {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:tns"}, Value: name.Space})
+			value = "tns:" + name.Local
+		}
+		start.Attr = append(start.Attr,
+			xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: XmlNsXsi},
+			xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: value},
+		)
+	}
+	return e.EncodeElement(v, start)
+}
+
+// EncodeWithXSIType encodes v as the element start, declaring its schema type
+// with the xsi:type attribute when v is an XSITyper, e.g. a value of a derived
+// type held by an interface of its base type.
+func EncodeWithXSIType(e *xml.Encoder, start xml.StartElement, v interface{}) error {
+	if t, ok := v.(XSITyper); ok {
+		return EncodeXSIType(e, start, t.XSIType(), v)
+	}
+	return e.EncodeElement(v, start)
+}
+
+// literalContent marshals a body content implementing xml.Marshaler as the
+// element named by its XMLName field, which encoding/xml only uses for values
+// without a MarshalXML method.
+type literalContent struct {
+	content interface{}
+}
+
+// MarshalXML implements xml.Marshaler on literalContent
+func (c literalContent) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if xmlName, ok := structXMLName(reflect.Indirect(reflect.ValueOf(c.content))); ok {
+		start.Name = xmlName
+	}
+	return e.EncodeElement(c.content, start)
+}
@@ -0,0 +1,189 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/xml"
+	"log"
+	"strconv"
+)
+
+// substitute is an element which may appear in place of the head of its
+// substitution group.
+type substitute struct {
+	Name string
+	Tag  string
+}
+
+// elementSubstitution is the place of a global element in substitution
+// groups.
+type elementSubstitution struct {
+	TypeName string
+	// Interface is the interface implemented by the elements of the
+	// substitution group headed by the element, if any.
+	Interface string
+	// Heads are the Go names of the heads of the substitution groups the
+	// element belongs to, including itself.
+	Heads []string
+	// Name is the name the element is registered with, unless it is
+	// abstract or untyped.
+	Name *xml.Name
+	// Renamed is set when the element is generated as a named type without
+	// XMLName field, which must set the name of the element when marshalled.
+	Renamed bool
+}
+
+// indexSubstitutionGroups indexes the direct members of each substitution
+// group by the symbol of its head element.
+func (st *symbolTable) indexSubstitutionGroups() {
+	st.substitutes = make(map[*symbol][]*symbol)
+	for _, s := range st.elements.all {
+		el := s.decl.(*XSDElement)
+		if el.SubstitutionGroup == "" {
+			continue
+		}
+		head := st.lookupElement(resolveQName(el.SubstitutionGroup, s.schema.Xmlns))
+		if head == nil {
+			log.Printf("[WARN] head element %s of %s is not declared", el.SubstitutionGroup, el.Name)
+			continue
+		}
+		st.substitutes[head] = append(st.substitutes[head], s)
+	}
+}
+
+// substitutable reports whether the element is abstract or the head of a
+// substitution group.
+func (st *symbolTable) substitutable(s *symbol) bool {
+	return s.decl.(*XSDElement).Abstract || len(st.substitutes[s]) > 0
+}
+
+// instantiable reports whether the element is neither abstract nor untyped,
+// and may thus appear in documents.
+func (st *symbolTable) instantiable(s *symbol) bool {
+	el := s.decl.(*XSDElement)
+	return !el.Abstract && (el.Type != "" || el.ComplexType != nil || el.SimpleType != nil)
+}
+
+// substitutionGroup returns the elements which may appear in place of the
+// head element: the head itself unless it is abstract or untyped, and its
+// direct and indirect members.
+func (st *symbolTable) substitutionGroup(head *symbol) []*symbol {
+	var group []*symbol
+	visited := make(map[*symbol]bool)
+	var walk func(*symbol)
+	walk = func(s *symbol) {
+		if visited[s] {
+			return
+		}
+		visited[s] = true
+		if st.instantiable(s) {
+			group = append(group, s)
+		}
+		for _, member := range st.substitutes[s] {
+			walk(member)
+		}
+	}
+	walk(head)
+	return group
+}
+
+// substitutionHeads returns the heads of the substitution groups the element
+// belongs to, including itself if it heads a group.
+func (st *symbolTable) substitutionHeads(s *symbol) []*symbol {
+	var heads []*symbol
+	visited := make(map[*symbol]bool)
+	for s != nil && !visited[s] {
+		visited[s] = true
+		if st.substitutable(s) {
+			heads = append(heads, s)
+		}
+		el := s.decl.(*XSDElement)
+		if el.SubstitutionGroup == "" {
+			break
+		}
+		s = st.lookupElement(resolveQName(el.SubstitutionGroup, s.schema.Xmlns))
+	}
+	return heads
+}
+
+// substitutionField returns the field generated for the element reference if
+// it references the head of a substitution group, or nil.
+func (g *GoWSDL) substitutionField(el *XSDElement) *polymorphicField {
+	if el.Ref == "" {
+		return nil
+	}
+	head := g.symbols.lookupElement(resolveQName(el.Ref, g.currentSchema.Xmlns))
+	if head == nil || !g.symbols.substitutable(head) {
+		return nil
+	}
+
+	qualifier := g.qualifier(head.name.Space, g.currentPackage, g.typeImports)
+	field := &polymorphicField{
+		Name:      g.elementFieldName(el),
+		Interface: qualifier + replaceReservedWords(makePublic(head.goName)) + "Element",
+		Slice:     el.Repeated(),
+		Local:     g.elementXMLName(el.Ref),
+		JSON:      removeNS(el.Ref),
+		Doc:       el.Doc,
+	}
+	names := make(map[string]bool)
+	for _, s := range g.symbols.substitutionGroup(head) {
+		member := &substitute{Name: makePublic(s.goName), Tag: s.name.Local}
+		if s.name.Space != "" {
+			member.Tag = s.name.Space + " " + s.name.Local
+		}
+		name := member.Name
+		for i := 2; names[member.Name]; i++ {
+			member.Name = name + strconv.Itoa(i)
+		}
+		names[member.Name] = true
+		field.Members = append(field.Members, member)
+	}
+	return field
+}
+
+// substitution returns the place in substitution groups of the global
+// element, generated as the type named typeName, or nil if it neither heads
+// nor belongs to a substitution group.
+func (g *GoWSDL) substitution(typeName string, el *XSDElement) *elementSubstitution {
+	s := g.symbols.decls[el]
+	if s == nil {
+		return nil
+	}
+	heads := g.symbols.substitutionHeads(s)
+	if len(heads) == 0 {
+		return nil
+	}
+
+	sub := &elementSubstitution{TypeName: typeName}
+	if heads[0] == s {
+		sub.Interface = typeName + "Element"
+	}
+	// untyped elements have no Go type
+	if el.Type == "" && el.ComplexType == nil && el.SimpleType == nil {
+		return sub
+	}
+	for _, head := range heads {
+		sub.Heads = append(sub.Heads, replaceReservedWords(makePublic(head.goName)))
+	}
+	if el.Abstract {
+		return sub
+	}
+	sub.Name = &s.name
+	switch {
+	case el.Type != "":
+		switch goType := removePointerFromType(g.toGoType(el.Type, el.Nillable)); {
+		case goType == typeName:
+			log.Printf("[WARN] %s has the name of its type and is marshalled with the name of the element it substitutes", el.Name)
+		case delegatesMarshalling(goType):
+			// their MarshalXML method sets the name of the element
+		default:
+			sub.Renamed = !g.marshalsXSIType(el.Type) && !g.marshalsQNames(el.Type)
+		}
+	case el.SimpleType != nil:
+		sub.Renamed = true
+	}
+	return sub
+}
@@ -0,0 +1,314 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/xml"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// builtinNamespaces are the namespaces whose types map to Go built-in types.
+var builtinNamespaces = map[string]bool{
+	xmlschema11:                                 true,
+	"http://www.w3.org/1999/XMLSchema":          true,
+	"http://www.w3.org/2000/10/XMLSchema":       true,
+	"http://schemas.xmlsoap.org/soap/encoding/": true,
+	"http://www.w3.org/2003/05/soap-encoding":   true,
+}
+
+// schemaNamespaces are the namespaces of XML Schema, which declares no other
+// types than the built-in ones.
+var schemaNamespaces = map[string]bool{
+	xmlschema11:                           true,
+	"http://www.w3.org/1999/XMLSchema":    true,
+	"http://www.w3.org/2000/10/XMLSchema": true,
+}
+
+// symbol is a global declaration of a schema.
+type symbol struct {
+	name   xml.Name
+	goName string
+	schema *XSDSchema
+	decl   interface{}
+}
+
+// symbolTable indexes the global types, elements, attributes and groups of
+// all the schemas by qualified name and assigns a unique Go name to each type and
+// element declaration.
+//
+// Same-named declarations from different namespaces are suffixed with the
+// last meaningful segment of their namespace (e.g. AddressBilling and
+// AddressShipping), falling back to a counter when that is not enough.
+type symbolTable struct {
+	types           symbolIndex
+	elements        symbolIndex
+	attributes      symbolIndex
+	groups          symbolIndex
+	attributeGroups symbolIndex
+	decls           map[interface{}]*symbol
+	derived         map[*symbol][]*symbol
+	substitutes     map[*symbol][]*symbol
+}
+
+// symbolIndex holds declarations of the same kind in document order.
+type symbolIndex struct {
+	byName map[xml.Name]*symbol
+	all    []*symbol
+}
+
+func newSymbolTable(schemas []*XSDSchema) *symbolTable {
+	st := &symbolTable{
+		decls: make(map[interface{}]*symbol),
+	}
+
+	for _, schema := range schemas {
+		for _, ct := range schema.ComplexTypes {
+			st.declare(&st.types, schema, ct.Name, ct)
+		}
+		for _, simpleType := range schema.SimpleType {
+			st.declare(&st.types, schema, simpleType.Name, simpleType)
+		}
+		for _, el := range schema.Elements {
+			st.declare(&st.elements, schema, el.Name, el)
+		}
+		for _, attr := range schema.Attributes {
+			st.declare(&st.attributes, schema, attr.Name, attr)
+		}
+		for _, group := range schema.Groups {
+			st.declare(&st.groups, schema, group.Name, group)
+		}
+		for _, attrGroup := range schema.AttributeGroups {
+			st.declare(&st.attributeGroups, schema, attrGroup.Name, attrGroup)
+		}
+	}
+
+	st.assignGoNames(func(string) string { return "" })
+
+	return st
+}
+
+// declare adds a declaration to the table. Redeclarations of the same
+// qualified name are kept for code generation, but references resolve to the
+// first declaration.
+func (st *symbolTable) declare(index *symbolIndex, schema *XSDSchema, name string, decl interface{}) {
+	s := &symbol{
+		name:   xml.Name{Space: schema.TargetNamespace, Local: name},
+		goName: name,
+		schema: schema,
+		decl:   decl,
+	}
+	if index.byName == nil {
+		index.byName = make(map[xml.Name]*symbol)
+	}
+	if _, ok := index.byName[s.name]; ok {
+		log.Printf("[WARN] %s is declared more than once in namespace %q", name, s.name.Space)
+	} else {
+		index.byName[s.name] = s
+	}
+	index.all = append(index.all, s)
+	st.decls[decl] = s
+}
+
+// assignGoNames makes the Go names of the types and elements unique within
+// the Go package of their namespace, given by pkg.
+func (st *symbolTable) assignGoNames(pkg func(ns string) string) {
+	assignGoNames(st.types.all, pkg)
+	assignGoNames(st.elements.all, pkg)
+}
+
+func assignGoNames(symbols []*symbol, pkg func(ns string) string) {
+	groups := make(map[xml.Name][]*symbol)
+	used := make(map[xml.Name]bool)
+	for _, s := range symbols {
+		key := xml.Name{Space: pkg(s.name.Space), Local: s.name.Local}
+		groups[key] = append(groups[key], s)
+		used[key] = true
+		s.goName = s.name.Local
+	}
+
+	for _, s := range symbols {
+		p := pkg(s.name.Space)
+		group := groups[xml.Name{Space: p, Local: s.name.Local}]
+		if len(group) < 2 || s != group[0] {
+			continue
+		}
+
+		namespaces := make(map[string]bool)
+		for _, s := range group {
+			namespaces[s.name.Space] = true
+		}
+
+		for i, s := range group {
+			base := s.name.Local
+			if len(namespaces) > 1 {
+				base += namespaceSuffix(s.name.Space)
+			}
+			// the first declaration keeps its name unless it is suffixed
+			if i == 0 && base == s.name.Local {
+				continue
+			}
+
+			goName := base
+			for n := 2; used[xml.Name{Space: p, Local: goName}]; n++ {
+				goName = base + strconv.Itoa(n)
+			}
+			used[xml.Name{Space: p, Local: goName}] = true
+			s.goName = goName
+		}
+	}
+}
+
+var versionSegment = regexp.MustCompile(`^[vV]?[0-9][0-9._-]*$`)
+
+// namespaceSuffix derives an identifier from the last meaningful segment of a
+// namespace, e.g. "Orders" for "http://example.com/orders/v2".
+func namespaceSuffix(ns string) string {
+	segments := strings.FieldsFunc(ns, func(r rune) bool {
+		return r == '/' || r == ':' || r == '#'
+	})
+	for i := len(segments) - 1; i >= 0; i-- {
+		segment := segments[i]
+		if versionSegment.MatchString(segment) {
+			continue
+		}
+		switch strings.ToLower(segment) {
+		case "http", "https", "urn":
+			continue
+		}
+
+		var suffix string
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			suffix += makePublic(word)
+		}
+		if suffix != "" {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// resolveQName resolves a prefixed name using the given namespace
+// declarations. It reports false when the prefix is not declared, or when an
+// unprefixed name has no default namespace, in which case lookups fall back
+// to the local name.
+func resolveQName(name string, xmlns map[string]string) (xml.Name, bool) {
+	prefix, local := splitQName(name)
+	ns, ok := xmlns[prefix]
+	return xml.Name{Space: ns, Local: local}, ok
+}
+
+// splitQName splits a prefixed name into its prefix and local name.
+func splitQName(name string) (prefix, local string) {
+	if i := strings.Index(name, ":"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// lookup finds the symbol with the given qualified name. Names that could not
+// be resolved, or that are not declared in the resolved namespace, are
+// matched by local name, preferring case sensitive matches.
+func (index *symbolIndex) lookup(name xml.Name, resolved bool) *symbol {
+	if resolved {
+		if s, ok := index.byName[name]; ok {
+			return s
+		}
+		if builtinNamespaces[name.Space] {
+			return nil
+		}
+	}
+
+	var found *symbol
+	for _, s := range index.all {
+		if s.name.Local == name.Local {
+			return s
+		}
+		if found == nil && strings.EqualFold(s.name.Local, name.Local) {
+			found = s
+		}
+	}
+	return found
+}
+
+func (st *symbolTable) lookupType(name xml.Name, resolved bool) *symbol {
+	return st.types.lookup(name, resolved)
+}
+
+func (st *symbolTable) lookupElement(name xml.Name, resolved bool) *symbol {
+	return st.elements.lookup(name, resolved)
+}
+
+func (st *symbolTable) lookupAttribute(name xml.Name, resolved bool) *symbol {
+	return st.attributes.lookup(name, resolved)
+}
+
+func (st *symbolTable) lookupGroup(name xml.Name, resolved bool) *symbol {
+	return st.groups.lookup(name, resolved)
+}
+
+func (st *symbolTable) lookupAttributeGroup(name xml.Name, resolved bool) *symbol {
+	return st.attributeGroups.lookup(name, resolved)
+}
+
+// goName returns the Go name of a type or element declaration.
+func (st *symbolTable) goName(decl interface{}) string {
+	if s, ok := st.decls[decl]; ok {
+		return s.goName
+	}
+
+	switch d := decl.(type) {
+	case *XSDComplexType:
+		return d.Name
+	case *XSDSimpleType:
+		return d.Name
+	case *XSDElement:
+		return d.Name
+	}
+	return ""
+}
+
+// prefixFor returns a prefix bound to ns in the schema, declaring a new one
+// if needed, so that names copied from other schemas can be resolved in it.
+func (s *XSDSchema) prefixFor(ns string) string {
+	for prefix, namespace := range s.Xmlns {
+		if namespace == ns && prefix != "" {
+			return prefix
+		}
+	}
+
+	if s.Xmlns == nil {
+		s.Xmlns = make(map[string]string)
+	}
+	for i := 0; ; i++ {
+		prefix := "ns" + strconv.Itoa(i)
+		if _, ok := s.Xmlns[prefix]; !ok {
+			s.Xmlns[prefix] = ns
+			return prefix
+		}
+	}
+}
+
+// localizeQName rewrites a qualified name resolved with the xmlns namespace
+// declarations, so that it resolves to the same namespace in the schema.
+func localizeQName(name string, xmlns map[string]string, schema *XSDSchema) string {
+	qname, resolved := resolveQName(name, xmlns)
+	if name == "" || !resolved {
+		return name
+	}
+	prefix, _ := splitQName(name)
+	if ns, ok := schema.Xmlns[prefix]; ok && ns == qname.Space {
+		return name
+	}
+	if qname.Space == "" && schema.Xmlns[""] == "" {
+		return qname.Local
+	}
+	return schema.prefixFor(qname.Space) + ":" + qname.Local
+}
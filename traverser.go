@@ -2,8 +2,7 @@ package gowsdl
 
 import (
 	"encoding/xml"
-
-	"fmt"
+	"log"
 	"strings"
 )
 
@@ -15,22 +14,22 @@ const (
 )
 
 type traverser struct {
-	c   *XSDSchema
-	all []*XSDSchema
-	tm  traverseMode
-	resolveCollisions map[string]string	
+	c       *XSDSchema
+	all     []*XSDSchema
+	tm      traverseMode
+	symbols *symbolTable
 	// fields used by findNameByType mode
-	typeName             string
+	typeName             xml.Name
 	foundElmName         string
 	conflictingTypeUsage bool
 }
 
-func newTraverser(c *XSDSchema, all []*XSDSchema, resolveCollisions map[string]string) *traverser {
+func newTraverser(c *XSDSchema, all []*XSDSchema, symbols *symbolTable) *traverser {
 	return &traverser{
-		c:   c,
-		all: all,
-		tm:  refResolution, // default traverse mode is refResolution
-		resolveCollisions: resolveCollisions,		
+		c:       c,
+		all:     all,
+		tm:      refResolution, // default traverse mode is refResolution
+		symbols: symbols,
 	}
 }
 
@@ -54,11 +53,16 @@ func (t *traverser) traverse() {
 // If multiple elements with different names of the given type are found,
 // the original type name is returned instead.
 // If no elements are found, the original type name is returned instead.
-func (t *traverser) findNameByType(name string) string {
-	t.initFindNameByType(name)
+func (t *traverser) findNameByType(ct *XSDComplexType) string {
+	t.initFindNameByType(ct)
 
 	// Search for elements of given type
 	for _, schema := range t.all {
+		// RPC part accessors are not named after their types
+		if schema.synthesized {
+			continue
+		}
+		t.c = schema
 		for _, elm := range schema.Elements {
 			t.traverseElement(elm)
 		}
@@ -77,13 +81,16 @@ func (t *traverser) findNameByType(name string) string {
 
 	// Return original type name
 	// No element found or conflicting element names found
-	return t.typeName
+	return t.typeName.Local
 }
 
-func (t *traverser) initFindNameByType(name string) {
+func (t *traverser) initFindNameByType(ct *XSDComplexType) {
 	// Initialize fields for processing
 	t.tm = findNameByType
-	t.typeName = stripns(name)
+	t.typeName = xml.Name{Local: ct.Name}
+	if s, ok := t.symbols.decls[ct]; ok {
+		t.typeName = s.name
+	}
 	t.foundElmName = ""
 	t.conflictingTypeUsage = false
 }
@@ -103,14 +110,6 @@ func (t *traverser) traverseElement(elm *XSDElement) {
 	if elm.SimpleType != nil {
 		t.traverseSimpleType(elm.SimpleType)
 	}
-	if t.resolveCollisions != nil {
-		ref := t.qname(elm.Type)
-
-		if updated, ok := t.resolveCollisions[fmt.Sprintf("%s/%s", ref.Space, ref.Local)]; ok {
-			elm.Type = updated
-		}
-	}
-
 }
 
 func (t *traverser) findElmName(elm *XSDElement) {
@@ -124,7 +123,10 @@ func (t *traverser) findElmName(elm *XSDElement) {
 		return
 	}
 
-	if stripns(elm.Type) == t.typeName {
+	if elm.Type == "" {
+		return
+	}
+	if s := t.symbols.lookupType(resolveQName(elm.Type, t.c.Xmlns)); s != nil && s.name == t.typeName {
 		if len(t.foundElmName) == 0 {
 			// First time usage t.typeName
 			t.foundElmName = elm.Name
@@ -139,16 +141,18 @@ func (t *traverser) traverseSimpleType(st *XSDSimpleType) {
 }
 
 func (t *traverser) traverseComplexType(ct *XSDComplexType) {
-	t.traverseElements(ct.Sequence)
-	t.traverseElements(ct.Choice)
-	t.traverseElements(ct.SequenceChoice)
-	t.traverseElements(ct.All)
+	if t.tm == refResolution {
+		t.expandGroups(ct)
+	}
+
+	t.traverseElements(ct.Elements())
 	t.traverseAttributes(ct.Attributes)
 	t.traverseAttributes(ct.ComplexContent.Extension.Attributes)
-	t.traverseElements(ct.ComplexContent.Extension.Sequence)
-	t.traverseElements(ct.ComplexContent.Extension.Choice)
-	t.traverseElements(ct.ComplexContent.Extension.SequenceChoice)
+	t.traverseElements(ct.ComplexContent.Extension.Elements())
 	t.traverseAttributes(ct.SimpleContent.Extension.Attributes)
+	t.traverseElements(ct.ComplexContent.Restriction.Elements())
+	t.traverseAttributes(ct.ComplexContent.Restriction.Attributes)
+	t.traverseAttributes(ct.SimpleContent.Restriction.Attributes)
 }
 
 func (t *traverser) traverseAttributes(attrs []*XSDAttribute) {
@@ -164,14 +168,29 @@ func (t *traverser) traverseAttribute(attr *XSDAttribute) {
 	}
 
 	if attr.Ref != "" {
-		refAttr := t.getGlobalAttribute(attr.Ref)
-		if refAttr != nil && refAttr.Ref == "" {
+		name, resolved := resolveQName(attr.Ref, t.c.Xmlns)
+		ref := t.symbols.lookupAttribute(name, resolved)
+		if ref == nil {
+			// e.g. xml:lang, which is not described by any schema
+			if attr.Name == "" {
+				attr.Name = name.Local
+				attr.namespace = name.Space
+			}
+			return
+		}
+
+		refAttr := ref.decl.(*XSDAttribute)
+		if refAttr.Ref == "" {
 			t.traverseAttribute(refAttr)
 			attr.Name = refAttr.Name
-			attr.Type = refAttr.Type
+			attr.namespace = ref.name.Space
+			attr.Type = t.localize(refAttr.Type, ref.schema)
 			if attr.Fixed == "" {
 				attr.Fixed = refAttr.Fixed
 			}
+			if attr.Default == "" {
+				attr.Default = refAttr.Default
+			}
 		}
 	} else if attr.Type == "" {
 		if attr.SimpleType != nil {
@@ -181,36 +200,248 @@ func (t *traverser) traverseAttribute(attr *XSDAttribute) {
 	}
 }
 
-func (t *traverser) getGlobalAttribute(name string) *XSDAttribute {
-	ref := t.qname(name)
+// localize rewrites a qualified name declared in another schema, so that it
+// resolves to the same namespace in the schema being traversed.
+func (t *traverser) localize(name string, from *XSDSchema) string {
+	if from == t.c {
+		return name
+	}
+	return localizeQName(name, from.Xmlns, t.c)
+}
+
+// expandGroups resolves the group references of the model groups of a
+// complex type, and replaces its attribute group references by the
+// attributes of the referenced groups.
+func (t *traverser) expandGroups(ct *XSDComplexType) {
+	t.resolveGroups(ct.ModelGroup(), nil)
+	t.resolveGroups(ct.ComplexContent.Extension.ModelGroup(), nil)
+	t.resolveGroups(ct.ComplexContent.Restriction.ModelGroup(), nil)
 
-	for _, schema := range t.all {
-		if schema.TargetNamespace == ref.Space {
-			for _, attr := range schema.Attributes {
-				if attr.Name == ref.Local {
-					return attr
-				}
-			}
+	ct.Attributes = append(ct.Attributes, t.attributeGroupAttributes(ct.AttributeGroups, t.c, nil)...)
+	ct.AttributeGroups = nil
+
+	for _, ext := range []*XSDExtension{&ct.ComplexContent.Extension, &ct.SimpleContent.Extension} {
+		ext.Attributes = append(ext.Attributes, t.attributeGroupAttributes(ext.AttributeGroups, t.c, nil)...)
+		ext.AttributeGroups = nil
+	}
+
+	restriction := &ct.ComplexContent.Restriction
+	restriction.Attributes = append(restriction.Attributes, t.attributeGroupAttributes(restriction.AttributeGroups, t.c, nil)...)
+	restriction.AttributeGroups = nil
+
+	simpleRestriction := &ct.SimpleContent.Restriction
+	simpleRestriction.Attributes = append(simpleRestriction.Attributes, t.attributeGroupAttributes(simpleRestriction.AttributeGroups, t.c, nil)...)
+	simpleRestriction.AttributeGroups = nil
+}
+
+// resolveGroups resolves the group references of a model group and of its
+// nested model groups.
+func (t *traverser) resolveGroups(m *XSDModelGroup, visiting map[*XSDGroup]bool) {
+	if m == nil {
+		return
+	}
+
+	for _, p := range m.Particles {
+		switch {
+		case p.ModelGroup != nil:
+			t.resolveGroups(p.ModelGroup, visiting)
+		case p.Group != nil && p.Group.resolved == nil:
+			t.resolveGroup(p.Group, visiting)
+		}
+	}
+}
+
+// resolveGroup sets the model group of the group referenced by ref.
+func (t *traverser) resolveGroup(ref *XSDGroup, visiting map[*XSDGroup]bool) {
+	s := t.symbols.lookupGroup(resolveQName(ref.Ref, t.c.Xmlns))
+	if s == nil {
+		log.Printf("[WARN] group %s is not declared", ref.Ref)
+		return
+	}
+
+	group := s.decl.(*XSDGroup)
+	if visiting[group] {
+		log.Printf("[WARN] group %s references itself", ref.Ref)
+		return
+	}
+	if visiting == nil {
+		visiting = make(map[*XSDGroup]bool)
+	}
+	visiting[group] = true
+	defer delete(visiting, group)
+
+	model := t.localizeModelGroup(group.ModelGroup(), s.schema)
+	t.resolveGroups(model, visiting)
+	ref.resolved = model
+}
+
+// attributeGroupAttributes returns the attributes of the attribute groups
+// referenced from schema, including the ones of nested references.
+func (t *traverser) attributeGroupAttributes(refs []*XSDAttributeGroup, schema *XSDSchema, visiting map[*XSDAttributeGroup]bool) []*XSDAttribute {
+	var attrs []*XSDAttribute
+	for _, ref := range refs {
+		s := t.symbols.lookupAttributeGroup(resolveQName(ref.Ref, schema.Xmlns))
+		if s == nil {
+			log.Printf("[WARN] attribute group %s is not declared", ref.Ref)
+			continue
+		}
+
+		attrGroup := s.decl.(*XSDAttributeGroup)
+		if visiting[attrGroup] {
+			log.Printf("[WARN] attribute group %s references itself", ref.Ref)
+			continue
 		}
+		if visiting == nil {
+			visiting = make(map[*XSDAttributeGroup]bool)
+		}
+		visiting[attrGroup] = true
+
+		attrs = append(attrs, t.localizeAttributes(attrGroup.Attributes, s.schema)...)
+		attrs = append(attrs, t.attributeGroupAttributes(attrGroup.AttributeGroups, s.schema, visiting)...)
+
+		delete(visiting, attrGroup)
+	}
+	return attrs
+}
+
+// localizeElements returns copies of elements declared in another schema,
+// whose qualified names resolve in the schema being traversed.
+func (t *traverser) localizeElements(elements []*XSDElement, from *XSDSchema) []*XSDElement {
+	if from == t.c || elements == nil {
+		return elements
 	}
 
-	return nil
+	localized := make([]*XSDElement, len(elements))
+	for i, el := range elements {
+		c := *el
+		c.Type = t.localize(el.Type, from)
+		c.Ref = t.localize(el.Ref, from)
+		if el.ComplexType != nil {
+			c.ComplexType = t.localizeComplexType(el.ComplexType, from)
+		}
+		if el.SimpleType != nil {
+			c.SimpleType = t.localizeSimpleType(el.SimpleType, from)
+		}
+		localized[i] = &c
+	}
+	return localized
+}
+
+func (t *traverser) localizeComplexType(ct *XSDComplexType, from *XSDSchema) *XSDComplexType {
+	c := *ct
+	c.XSDContentModel = t.localizeContentModel(ct.XSDContentModel, from)
+	c.Attributes = t.localizeAttributes(ct.Attributes, from)
+	c.AttributeGroups = t.localizeAttributeGroups(ct.AttributeGroups, from)
+	c.ComplexContent.Extension = t.localizeExtension(ct.ComplexContent.Extension, from)
+	c.ComplexContent.Restriction.XSDContentModel = t.localizeContentModel(ct.ComplexContent.Restriction.XSDContentModel, from)
+	c.ComplexContent.Restriction.Base = t.localize(ct.ComplexContent.Restriction.Base, from)
+	c.ComplexContent.Restriction.Attributes = t.localizeAttributes(ct.ComplexContent.Restriction.Attributes, from)
+	c.ComplexContent.Restriction.AttributeGroups = t.localizeAttributeGroups(ct.ComplexContent.Restriction.AttributeGroups, from)
+	c.SimpleContent.Extension = t.localizeExtension(ct.SimpleContent.Extension, from)
+	c.SimpleContent.Restriction.Base = t.localize(ct.SimpleContent.Restriction.Base, from)
+	c.SimpleContent.Restriction.Attributes = t.localizeAttributes(ct.SimpleContent.Restriction.Attributes, from)
+	c.SimpleContent.Restriction.AttributeGroups = t.localizeAttributeGroups(ct.SimpleContent.Restriction.AttributeGroups, from)
+	if st := ct.SimpleContent.Restriction.SimpleType; st != nil {
+		c.SimpleContent.Restriction.SimpleType = t.localizeSimpleType(st, from)
+	}
+	return &c
+}
+
+func (t *traverser) localizeExtension(ext XSDExtension, from *XSDSchema) XSDExtension {
+	ext.XSDContentModel = t.localizeContentModel(ext.XSDContentModel, from)
+	ext.Base = t.localize(ext.Base, from)
+	ext.Attributes = t.localizeAttributes(ext.Attributes, from)
+	ext.AttributeGroups = t.localizeAttributeGroups(ext.AttributeGroups, from)
+	return ext
+}
+
+func (t *traverser) localizeContentModel(c XSDContentModel, from *XSDSchema) XSDContentModel {
+	c.Sequence = t.localizeModelGroup(c.Sequence, from)
+	c.Choice = t.localizeModelGroup(c.Choice, from)
+	c.All = t.localizeModelGroup(c.All, from)
+	c.Group = t.localizeGroup(c.Group, from)
+	return c
 }
 
-// qname resolves QName into xml.Name.
-func (t *traverser) qname(name string) (qname xml.Name) {
-	x := strings.SplitN(name, ":", 2)
-	if len(x) == 1 {
-		qname.Local = x[0]
-	} else {
-		qname.Local = x[1]
-		qname.Space = x[0]
-		if t.c != nil {
-			if ns, ok := t.c.Xmlns[qname.Space]; ok {
-				qname.Space = ns		
-			}		
+// localizeModelGroup returns a copy of a model group declared in another
+// schema, whose qualified names resolve in the schema being traversed.
+func (t *traverser) localizeModelGroup(m *XSDModelGroup, from *XSDSchema) *XSDModelGroup {
+	if from == t.c || m == nil {
+		return m
+	}
+
+	c := *m
+	c.Particles = make([]*XSDParticle, len(m.Particles))
+	for i, p := range m.Particles {
+		lp := *p
+		if p.Element != nil {
+			lp.Element = t.localizeElements([]*XSDElement{p.Element}, from)[0]
 		}
+		lp.Group = t.localizeGroup(p.Group, from)
+		lp.ModelGroup = t.localizeModelGroup(p.ModelGroup, from)
+		c.Particles[i] = &lp
 	}
+	return &c
+}
+
+func (t *traverser) localizeGroup(ref *XSDGroup, from *XSDSchema) *XSDGroup {
+	if from == t.c || ref == nil {
+		return ref
+	}
+
+	c := *ref
+	c.Ref = t.localize(ref.Ref, from)
+	c.resolved = nil
+	return &c
+}
+
+func (t *traverser) localizeSimpleType(st *XSDSimpleType, from *XSDSchema) *XSDSimpleType {
+	c := *st
+	c.Restriction.Base = t.localize(st.Restriction.Base, from)
+	c.List.ItemType = t.localize(st.List.ItemType, from)
+	if st.List.SimpleType != nil {
+		c.List.SimpleType = t.localizeSimpleType(st.List.SimpleType, from)
+	}
+	var members []string
+	for _, member := range strings.Fields(st.Union.MemberTypes) {
+		members = append(members, t.localize(member, from))
+	}
+	c.Union.MemberTypes = strings.Join(members, " ")
+	c.Union.SimpleType = nil
+	for _, member := range st.Union.SimpleType {
+		c.Union.SimpleType = append(c.Union.SimpleType, t.localizeSimpleType(member, from))
+	}
+	return &c
+}
 
-	return qname
+func (t *traverser) localizeAttributes(attrs []*XSDAttribute, from *XSDSchema) []*XSDAttribute {
+	if from == t.c || attrs == nil {
+		return attrs
+	}
+
+	localized := make([]*XSDAttribute, len(attrs))
+	for i, attr := range attrs {
+		c := *attr
+		c.Type = t.localize(attr.Type, from)
+		c.Ref = t.localize(attr.Ref, from)
+		if attr.SimpleType != nil {
+			c.SimpleType = t.localizeSimpleType(attr.SimpleType, from)
+		}
+		localized[i] = &c
+	}
+	return localized
+}
+
+func (t *traverser) localizeAttributeGroups(attrGroups []*XSDAttributeGroup, from *XSDSchema) []*XSDAttributeGroup {
+	if from == t.c || attrGroups == nil {
+		return attrGroups
+	}
+
+	localized := make([]*XSDAttributeGroup, len(attrGroups))
+	for i, attrGroup := range attrGroups {
+		c := *attrGroup
+		c.Ref = t.localize(attrGroup.Ref, from)
+		localized[i] = &c
+	}
+	return localized
 }
@@ -0,0 +1,158 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// TypeMapping is the Go type generated for the values of an XML Schema type
+// in place of the one derived from its declaration.
+type TypeMapping struct {
+	// Type is the Go type, qualified by the name of the package imported
+	// from Import, e.g. "decimal.Decimal" or "*soap.Binary".
+	Type   string `json:"type"`
+	Import string `json:"import,omitempty"`
+	// Adapter is an optional type of the same package declared with Type as
+	// underlying type, e.g. "XMLAmount" for "type XMLAmount Amount", whose
+	// methods marshal the values of Type which do not marshal themselves as
+	// required by the schema. Fields are then declared with the adapter type,
+	// and converted to Type with Type(field).
+	Adapter string `json:"adapter,omitempty"`
+}
+
+// headerImports are the packages always imported by the generated files.
+var headerImports = map[string]bool{
+	"encoding/xml":                  true,
+	"time":                          true,
+	"github.com/ilmich/gowsdl/soap": true,
+}
+
+// SetTypeMapping maps XML Schema types, built-in or declared by the schemas,
+// to the given Go types. Declarations of mapped types are not generated.
+// Built-in types are named in the XML Schema namespace, e.g.
+// {http://www.w3.org/2001/XMLSchema}decimal, which also maps them in the
+// legacy XML Schema and SOAP encoding namespaces.
+func (g *GoWSDL) SetTypeMapping(mapping map[xml.Name]TypeMapping) {
+	g.typeMapping = mapping
+	g.mappedPackages = make(map[string]*nsPackage)
+}
+
+// ParseTypeMapping parses a type mapping, as given to SetTypeMapping, from
+// a JSON object whose keys are qualified names of types in the
+// {namespace}local notation and whose values are type mappings, e.g.
+//
+//	{"{http://www.w3.org/2001/XMLSchema}decimal": {"type": "decimal.Decimal", "import": "github.com/shopspring/decimal"}}
+//
+// Keys without a namespace name types of the XML Schema namespace.
+func ParseTypeMapping(data []byte) (map[xml.Name]TypeMapping, error) {
+	var entries map[string]TypeMapping
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	mapping := make(map[xml.Name]TypeMapping)
+	for key, m := range entries {
+		name := xml.Name{Space: xmlschema11, Local: key}
+		if strings.HasPrefix(key, "{") {
+			i := strings.Index(key, "}")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid type name %q", key)
+			}
+			name = xml.Name{Space: key[1:i], Local: key[i+1:]}
+		}
+		if name.Local == "" {
+			return nil, fmt.Errorf("invalid type name %q", key)
+		}
+		if m.Type == "" {
+			return nil, fmt.Errorf("no Go type for %s", key)
+		}
+		mapping[name] = m
+	}
+	return mapping, nil
+}
+
+// mappedType returns the mapping of a type reference of the current schema.
+func (g *GoWSDL) mappedType(xsdType string) (TypeMapping, bool) {
+	if len(g.typeMapping) == 0 {
+		return TypeMapping{}, false
+	}
+	name, resolved := resolveQName(xsdType, g.currentSchema.Xmlns)
+	if !resolved || builtinNamespaces[name.Space] {
+		name.Space = xmlschema11
+	}
+	m, ok := g.typeMapping[name]
+	return m, ok
+}
+
+// isMappedType reports whether a type reference of the current schema is
+// mapped.
+func (g *GoWSDL) isMappedType(xsdType string) bool {
+	_, ok := g.mappedType(xsdType)
+	return ok
+}
+
+// restrictsMappedType reports whether the simple type, declared by the
+// current schema, restricts a mapped type. It is generated as an alias of
+// the mapped type, keeping its methods, and has no methods of its own.
+func (g *GoWSDL) restrictsMappedType(st *XSDSimpleType) bool {
+	return st.List.ItemType == "" && st.Union.MemberTypes == "" && st.Union.SimpleType == nil &&
+		st.Restriction.Base != "" && g.isMappedType(st.Restriction.Base)
+}
+
+// mapsType reports whether the type declared by the current schema with the
+// given name is mapped, and not generated.
+func (g *GoWSDL) mapsType(name string) bool {
+	_, ok := g.typeMapping[xml.Name{Space: g.currentSchema.TargetNamespace, Local: name}]
+	return ok
+}
+
+// mappedGoType returns the Go type of the fields of a mapped type, recording
+// the import of its package.
+func (g *GoWSDL) mappedGoType(m TypeMapping) string {
+	goType := m.Type
+	if m.Adapter != "" {
+		goType = m.Adapter
+		if !strings.Contains(goType, ".") {
+			if i := strings.LastIndex(m.Type, "."); i >= 0 {
+				goType = strings.TrimLeft(m.Type[:i+1], "*[]") + goType
+			}
+		}
+	}
+
+	if m.Import != "" && !headerImports[m.Import] && g.typeImports != nil {
+		name := strings.TrimLeft(goType, "*[]")
+		if i := strings.Index(name, "."); i >= 0 {
+			p := g.mappedPackages[m.Import]
+			if p == nil {
+				p = &nsPackage{name: name[:i], importPath: m.Import}
+				g.mappedPackages[m.Import] = p
+			}
+			g.typeImports.add(p)
+		}
+	}
+	return goType
+}
+
+// removeMappedTypes removes the mapped types from the symbol table, so that
+// references to them are not resolved to their declarations.
+func (st *symbolTable) removeMappedTypes(mapping map[xml.Name]TypeMapping) {
+	if len(mapping) == 0 {
+		return
+	}
+	var all []*symbol
+	for _, s := range st.types.all {
+		if _, ok := mapping[s.name]; ok {
+			delete(st.types.byName, s.name)
+			delete(st.decls, s.decl)
+			continue
+		}
+		all = append(all, s)
+	}
+	st.types.all = all
+}
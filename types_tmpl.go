@@ -6,7 +6,7 @@ package gowsdl
 
 var typesTmpl = `
 {{define "SimpleType"}}
-	{{$typeName := replaceReservedWords .Name | makePublic}}
+	{{$typeName := goTypeName . | replaceReservedWords | makePublic}}
 	{{if .Doc}} {{.Doc | comment}} {{end}}
 	{{if ne .List.ItemType ""}}
 		type {{$typeName}} []{{toGoType .List.ItemType false | removePointerFromType}}
@@ -14,43 +14,63 @@ var typesTmpl = `
 		type {{$typeName}} string
 	{{else if .Union.SimpleType}}
 		type {{$typeName}} string
+	{{else if restrictsMappedType .}}
+		type {{$typeName}} = {{toGoType .Restriction.Base false | removePointerFromType}}
 	{{else if .Restriction.Base}}
-		type {{$typeName}} {{toGoType .Restriction.Base false | removePointerFromType}}
+		{{$type := toGoType .Restriction.Base false | removePointerFromType}}
+		type {{$typeName}} {{$type}}
+		{{template "Delegation" delegation $typeName $type}}
     {{else}}
 		type {{$typeName}} interface{}
 	{{end}}
 
-	{{if .Restriction.Enumeration}}
-	const (
-		{{with .Restriction}}
-			{{range .Enumeration}}
-				{{if .Doc}} {{.Doc | comment}} {{end}}
-				{{$typeName}}{{$value := replaceReservedWords .Value}}{{$value | makePublic}} {{$typeName}} = "{{goString .Value}}" {{end}}
-		{{end}}
-	)
+	{{template "Enumeration" simpleTypeEnumeration $typeName .}}
+	{{template "Validate" simpleTypeValidation $typeName .}}
+{{end}}
+
+{{define "Delegation"}}
+	{{with .}}
+		func (t {{.TypeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+			return {{.Type}}(t).MarshalXML(e, start)
+		}
+
+		func (t {{.TypeName}}) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+			return {{.Type}}(t).MarshalXMLAttr(name)
+		}
+
+		func (t *{{.TypeName}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+			return (*{{.Type}})(t).UnmarshalXML(d, start)
+		}
+
+		func (t *{{.TypeName}}) UnmarshalXMLAttr(attr xml.Attr) error {
+			return (*{{.Type}})(t).UnmarshalXMLAttr(attr)
+		}
 	{{end}}
 {{end}}
 
 {{define "ComplexContent"}}
+	{{template "ExtensionBase" .}}
+	{{template "Elements" .Extension.Elements}}
+	{{template "Attributes" .Extension.Attributes}}
+{{end}}
+
+{{define "ExtensionBase"}}
 	{{$baseType := toGoType .Extension.Base false}}
 	{{ if $baseType }}
 		{{$baseType}}
 	{{end}}
-
-	{{template "Elements" .Extension.Sequence}}
-	{{template "Elements" .Extension.Choice}}
-	{{template "Elements" .Extension.SequenceChoice}}
-	{{template "Attributes" .Extension.Attributes}}
 {{end}}
 
 {{define "Attributes"}}
     {{ $targetNamespace := getNS }}
 	{{range .}}
 		{{if .Doc}} {{.Doc | comment}} {{end}}
-		{{ if ne .Type "" }}
-			{{ normalize .Name | makeFieldPublic}} {{toGoType .Type false}} ` + "`" + `xml:"{{.Name}},attr,omitempty" json:"{{.Name}},omitempty"` + "`" + `
+		{{ if ne .Fixed "" }}
+			{{ normalize .Name | makeFieldPublic}} {{fixedAttributeType .}} ` + "`" + `xml:"{{attributeXMLName .}},attr" json:"{{.Name}},omitempty"` + "`" + `
+		{{ else if ne .Type "" }}
+			{{ normalize .Name | makeFieldPublic}} {{toGoType .Type false}} ` + "`" + `xml:"{{attributeXMLName .}},attr,omitempty" json:"{{.Name}},omitempty"` + "`" + `
 		{{ else }}
-			{{ normalize .Name | makeFieldPublic}} string ` + "`" + `xml:"{{.Name}},attr,omitempty" json:"{{.Name}},omitempty"` + "`" + `
+			{{ normalize .Name | makeFieldPublic}} string ` + "`" + `xml:"{{attributeXMLName .}},attr,omitempty" json:"{{.Name}},omitempty"` + "`" + `
 		{{ end }}
 	{{end}}
 {{end}}
@@ -60,81 +80,749 @@ var typesTmpl = `
 	{{template "Attributes" .Extension.Attributes}}
 {{end}}
 
+{{define "SimpleContentRestriction"}}
+	Value {{simpleContentType .}} ` + "`xml:\",chardata\" json:\"-,\"`" + `
+	{{template "Attributes" contentAttributes .}}
+{{end}}
+
+{{define "Enumeration"}}
+	{{with .}}
+		{{$enum := .}}
+		{{if .Parse}}
+			{{$type := .GoType}}
+			{{if .Typed}}{{$type = .TypeName}}{{end}}
+			var (
+				{{range .Values}}
+					{{if .Doc}} {{.Doc | comment}} {{end}}
+					{{.Name}} = func() {{$type}} { v, _ := soap.{{$enum.Parse}}({{.Literal}}); return {{if $enum.Typed}}{{$type}}(v){{else}}v{{end}} }()
+				{{end}}
+			)
+		{{else}}
+			const (
+				{{range .Values}}
+					{{if .Doc}} {{.Doc | comment}} {{end}}
+					{{.Name}} {{if $enum.Typed}}{{$enum.TypeName}} {{end}}= {{.Literal}}
+				{{end}}
+			)
+		{{end}}
+	{{end}}
+	{{if and . .Methods .Parse}}
+		{{template "ExactEnumeration" .}}
+	{{else if and . .Methods}}
+		// Values returns the values enumerated by the schema of {{.TypeName}}.
+		func ({{.TypeName}}) Values() []{{.TypeName}} {
+			return []{{.TypeName}}{ {{- range $i, $name := .Distinct}}{{if $i}}, {{end}}{{$name}}{{end}}}
+		}
+
+		// IsValid reports whether t is one of the values enumerated by the
+		// schema of {{.TypeName}}.
+		func (t {{.TypeName}}) IsValid() bool {
+			for _, v := range t.Values() {
+				if t == v {
+					return true
+				}
+			}
+			return false
+		}
+
+		// String implements fmt.Stringer.
+		func (t {{.TypeName}}) String() string {
+			{{- if eq .Kind "string"}}
+				return string(t)
+			{{- else}}
+				return soap.FormatNumber(t)
+			{{- end}}
+		}
+
+		{{if .Strict}}
+			// MarshalText implements encoding.TextMarshaler, rejecting the values
+			// which are not enumerated by the schema of {{.TypeName}}.
+		{{- else}}
+			// MarshalText implements encoding.TextMarshaler.
+		{{- end}}
+		func (t {{.TypeName}}) MarshalText() ([]byte, error) {
+			{{- if .Strict}}
+				if !t.IsValid() {
+					return nil, &soap.EnumerationError{Type: "{{.TypeName}}", Value: t.String()}
+				}
+			{{- end}}
+			return []byte(t.String()), nil
+		}
+
+		{{if .Strict}}
+			// UnmarshalText implements encoding.TextUnmarshaler, rejecting the
+			// values which are not enumerated by the schema of {{.TypeName}}.
+		{{- else}}
+			// UnmarshalText implements encoding.TextUnmarshaler.
+		{{- end}}
+		func (t *{{.TypeName}}) UnmarshalText(text []byte) error {
+			{{- if eq .Kind "string"}}
+				v := {{.TypeName}}(text)
+			{{- else}}
+				var v {{.TypeName}}
+				if err := soap.ParseNumber(text, &v); err != nil {
+					return err
+				}
+			{{- end}}
+			{{- if .Strict}}
+				if !v.IsValid() {
+					return &soap.EnumerationError{Type: "{{.TypeName}}", Value: string(text)}
+				}
+			{{- end}}
+			*t = v
+			return nil
+		}
+	{{end}}
+{{end}}
+
+{{define "ExactEnumeration"}}
+	// Values returns the values enumerated by the schema of {{.TypeName}}.
+	func ({{.TypeName}}) Values() []{{.TypeName}} {
+		return []{{.TypeName}}{ {{- range $i, $name := .Distinct}}{{if $i}}, {{end}}{{$name}}{{end}}}
+	}
+
+	// IsValid reports whether the lexical representation of t is the one of
+	// a value enumerated by the schema of {{.TypeName}}.
+	func (t {{.TypeName}}) IsValid() bool {
+		for _, v := range t.Values() {
+			if t.String() == v.String() {
+				return true
+			}
+		}
+		return false
+	}
+	{{if .Strict}}
+
+		// MarshalText implements encoding.TextMarshaler, rejecting the values
+		// which are not enumerated by the schema of {{.TypeName}}. Unset values
+		// are not marshalled, and are accepted.
+		func (t {{.TypeName}}) MarshalText() ([]byte, error) {
+			if t.String() != "" && !t.IsValid() {
+				return nil, &soap.EnumerationError{Type: "{{.TypeName}}", Value: t.String()}
+			}
+			return []byte(t.String()), nil
+		}
+
+		// UnmarshalText implements encoding.TextUnmarshaler, rejecting the
+		// values which are not enumerated by the schema of {{.TypeName}}.
+		func (t *{{.TypeName}}) UnmarshalText(text []byte) error {
+			v, err := soap.{{.Parse}}(string(text))
+			if err != nil {
+				return err
+			}
+			if v.String() != "" && !{{.TypeName}}(v).IsValid() {
+				return &soap.EnumerationError{Type: "{{.TypeName}}", Value: string(text)}
+			}
+			*t = {{.TypeName}}(v)
+			return nil
+		}
+
+		// MarshalXML implements xml.Marshaler, rejecting the values which are
+		// not enumerated by the schema of {{.TypeName}}.
+		func (t {{.TypeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+			if _, err := t.MarshalText(); err != nil {
+				return err
+			}
+			return {{.GoType}}(t).MarshalXML(e, start)
+		}
+
+		// MarshalXMLAttr implements xml.MarshalerAttr, rejecting the values
+		// which are not enumerated by the schema of {{.TypeName}}.
+		func (t {{.TypeName}}) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+			if _, err := t.MarshalText(); err != nil {
+				return xml.Attr{}, err
+			}
+			return {{.GoType}}(t).MarshalXMLAttr(name)
+		}
+
+		// UnmarshalXML implements xml.Unmarshaler, rejecting the values which
+		// are not enumerated by the schema of {{.TypeName}}.
+		func (t *{{.TypeName}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+			var text string
+			if err := d.DecodeElement(&text, &start); err != nil {
+				return err
+			}
+			return t.UnmarshalText([]byte(text))
+		}
+
+		// UnmarshalXMLAttr implements xml.UnmarshalerAttr, rejecting the values
+		// which are not enumerated by the schema of {{.TypeName}}.
+		func (t *{{.TypeName}}) UnmarshalXMLAttr(attr xml.Attr) error {
+			return t.UnmarshalText([]byte(attr.Value))
+		}
+	{{end}}
+{{end}}
+
 {{define "ComplexTypeInline"}}
-	{{replaceReservedWords .Name | makePublic}} {{if eq .MaxOccurs "unbounded"}}[]{{end}}struct {
+	{{replaceReservedWords .Name | makePublic}} {{if .Repeated}}[]{{end}}struct {
 	{{with .ComplexType}}
 		{{if ne .ComplexContent.Extension.Base ""}}
 			{{template "ComplexContent" .ComplexContent}}
+			{{template "HiddenMarshaler" .}}
 		{{else if ne .SimpleContent.Extension.Base ""}}
 			{{template "SimpleContent" .SimpleContent}}
+		{{else if ne .SimpleContent.Restriction.Base ""}}
+			{{template "SimpleContentRestriction" .}}
+		{{else if ne .ComplexContent.Restriction.Base ""}}
+			{{template "Elements" .ComplexContent.Restriction.Elements}}
+			{{template "Attributes" contentAttributes .}}
 		{{else}}
-			{{template "Elements" .Sequence}}
-			{{template "Elements" .Choice}}
-			{{template "Elements" .SequenceChoice}}
-			{{template "Elements" .All}}
+			{{template "Elements" .Elements}}
 			{{template "Attributes" .Attributes}}
 		{{end}}
 	{{end}}
 	} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + `
 {{end}}
 
+{{define "Fields"}}
+	{{range .}}
+		{{if .Choice}}
+			{{.Choice.Field}} {{if .Choice.Repeated}}[]{{end}}{{.Choice.Interface}} ` + "`" + `xml:",any" json:"-"` + "`" + `
+		{{else if .Polymorphic}}
+			{{with .Polymorphic}}
+				{{if .Doc}}{{.Doc | comment}} {{end}}
+				{{.Name}} {{if .Slice}}[]{{end}}{{.Interface}} ` + "`" + `xml:"{{.Local}},omitempty" json:"{{.JSON}},omitempty"` + "`" + `
+			{{end}}
+		{{else}}
+			{{template "Element" .Element}}
+		{{end}}
+	{{end}}
+{{end}}
+
 {{define "Elements"}}
 	{{range .}}
-		{{if ne .Ref ""}}
-			{{removeNS .Ref | replaceReservedWords  | makePublic}} {{if eq .MaxOccurs "unbounded"}}[]{{end}}{{toGoType .Ref .Nillable }} ` + "`" + `xml:"{{.Ref | removeNS}},omitempty" json:"{{.Ref | removeNS}},omitempty"` + "`" + `
+		{{template "Element" .}}
+	{{end}}
+{{end}}
+
+{{define "Element"}}
+	{{if ne .Ref ""}}
+		{{removeNS .Ref | replaceReservedWords  | makePublic}} {{elementFieldType .}} ` + "`" + `xml:"{{elementXMLName .Ref}}{{if not rpcWrapper}},omitempty{{end}}" json:"{{.Ref | removeNS}},omitempty"` + "`" + `
+	{{else}}
+	{{if not .Type}}
+		{{if .SimpleType}}
+			{{if .Doc}} {{.Doc | comment}} {{end}}
+			{{ normalize .Name | makeFieldPublic}} {{elementFieldType .}} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + `
 		{{else}}
-		{{if not .Type}}
-			{{if .SimpleType}}
-				{{if .Doc}} {{.Doc | comment}} {{end}}
-				{{if ne .SimpleType.List.ItemType ""}}
-					{{ normalize .Name | makeFieldPublic}} []{{toGoType .SimpleType.List.ItemType false}} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + `
-				{{else}}
-					{{ normalize .Name | makeFieldPublic}} {{toGoType .SimpleType.Restriction.Base false}} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + `
-				{{end}}
+			{{template "ComplexTypeInline" .}}
+		{{end}}
+	{{else}}
+		{{if .Doc}}{{.Doc | comment}} {{end}}
+		{{replaceAttrReservedWords .Name | makeFieldPublic}} {{elementFieldType .}} ` + "`" + `xml:"{{.Name}}{{if not rpcWrapper}},omitempty{{end}}" json:"{{.Name}},omitempty"` + "`" + ` {{end}}
+	{{end}}
+{{end}}
+
+{{define "Choices"}}
+	{{$typeName := .TypeName}}
+	{{range .Choices}}
+		{{$interface := .Interface}}
+		// {{$interface}} is one of the alternatives of a choice of {{$typeName}}:
+		// {{range $i, $alt := .Alternatives}}{{if $i}}, {{end}}{{$alt.Wrapper}}{{end}}.
+		type {{$interface}} interface {
+			is{{$interface}}()
+		}
+
+		{{range .Alternatives}}
+			{{$alt := .}}
+			{{if .Doc}} {{.Doc | comment}} {{end}}
+			type {{.Wrapper}} struct {
+				{{- if .Any}}
+					XMLName xml.Name
+				{{- end}}
+				Value {{.GoType}}
+			}
+
+			func ({{.Wrapper}}) is{{$interface}}() {}
+
+			{{if .Any}}
+				// MarshalXML implements xml.Marshaler, emitting an element named
+				// after XMLName.
+				func (c {{.Wrapper}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+					return e.EncodeElement(c.Value, xml.StartElement{Name: c.XMLName})
+				}
+			{{else if .Polymorphic}}
+				// MarshalXML implements xml.Marshaler, emitting the {{.Local}} element
+				// declaring the xsi:type of its value.
+				func (c {{.Wrapper}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+					start = xml.StartElement{Name: xml.Name{ {{- if .Space}}Space: "{{.Space}}", {{end}}Local: "{{.Local}}"}}
+					{{- if .Slice}}
+						for _, v := range c.Value {
+							if err := soap.EncodeWithXSIType(e, start, v); err != nil {
+								return err
+							}
+						}
+						return nil
+					{{- else}}
+						return soap.EncodeWithXSIType(e, start, c.Value)
+					{{- end}}
+				}
+
+				// UnmarshalXML implements xml.Unmarshaler, decoding the {{.Local}}
+				// element into the type named by its xsi:type attribute.
+				func (c *{{.Wrapper}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+					{{- if .Slice}}
+						var v {{.Interface}}{{with .Default}} = new({{.}}){{end}}
+						if err := soap.DecodeXSIType(d, start, &v); err != nil {
+							return err
+						}
+						c.Value = append(c.Value, v)
+						return nil
+					{{- else}}
+						{{- with .Default}}
+							c.Value = new({{.}})
+						{{- end}}
+						return soap.DecodeXSIType(d, start, &c.Value)
+					{{- end}}
+				}
 			{{else}}
-				{{template "ComplexTypeInline" .}}
+				// MarshalXML implements xml.Marshaler, emitting the {{.Local}} element.
+				func (c {{.Wrapper}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+					return e.EncodeElement(c.Value, xml.StartElement{Name: xml.Name{ {{- if .Space}}Space: "{{.Space}}", {{end}}Local: "{{.Local}}"}})
+				}
 			{{end}}
-		{{else}}
-			{{if .Doc}}{{.Doc | comment}} {{end}}
-			{{replaceAttrReservedWords .Name | makeFieldPublic}} {{if eq .MaxOccurs "unbounded"}}[]{{end}}{{toGoType .Type .Nillable }} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + ` {{end}}
+			{{with .Checks}}
+				// Validate checks the value of the {{$alt.Local}} element.
+				func (c {{$alt.Wrapper}}) Validate() error {
+					v := new(soap.Validation)
+					{{- template "Checks" .}}
+					return v.Err()
+				}
+			{{end}}
+		{{end}}
+	{{end}}
+
+	{{if .Decoded}}
+		{{if .Repeats}}
+			// UnmarshalXML implements xml.Unmarshaler, keeping the alternatives
+			// of the repeated choices of {{$typeName}} in document order and
+			// accepting a single alternative of the other ones.
+		{{- else if .Chooses}}
+			// UnmarshalXML implements xml.Unmarshaler, accepting a single
+			// alternative of each choice of {{$typeName}}.
+		{{- else}}
+			// UnmarshalXML implements xml.Unmarshaler for {{$typeName}}.
+		{{- end}}
+		{{- if .Polymorphic}}
+			// The elements of abstract or extended types are decoded into the
+			// types named by their xsi:type attribute.
+		{{- end}}
+		{{- if .Substitutes}}
+			// The members of substitution groups are decoded into the types of
+			// their elements.
+		{{- end}}
+		func (t *{{$typeName}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+			type plain {{$typeName}}
+			{{- range .Decoded}}
+				{{- if or .Repeated .Polymorphic}}
+					var {{.Var}} []{{.Interface}}
+				{{- end}}
+			{{- end}}
+			v := struct {
+				{{- if .Items}}
+					Items []string ` + "`" + `xml:",any"` + "`" + `
+				{{- else if .Skips}}
+					// skips the elements which are not alternatives of the choices
+					Skipped []struct{} ` + "`" + `xml:",any"` + "`" + `
+				{{- end}}
+				{{- range .Decoded}}
+					{{- range .Captures}}
+						{{.Field}} {{.Type}} ` + "`" + `xml:"{{.Tag}}"` + "`" + `
+					{{- end}}
+				{{- end}}
+				*plain
+				{{if .HidesBase}}
+					// hides the UnmarshalXML method of the embedded base type
+					UnmarshalXML struct{} ` + "`" + `xml:"-"` + "`" + `
+				{{end}}
+			}{plain: (*plain)(t)}
+			{{- range .Decoded}}
+				{{- if .Polymorphic}}
+					{{- $target := .}}
+					{{- range .Captures}}
+						{{.Value}} = func(d *xml.Decoder, start xml.StartElement) error {
+							var c {{$target.Interface}}{{with $target.Default}} = new({{.}}){{end}}
+							if err := {{$target.Decode}}(d, start, &c); err != nil {
+								return err
+							}
+							{{$target.Var}} = append({{$target.Var}}, c)
+							return nil
+						}
+					{{- end}}
+				{{- else if .Repeated}}
+					{{- $var := .Var}}
+					{{- range .Captures}}
+						{{.Value}} = func(d *xml.Decoder, start xml.StartElement) error {
+							{{- if .Any}}
+								c := {{.Wrapper}}{XMLName: start.Name}
+							{{- else}}
+								var c {{.Wrapper}}
+							{{- end}}
+							if err := d.DecodeElement(&c{{if not .Polymorphic}}.Value{{end}}, &start); err != nil {
+								return err
+							}
+							{{$var}} = append({{$var}}, c)
+							return nil
+						}
+					{{- end}}
+				{{- end}}
+			{{- end}}
+			{{- if .QNames}}
+				if err := soap.DecodeQNameAttrs(d, &v, &start); err != nil {
+					return err
+				}
+			{{- else}}
+				if err := d.DecodeElement(&v, &start); err != nil {
+					return err
+				}
+			{{- end}}
+
+			{{- with .Items}}
+				if v.Items != nil {
+					{{- template "EmbeddedBases" .Inits}}
+					{{.Path}} = v.Items
+				}
+			{{- end}}
+			{{- range .Decoded}}
+				{{$var := .Var}}
+				{{- if and .Polymorphic (not .Repeated)}}
+					if len({{$var}}) > 0 {
+						{{- template "EmbeddedBases" .Inits}}
+						{{.Path}} = {{$var}}[len({{$var}})-1]
+					}
+				{{- else if .Repeated}}
+					if len({{$var}}) > 0 {
+						{{- template "EmbeddedBases" .Inits}}
+						{{.Path}} = {{$var}}
+					}
+				{{- else}}
+					var {{$var}} []{{.Interface}}
+					{{- range .Captures}}
+						if {{.Present}} {
+							{{$var}} = append({{$var}}, {{.Wrapper}}{Value: {{.Value}}})
+						}
+					{{- end}}
+					if len({{$var}}) > 1 {
+						return xml.UnmarshalError("more than one of {{.Names}} in {{$typeName}}")
+					}
+					if len({{$var}}) == 1 {
+						{{- template "EmbeddedBases" .Inits}}
+						{{.Path}} = {{$var}}[0]
+					}
+				{{- end}}
+			{{- end}}
+
+			return nil
+		}
+	{{end}}
+{{end}}
+
+{{define "EmbeddedBases"}}
+	{{- range .}}
+		if {{.Path}} == nil {
+			{{.Path}} = new({{.Type}})
+		}
+	{{- end}}
+{{- end}}
+
+{{define "HiddenMarshaler"}}
+	{{/* anonymous structs have no methods, they hide the ones of their base with fields */}}
+	{{with typeHierarchy "" .}}
+		{{if and .HidesBase (not usesSOAPEncoding)}}
+			// hides the MarshalXML method of the embedded base type
+			MarshalXML struct{} ` + "`" + `xml:"-" json:"-"` + "`" + `
+		{{end}}
+		{{if .HidesDecoder}}
+			// hides the UnmarshalXML method of the embedded base type
+			UnmarshalXML struct{} ` + "`" + `xml:"-" json:"-"` + "`" + `
 		{{end}}
 	{{end}}
 {{end}}
 
-{{define "Any"}}
+{{define "QNameMethods"}}
+	{{with .}}
+		{{$typeName := .TypeName}}
+		{{if .Marshal}}
+			// MarshalXML implements xml.Marshaler, declaring the namespaces of the
+			// QName attributes of {{$typeName}}.
+			func (t {{$typeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+				type plain {{$typeName}}
+				{{- template "ElementName" .}}
+				soap.DeclareQNameAttrs(&start, t)
+				return e.EncodeElement(plain(t), start)
+			}
+		{{end}}
+		{{if .Unmarshal}}
+			// UnmarshalXML implements xml.Unmarshaler, resolving the QName
+			// attributes of {{$typeName}} against the namespaces in scope.
+			func (t *{{$typeName}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+				type plain {{$typeName}}
+				{{- if .HidesBase}}
+					return soap.DecodeQNameAttrs(d, &struct {
+						// hides the UnmarshalXML method of the embedded base type,
+						// declared first as encoding/xml looks the XMLName field of
+						// plain up at its index in plain
+						UnmarshalXML struct{} ` + "`" + `xml:"-"` + "`" + `
+						*plain
+					}{plain: (*plain)(t)}, &start)
+				{{- else}}
+					return soap.DecodeQNameAttrs(d, (*plain)(t), &start)
+				{{- end}}
+			}
+		{{end}}
+	{{end}}
+{{end}}
+
+{{define "TypeHierarchy"}}
+	{{with .}}
+		{{$typeName := .TypeName}}
+		{{with .Interface}}
+			// {{.}} is implemented by {{$typeName}} and the types derived from it.
+			type {{.}} interface {
+				Get{{$typeName}}() *{{$typeName}}
+			}
+
+			// Get{{$typeName}} returns t, or the {{$typeName}} embedded by a derived type.
+			func (t *{{$typeName}}) Get{{$typeName}}() *{{$typeName}} {
+				return t
+			}
+		{{end}}
+		{{if and .Derived (not usesSOAPEncoding)}}
+			{{if .XSIType}}
+				func (t *{{$typeName}}) XSIType() xml.Name {
+					return xml.Name{Space: "{{.XSIType.Space}}", Local: "{{.XSIType.Local}}"}
+				}
+
+				// MarshalXML implements xml.Marshaler, declaring the xsi:type of {{$typeName}}.
+				func (t {{$typeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+					type plain {{$typeName}}
+					{{- if .QNames}}
+						soap.DeclareQNameAttrs(&start, t)
+					{{- end}}
+					return soap.EncodeXSIType(e, start, t.XSIType(), struct {
+						*plain
+						{{- if .HidesBase}}
+							// hides the MarshalXML method of the embedded base type
+							MarshalXML struct{} ` + "`" + `xml:"-"` + "`" + `
+						{{- end}}
+					}{plain: (*plain)(&t)})
+				}
+			{{end}}
+		{{end}}
+		{{if .Registered}}
+			func init() {
+				soap.RegisterType(xml.Name{Space: "{{.XSIType.Space}}", Local: "{{.XSIType.Local}}"}, func() interface{} {
+					return new({{$typeName}})
+				})
+			}
+		{{end}}
+	{{end}}
+{{end}}
+
+{{define "Constructor"}}
+	{{with .}}
+		// New{{.TypeName}} returns a new {{.TypeName}} holding the default and
+		// fixed values declared by its schema.
+		func New{{.TypeName}}() *{{.TypeName}} {
+			t := new({{.TypeName}})
+			{{- with .Base}}
+				t.{{.}} = {{$.BaseNew}}()
+			{{- end}}
+			{{- range .Values}}
+				soap.SetDefault({{.Ref}}, {{printf "%q" .Value}})
+			{{- end}}
+			return t
+		}
+	{{end}}
+{{end}}
+
+{{define "FixedAttributes"}}
 	{{range .}}
+		// {{.TypeName}} is the type of the {{.Name}} attributes, always
+		// marshalled with the value {{printf "%q" .Value}} fixed by the schema.
+		type {{.TypeName}} string
+
+		// MarshalXMLAttr implements xml.MarshalerAttr, emitting the fixed value.
+		func ({{.TypeName}}) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+			return xml.Attr{Name: name, Value: {{printf "%q" .Value}}}, nil
+		}
+	{{end}}
+{{end}}
+
+{{define "ElementName"}}
+	{{- with .}}
+		{{- with .Name}}
+			start.Name = xml.Name{Space: "{{.Space}}", Local: "{{.Local}}"}
+		{{- end}}
+	{{- end}}
+{{- end}}
+
+{{define "Substitution"}}
+	{{with .}}
+		{{$typeName := .TypeName}}
+		{{with .Interface}}
+			// {{.}} is implemented by the members of the substitution group of {{$typeName}}.
+			type {{.}} interface {
+				Substitutes{{$typeName}}()
+			}
+		{{end}}
+		{{range .Heads}}
+			func ({{$typeName}}) Substitutes{{.}}() {}
+		{{end}}
+		{{if .Renamed}}
+			// MarshalXML implements xml.Marshaler, emitting the {{.Name.Local}} element.
+			func (t {{$typeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+				type plain {{$typeName}}
+				{{- template "ElementName" .}}
+				return e.EncodeElement(plain(t), start)
+			}
+		{{end}}
+		{{with .Name}}
+			func init() {
+				soap.RegisterElement(xml.Name{Space: "{{.Space}}", Local: "{{.Local}}"}, func() interface{} {
+					return new({{$typeName}})
+				})
+			}
+		{{end}}
+	{{end}}
+{{end}}
+
+{{define "Validate"}}
+	{{with .}}
+		// Validate checks {{.TypeName}} against the constraints of its schema type,
+		// returning a *soap.ValidationError listing every violation.
+		func (t {{if .Pointer}}*{{end}}{{.TypeName}}) Validate() error {
+			{{- if .Checks}}
+				v := new(soap.Validation)
+				{{- template "Checks" .Checks}}
+				return v.Err()
+			{{- else}}
+				return nil
+			{{- end}}
+		}
+	{{end}}
+{{end}}
+
+{{define "Checks"}}
+	{{- range .}}
+		{{- $check := .}}
+		{{- if .Occurs}}
+			v.Occurs("{{.Name}}", len({{.Field}}), {{.Occurs.Min}}, {{.Occurs.Max}})
+		{{- else if .Required}}
+			v.Required("{{.Name}}", {{.Field}})
+		{{- end}}
+		{{- with .Facets}}
+			{{- if $check.Slice}}
+				for i := range {{$check.Field}} {
+					v.Facets(soap.Index("{{$check.Name}}", i), {{$check.Field}}[i], {{template "Facets" .}})
+				}
+			{{- else}}
+				v.Facets("{{$check.Name}}", {{$check.Field}}, {{template "Facets" .}})
+			{{- end}}
+		{{- end}}
+		{{- if .Validates}}
+			v.Validate("{{.Name}}", {{.Ref}})
+		{{- end}}
+		{{- with .Inline}}
+			{{- if $check.Slice}}
+				for i := range {{$check.Field}} {
+					t, v := &{{$check.Field}}[i], v.At(soap.Index("{{$check.Name}}", i))
+					{{- template "Checks" .Checks}}
+				}
+			{{- else}}
+				{
+					t, v := &{{$check.Field}}, v.At("{{$check.Name}}")
+					{{- template "Checks" .Checks}}
+				}
+			{{- end}}
+		{{- end}}
+	{{- end}}
+{{- end}}
+
+{{define "Facets" -}}
+	soap.Facets{
+		{{- with .Enumeration}}Enumeration: []string{ {{- range $i, $value := .}}{{if $i}}, {{end}}{{printf "%q" $value}}{{end}}}, {{end}}
+		{{- with .Pattern}}Pattern: {{printf "%q" .}}, {{end}}
+		{{- with .MinInclusive}}MinInclusive: {{printf "%q" .}}, {{end}}
+		{{- with .MaxInclusive}}MaxInclusive: {{printf "%q" .}}, {{end}}
+		{{- with .Length}}Length: {{printf "%q" .}}, {{end}}
+		{{- with .MinLength}}MinLength: {{printf "%q" .}}, {{end}}
+		{{- with .MaxLength}}MaxLength: {{printf "%q" .}}, {{end -}}
+	}
+{{- end}}
+
+{{define "Any"}}
+	{{if .}}
 		Items     []string ` + "`" + `xml:",any" json:"items,omitempty"` + "`" + `
 	{{end}}
 {{end}}
 
 {{range .Schemas}}
-	{{ $targetNamespace := setNS .TargetNamespace }}
+	{{ $targetNamespace := setSchema . }}
 
 	{{range .SimpleType}}
-		{{template "SimpleType" .}}
+		{{if not (mapsType .Name)}}
+			{{template "SimpleType" .}}
+		{{end}}
 	{{end}}
 
 	{{range .Elements}}
 		{{$name := .Name}}
-		{{$typeName := replaceReservedWords $name | makePublic}}
+		{{$typeName := goTypeName . | replaceReservedWords | makePublic}}
+		{{$substitution := substitution $typeName .}}
 		{{if not .Type}}
 			{{/* ComplexTypeLocal */}}
 			{{with .ComplexType}}
+				{{$content := structContent $typeName .}}
 				type {{$typeName}} struct {
 					XMLName xml.Name ` + "`xml:\"{{$targetNamespace}} {{$name}}\"`" + `
 					{{if ne .ComplexContent.Extension.Base ""}}
-						{{template "ComplexContent" .ComplexContent}}
+						{{template "ExtensionBase" .ComplexContent}}
+						{{template "Fields" $content.Fields}}
+						{{template "Attributes" .ComplexContent.Extension.Attributes}}
 					{{else if ne .SimpleContent.Extension.Base ""}}
 						{{template "SimpleContent" .SimpleContent}}
+					{{else if ne .SimpleContent.Restriction.Base ""}}
+						{{template "SimpleContentRestriction" .}}
 					{{else}}
-						{{template "Elements" .Sequence}}
-						{{template "Any" .Any}}
-						{{template "Elements" .Choice}}
-						{{template "Elements" .SequenceChoice}}
-						{{template "Elements" .All}}
-						{{template "Attributes" .Attributes}}
+						{{template "Fields" $content.Fields}}
+						{{template "Any" $content.Wildcards}}
+						{{template "Attributes" contentAttributes .}}
 					{{end}}
 				}
+
+				{{with typeHierarchy "" .}}
+					{{if and .HidesBase (not usesSOAPEncoding)}}
+						// MarshalXML implements xml.Marshaler, marshalling {{$typeName}} rather
+						// than the base type it embeds.
+						func (t {{$typeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+							type plain {{$typeName}}
+							start.Name = xml.Name{Space: "{{$targetNamespace}}", Local: "{{$name}}"}
+							{{- if .QNames}}
+								soap.DeclareQNameAttrs(&start, t)
+							{{- end}}
+							return e.EncodeElement(struct {
+								*plain
+								// hides the MarshalXML method of the embedded base type
+								MarshalXML struct{} ` + "`" + `xml:"-"` + "`" + `
+							}{plain: (*plain)(&t)}, start)
+						}
+					{{end}}
+				{{end}}
+				{{if rpcWrapper}}
+					// MarshalXML implements xml.Marshaler, qualifying {{$typeName}} by a
+					// prefix for its part accessors to be unqualified.
+					func (t {{$typeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+						type plain {{$typeName}}
+						start.Name = xml.Name{Space: "{{$targetNamespace}}", Local: "{{$name}}"}
+						soap.PrefixRPCWrapper(&start)
+						return e.EncodeElement(plain(t), start)
+					}
+				{{end}}
+				{{template "QNameMethods" qnameMethods $typeName $name $content .}}
+				{{template "Enumeration" $content.Enumeration}}
+				{{template "Choices" $content}}
+				{{template "Validate" structValidation $content .}}
+				{{template "Constructor" structConstructor $content .}}
 			{{end}}
 			{{/* SimpleTypeLocal */}}
 			{{with .SimpleType}}
@@ -145,28 +833,28 @@ var typesTmpl = `
 					type {{$typeName}} string
 				{{else if .Union.SimpleType}}
 					type {{$typeName}} string
+				{{else if restrictsMappedType .}}
+					type {{$typeName}} = {{toGoType .Restriction.Base false | removePointerFromType}}
 				{{else if .Restriction.Base}}
-					type {{$typeName}} {{toGoType .Restriction.Base false | removePointerFromType}}
+					{{$type := toGoType .Restriction.Base false | removePointerFromType}}
+					type {{$typeName}} {{$type}}
+					{{template "Delegation" delegation $typeName $type}}
 				{{else}}
 					type {{$typeName}} interface{}
 				{{end}}
 			
-				{{if .Restriction.Enumeration}}
-				const (
-					{{with .Restriction}}
-						{{range .Enumeration}}
-							{{if .Doc}} {{.Doc | comment}} {{end}}
-							{{$typeName}}{{$value := replaceReservedWords .Value}}{{$value | makePublic}} {{$typeName}} = "{{goString .Value}}" {{end}}
-					{{end}}
-				)
-				{{end}}
+				{{template "Enumeration" simpleTypeEnumeration $typeName .}}
+				{{template "Validate" simpleTypeValidation $typeName .}}
 			{{end}}
+		{{else if isMappedType .Type}}
+			type {{$typeName}} = {{toGoType .Type false | removePointerFromType}}
 		{{else}}
 			{{$type := toGoType .Type .Nillable | removePointerFromType}}
 			{{if ne ($typeName) ($type)}}
 				type {{$typeName}} {{$type}}
 				{{if eq ($type) ("soap.XSDDateTime")}}
 					func (xdt {{$typeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+						{{- template "ElementName" $substitution}}
 						return soap.XSDDateTime(xdt).MarshalXML(e, start)
 					}
 
@@ -175,6 +863,7 @@ var typesTmpl = `
 					}
 				{{else if eq ($type) ("soap.XSDDate")}}
 					func (xd {{$typeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+						{{- template "ElementName" $substitution}}
 						return soap.XSDDate(xd).MarshalXML(e, start)
 					}
 
@@ -183,42 +872,123 @@ var typesTmpl = `
 					}
 				{{else if eq ($type) ("soap.XSDTime")}}
 					func (xt {{$typeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+						{{- template "ElementName" $substitution}}
 						return soap.XSDTime(xt).MarshalXML(e, start)
 					}
 
 					func (xt *{{$typeName}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 						return (*soap.XSDTime)(xt).UnmarshalXML(d, start)
 					}
+				{{else if delegatesMarshalling $type}}
+					func (t {{$typeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+						{{- template "ElementName" $substitution}}
+						return {{$type}}(t).MarshalXML(e, start)
+					}
+
+					func (t *{{$typeName}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+						return (*{{$type}})(t).UnmarshalXML(d, start)
+					}
+				{{else}}
+					{{if or (decodesChoices .Type) (qnameAttributes .Type)}}
+						func (t *{{$typeName}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+							return (*{{$type}})(t).UnmarshalXML(d, start)
+						}
+					{{end}}
+					{{if or (marshalsXSIType .Type) (marshalsQNames .Type)}}
+						func (t {{$typeName}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+							start.Name = xml.Name{Space: "{{$targetNamespace}}", Local: "{{$name}}"}
+							return {{$type}}(t).MarshalXML(e, start)
+						}
+					{{end}}
+					{{if validatesType .Type}}
+						// Validate checks {{$typeName}} against the constraints of its schema type,
+						// returning a *soap.ValidationError listing every violation.
+						func (t *{{$typeName}}) Validate() error {
+							return (*{{$type}})(t).Validate()
+						}
+					{{end}}
+					{{with constructorOf .Type}}
+						// New{{$typeName}} returns a new {{$typeName}} holding the default
+						// and fixed values declared by its schema.
+						func New{{$typeName}}() *{{$typeName}} {
+							return (*{{$typeName}})({{.}}())
+						}
+					{{end}}
 				{{end}}
 			{{end}}
 		{{end}}
+		{{template "Substitution" $substitution}}
 	{{end}}
 
 	{{range .ComplexTypes}}
 		{{/* ComplexTypeGlobal */}}
-		{{$typeName := replaceReservedWords .Name | makePublic}}
-		{{if and (eq (len .SimpleContent.Extension.Attributes) 0) (eq (toGoType .SimpleContent.Extension.Base false) "string") }}
+		{{$typeName := goTypeName . | replaceReservedWords | makePublic}}
+		{{$arrayItemType := soapArrayItemType .}}
+		{{if mapsType .Name}}
+		{{else if ne $arrayItemType ""}}
+			type {{$typeName}} struct {
+				Items []{{$arrayItemType}} ` + "`" + `xml:",any" json:"items,omitempty"` + "`" + `
+			}
+
+			func (t *{{$typeName}}) XSIType() xml.Name {
+				return xml.Name{Space: soap.XmlNsSoapEnc, Local: "Array"}
+			}
+
+			// Validate checks the items of {{$typeName}} against the constraints of
+			// their schema type, returning a *soap.ValidationError listing every
+			// violation.
+			func (t *{{$typeName}}) Validate() error {
+				v := new(soap.Validation)
+				v.Validate("", t.Items)
+				return v.Err()
+			}
+		{{else if and (eq (len .SimpleContent.Extension.Attributes) 0) (eq (toGoType .SimpleContent.Extension.Base false) "string") }}
 			type {{$typeName}} string
+
+			// Validate implements soap.Validator for {{$typeName}}, whose values
+			// are not constrained.
+			func ({{$typeName}}) Validate() error {
+				return nil
+			}
 		{{else}}
+			{{$content := structContent $typeName .}}
 			type {{$typeName}} struct {
-				{{$type := findNameByType .Name}}
+				{{$type := findNameByType .}}
 				{{if ne .Name $type}}
 					XMLName xml.Name ` + "`xml:\"{{$targetNamespace}} {{$type}}\"`" + `
 				{{end}}
 
 				{{if ne .ComplexContent.Extension.Base ""}}
-					{{template "ComplexContent" .ComplexContent}}
+					{{template "ExtensionBase" .ComplexContent}}
+					{{template "Fields" $content.Fields}}
+					{{template "Attributes" .ComplexContent.Extension.Attributes}}
 				{{else if ne .SimpleContent.Extension.Base ""}}
 					{{template "SimpleContent" .SimpleContent}}
+				{{else if ne .SimpleContent.Restriction.Base ""}}
+					{{template "SimpleContentRestriction" .}}
 				{{else}}
-					{{template "Elements" .Sequence}}
-					{{template "Any" .Any}}
-					{{template "Elements" .Choice}}
-					{{template "Elements" .SequenceChoice}}
-					{{template "Elements" .All}}
-					{{template "Attributes" .Attributes}}
+					{{template "Fields" $content.Fields}}
+					{{template "Any" $content.Wildcards}}
+					{{template "Attributes" contentAttributes .}}
 				{{end}}
 			}
+
+			{{$xmlName := ""}}
+			{{if ne .Name $type}}
+				{{$xmlName = $type}}
+			{{end}}
+			{{template "QNameMethods" qnameMethods $typeName $xmlName $content .}}
+			{{template "Enumeration" $content.Enumeration}}
+			{{template "Choices" $content}}
+			{{template "TypeHierarchy" typeHierarchy $typeName .}}
+			{{template "Validate" structValidation $content .}}
+			{{template "Constructor" structConstructor $content .}}
+
+			{{if usesSOAPEncoding}}
+				func (t *{{$typeName}}) XSIType() xml.Name {
+					return xml.Name{Space: "{{$targetNamespace}}", Local: "{{.Name}}"}
+				}
+			{{end}}
 		{{end}}
 	{{end}}
 {{end}}
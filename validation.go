@@ -0,0 +1,490 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// validation is the Validate method of a generated type, checking the value
+// of the type and of its fields.
+type validation struct {
+	TypeName string
+	// Pointer is set for structs, validated by pointer.
+	Pointer bool
+	Checks  []*validationCheck
+}
+
+// validationCheck checks the value of a field, or of the type itself.
+type validationCheck struct {
+	// Name is the name of the element or attribute in the paths of the
+	// violations, empty for the value of the type.
+	Name string
+	// Field is the expression of the value and Ref the one passed to
+	// soap.Validation.Validate.
+	Field string
+	Ref   string
+	// Required is set for elements and attributes which must be present,
+	// and Occurs for the slices of elements with bounded occurrences.
+	Required bool
+	Occurs   *validationOccurs
+	Facets   *validationFacets
+	// Validates is set when the value may implement soap.Validator.
+	Validates bool
+	// Inline is the validation of an anonymous struct. Facets and Inline
+	// apply to each item of the field when Slice is set.
+	Inline *validation
+	Slice  bool
+}
+
+// validationOccurs are the occurrences of an element, Max being -1 when
+// unbounded.
+type validationOccurs struct {
+	Min, Max int
+}
+
+// validationFacets are the facets of a restriction, as soap.Facets.
+type validationFacets struct {
+	Enumeration  []string
+	Pattern      string
+	MinInclusive string
+	MaxInclusive string
+	Length       string
+	MinLength    string
+	MaxLength    string
+}
+
+// simpleTypeValidation returns the Validate method of the type named
+// typeName generated for the simple type, or nil if it is generated as an
+// empty interface.
+func (g *GoWSDL) simpleTypeValidation(typeName string, st *XSDSimpleType) *validation {
+	if !g.simpleTypeValidates(st) {
+		return nil
+	}
+	v := &validation{TypeName: typeName}
+	switch {
+	case st.List.ItemType != "":
+		if g.validatesType(st.List.ItemType) {
+			itemType := removePointerFromType(g.toGoType(st.List.ItemType, false))
+			v.Checks = append(v.Checks, &validationCheck{Ref: "[]" + itemType + "(t)", Validates: true})
+		}
+	case st.Restriction.Base != "":
+		if g.validatesType(st.Restriction.Base) {
+			baseType := removePointerFromType(g.toGoType(st.Restriction.Base, false))
+			v.Checks = append(v.Checks, &validationCheck{Ref: baseType + "(t)", Validates: true})
+		}
+		if facets := g.restrictionFacets(st.Restriction, typeName); facets != nil {
+			v.Checks = append(v.Checks, &validationCheck{Field: "t", Facets: facets})
+		}
+	}
+	return v
+}
+
+// simpleTypeValidates reports whether the simple type, declared by the
+// current schema, is not generated as an empty interface or as an alias of a
+// mapped type, which cannot have methods.
+func (g *GoWSDL) simpleTypeValidates(st *XSDSimpleType) bool {
+	if g.restrictsMappedType(st) {
+		return false
+	}
+	return st.List.ItemType != "" || st.Union.MemberTypes != "" || st.Union.SimpleType != nil || st.Restriction.Base != ""
+}
+
+// validatesType reports whether the Go type of a type reference of the
+// current schema has a Validate method.
+func (g *GoWSDL) validatesType(xsdType string) bool {
+	name, resolved := resolveQName(xsdType, g.currentSchema.Xmlns)
+	if !resolved || builtinNamespaces[name.Space] {
+		return false
+	}
+	s := g.symbols.lookupType(name, resolved)
+	if s == nil {
+		return false
+	}
+
+	schema := g.currentSchema
+	g.currentSchema = s.schema
+	defer func() { g.currentSchema = schema }()
+	switch decl := s.decl.(type) {
+	case *XSDComplexType:
+		return true
+	case *XSDSimpleType:
+		return g.simpleTypeValidates(decl)
+	}
+	return false
+}
+
+// validatesElement reports whether the Go type of an element reference of
+// the current schema has a Validate method.
+func (g *GoWSDL) validatesElement(ref string) bool {
+	s := g.symbols.lookupElement(resolveQName(ref, g.currentSchema.Xmlns))
+	if s == nil {
+		return false
+	}
+	el := s.decl.(*XSDElement)
+	schema := g.currentSchema
+	g.currentSchema = s.schema
+	defer func() { g.currentSchema = schema }()
+
+	switch {
+	case el.ComplexType != nil:
+		return true
+	case el.SimpleType != nil:
+		return g.simpleTypeValidates(el.SimpleType)
+	case el.Type == "":
+		return false
+	}
+	return g.validatesType(el.Type)
+}
+
+// structValidation returns the Validate method of the struct generated for
+// the complex type, whose element content is content.
+func (g *GoWSDL) structValidation(content *structContent, ct *XSDComplexType) *validation {
+	v := &validation{TypeName: content.TypeName, Pointer: true}
+	switch {
+	case ct.ComplexContent.Extension.Base != "":
+		v.Checks = append(v.Checks, g.baseCheck(ct.ComplexContent.Extension.Base)...)
+		v.Checks = append(v.Checks, g.fieldChecks(content.Fields)...)
+		v.Checks = append(v.Checks, g.attributeChecks(ct.ComplexContent.Extension.Attributes)...)
+	case ct.SimpleContent.Extension.Base != "" || ct.SimpleContent.Restriction.Base != "":
+		v.Checks = g.simpleContentChecks(ct)
+	default:
+		v.Checks = append(g.fieldChecks(content.Fields), g.attributeChecks(g.contentAttributes(ct))...)
+	}
+	return v
+}
+
+// inlineValidation returns the checks of the anonymous struct generated for
+// the local complex type of an element nested in another type, or nil if
+// there are none.
+func (g *GoWSDL) inlineValidation(ct *XSDComplexType) *validation {
+	if ct == nil {
+		return nil
+	}
+	v := &validation{}
+	switch {
+	case ct.ComplexContent.Extension.Base != "":
+		v.Checks = append(v.Checks, g.baseCheck(ct.ComplexContent.Extension.Base)...)
+		v.Checks = append(v.Checks, g.elementChecks(ct.ComplexContent.Extension.Elements())...)
+		v.Checks = append(v.Checks, g.attributeChecks(ct.ComplexContent.Extension.Attributes)...)
+	case ct.SimpleContent.Extension.Base != "" || ct.SimpleContent.Restriction.Base != "":
+		v.Checks = g.simpleContentChecks(ct)
+	case ct.ComplexContent.Restriction.Base != "":
+		v.Checks = append(g.elementChecks(ct.ComplexContent.Restriction.Elements()), g.attributeChecks(g.contentAttributes(ct))...)
+	default:
+		v.Checks = append(g.elementChecks(ct.Elements()), g.attributeChecks(ct.Attributes)...)
+	}
+	if len(v.Checks) == 0 {
+		return nil
+	}
+	return v
+}
+
+// baseCheck returns the check of the base type embedded by an extension.
+func (g *GoWSDL) baseCheck(base string) []*validationCheck {
+	if !g.validatesType(base) {
+		return nil
+	}
+	field := removePointerFromType(g.toGoType(base, false))
+	field = field[strings.LastIndex(field, ".")+1:]
+	return []*validationCheck{{Ref: "t." + field, Validates: true}}
+}
+
+// simpleContentChecks returns the checks of the value and attributes of a
+// complex type with simple content.
+func (g *GoWSDL) simpleContentChecks(ct *XSDComplexType) []*validationCheck {
+	if ext := ct.SimpleContent.Extension; ext.Base != "" {
+		var checks []*validationCheck
+		if g.validatesType(ext.Base) {
+			checks = append(checks, &validationCheck{Ref: "&t.Value", Validates: true})
+		}
+		return append(checks, g.attributeChecks(ext.Attributes)...)
+	}
+
+	var checks []*validationCheck
+	restriction := ct.SimpleContent.Restriction
+	if st := restriction.SimpleType; st != nil {
+		if facets := g.restrictionFacets(st.Restriction, ct.Name); facets != nil {
+			checks = append(checks, &validationCheck{Field: "t.Value", Facets: facets})
+		}
+	}
+	if facets := g.restrictionFacets(restriction.XSDRestriction, ct.Name); facets != nil {
+		checks = append(checks, &validationCheck{Field: "t.Value", Facets: facets})
+	}
+	return append(checks, g.attributeChecks(g.contentAttributes(ct))...)
+}
+
+// fieldChecks returns the checks of the fields of a struct.
+func (g *GoWSDL) fieldChecks(fields []*contentField) []*validationCheck {
+	var checks []*validationCheck
+	for _, field := range fields {
+		switch {
+		case field.Choice != nil:
+			checks = append(checks, choiceChecks(field.Choice)...)
+		case field.Polymorphic != nil:
+			o := parseOccurs(field.Element.MinOccurs, field.Element.MaxOccurs)
+			check := &validationCheck{
+				Name:      field.Polymorphic.JSON,
+				Field:     "t." + field.Polymorphic.Name,
+				Ref:       "t." + field.Polymorphic.Name,
+				Validates: true,
+			}
+			occurrences(check, o, field.Polymorphic.Slice, field.Element.Nillable)
+			checks = append(checks, check)
+		default:
+			if check := g.elementCheck(field.Element); check != nil {
+				checks = append(checks, check)
+			}
+		}
+	}
+	return checks
+}
+
+// choiceChecks returns the checks of a choice generated as a sealed
+// interface: its presence, named after its alternatives, and the values of
+// the alternatives, validated by their wrappers under their own name.
+func choiceChecks(choice *choiceGroup) []*validationCheck {
+	var names []string
+	validates := false
+	for _, alt := range choice.Alternatives {
+		names = append(names, alt.Local)
+		validates = validates || alt.Checks != nil
+	}
+	field := "t." + choice.Field
+	checks := []*validationCheck{{Name: strings.Join(names, "|"), Field: field}}
+	occurrences(checks[0], choice.occurs, choice.Repeated, false)
+	if !checks[0].Required && checks[0].Occurs == nil {
+		checks = nil
+	}
+	if validates {
+		checks = append(checks, &validationCheck{Ref: field, Validates: true})
+	}
+	return checks
+}
+
+// alternativeChecks returns the checks of the value of the wrapper of an
+// alternative of a choice, or nil if there are none.
+func (g *GoWSDL) alternativeChecks(el *XSDElement) []*validationCheck {
+	check := g.elementCheck(el)
+	if check == nil || check.Facets == nil && !check.Validates {
+		return nil
+	}
+	check.Field, check.Ref = "c.Value", "&c.Value"
+	check.Required, check.Occurs = false, nil
+	return []*validationCheck{check}
+}
+
+// elementChecks returns the checks of the fields of the elements.
+func (g *GoWSDL) elementChecks(elements []*XSDElement) []*validationCheck {
+	var checks []*validationCheck
+	for _, el := range elements {
+		if check := g.elementCheck(el); check != nil {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// elementCheck returns the check of the field of an element, as generated by
+// the Element template, or nil if there is nothing to check.
+func (g *GoWSDL) elementCheck(el *XSDElement) *validationCheck {
+	field := "t." + g.elementFieldName(el)
+	check := &validationCheck{Name: el.Name, Field: field, Ref: "&" + field}
+	slice := el.Repeated()
+	var goType string
+	switch {
+	case el.Ref != "":
+		check.Name = removeNS(el.Ref)
+		check.Validates = g.validatesElement(el.Ref)
+	case el.Type != "":
+		goType = g.toGoType(el.Type, el.Nillable)
+		check.Validates = g.validatesType(el.Type)
+	case el.SimpleType != nil:
+		if itemType := el.SimpleType.List.ItemType; itemType != "" {
+			slice = false
+			check.Validates = g.validatesType(itemType)
+		} else {
+			goType = g.toGoType(el.SimpleType.Restriction.Base, false)
+			check.Validates = g.validatesType(el.SimpleType.Restriction.Base)
+			check.Facets = g.restrictionFacets(el.SimpleType.Restriction, el.Name)
+			check.Slice = slice
+		}
+	default:
+		check.Field = "t." + g.makePublicFn(replaceReservedWords(el.Name))
+		check.Inline = g.inlineValidation(el.ComplexType)
+		check.Slice = slice
+	}
+	occurrences(check, parseOccurs(el.MinOccurs, el.MaxOccurs), slice, el.Nillable)
+	check.Required = check.Required && !zeroIsValue(goType)
+	if !check.Required && check.Occurs == nil && check.Facets == nil && !check.Validates && check.Inline == nil {
+		return nil
+	}
+	return check
+}
+
+// occurrences sets the checks of the occurrences of the field of an
+// element: its number of items for slices, its presence otherwise.
+func occurrences(check *validationCheck, o occurs, slice, nillable bool) {
+	switch {
+	case slice && (o.min > 0 || o.max >= 0):
+		check.Occurs = &validationOccurs{Min: o.min, Max: o.max}
+	case !slice && o.min > 0 && !nillable:
+		check.Required = true
+	}
+}
+
+// zeroIsValue reports whether the zero value of the Go type is a value of
+// its schema type rather than a missing one, as for numbers and booleans.
+// Their presence cannot be checked.
+func zeroIsValue(goType string) bool {
+	switch goType {
+	case "bool", "byte", "float32", "float64",
+		"int", "int8", "int16", "int32", "int64",
+		"uint", "uint16", "uint32", "uint64":
+		return true
+	}
+	return false
+}
+
+// attributeChecks returns the checks of the fields of the attributes, as
+// generated by the Attributes template.
+func (g *GoWSDL) attributeChecks(attributes []*XSDAttribute) []*validationCheck {
+	var checks []*validationCheck
+	for _, attr := range attributes {
+		if attr.Fixed != "" {
+			// always marshalled with its fixed value
+			continue
+		}
+		field := "t." + makePublic(normalize(attr.Name))
+		check := &validationCheck{
+			Name:     "@" + attr.Name,
+			Field:    field,
+			Ref:      "&" + field,
+			Required: attr.Use == "required",
+		}
+		if attr.Type != "" {
+			check.Required = check.Required && !zeroIsValue(g.toGoType(attr.Type, false))
+			check.Validates = g.validatesType(attr.Type)
+		} else if attr.SimpleType != nil {
+			check.Facets = g.restrictionFacets(attr.SimpleType.Restriction, attr.Name)
+		}
+		if check.Required || check.Validates || check.Facets != nil {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// restrictionFacets returns the facets of the restriction of the simple type
+// named owner, or nil if it has none.
+func (g *GoWSDL) restrictionFacets(r XSDRestriction, owner string) *validationFacets {
+	f := &validationFacets{
+		MinInclusive: strings.TrimSpace(r.MinInclusive.Value),
+		MaxInclusive: strings.TrimSpace(r.MaxInclusive.Value),
+		Length:       strings.TrimSpace(r.Length.Value),
+		MinLength:    strings.TrimSpace(r.MinLength.Value),
+		MaxLength:    strings.TrimSpace(r.MaxLength.Value),
+	}
+	for _, value := range r.Enumeration {
+		f.Enumeration = append(f.Enumeration, value.Value)
+	}
+	f.Pattern = restrictionPattern(r.Pattern, owner)
+	if f.Enumeration == nil && f.Pattern == "" && f.MinInclusive == "" && f.MaxInclusive == "" &&
+		f.Length == "" && f.MinLength == "" && f.MaxLength == "" {
+		return nil
+	}
+	return f
+}
+
+// restrictionPattern returns the translation of the patterns of a
+// restriction of the simple type named owner. Values are valid if they match
+// any of the patterns, so they are joined as alternatives. It returns "" if
+// a pattern cannot be translated: checking the others alone would reject
+// valid values.
+func restrictionPattern(values []XSDRestrictionValue, owner string) string {
+	var patterns []string
+	for _, value := range values {
+		pattern, err := goPattern(value.Value)
+		if err != nil {
+			log.Printf("[WARN] pattern %q of %s is not validated: %v", value.Value, owner, err)
+			return ""
+		}
+		patterns = append(patterns, pattern)
+	}
+	if len(patterns) == 1 {
+		return patterns[0]
+	}
+	for i, pattern := range patterns {
+		patterns[i] = "(?:" + pattern + ")"
+	}
+	return strings.Join(patterns, "|")
+}
+
+// goPattern translates a regular expression of XML Schema to the syntax of
+// package regexp. XML Schema expressions match whole values, without
+// anchors: ^ and $ are ordinary characters.
+func goPattern(pattern string) (string, error) {
+	var b strings.Builder
+	inClass := false
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			escape, ok := patternEscapes[runes[i]]
+			switch {
+			case !ok:
+				b.WriteRune(r)
+				b.WriteRune(runes[i])
+			case inClass && escape.inClass == "":
+				return "", errors.New("negated multi-character escape in a character class")
+			case inClass:
+				b.WriteString(escape.inClass)
+			default:
+				b.WriteString(escape.outside)
+			}
+		case inClass && r == '-' && i+1 < len(runes) && runes[i+1] == '[':
+			return "", errors.New("character class subtraction")
+		case inClass && r == ']':
+			inClass = false
+			b.WriteRune(r)
+		case !inClass && r == '[':
+			inClass = true
+			b.WriteRune(r)
+			if i+1 < len(runes) && runes[i+1] == '^' {
+				i++
+				b.WriteRune('^')
+			}
+		case !inClass && (r == '^' || r == '$'):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if _, err := regexp.Compile("^(?:" + b.String() + ")$"); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// patternEscape is the translation of a multi-character escape of XML
+// Schema, outside and inside character classes.
+type patternEscape struct {
+	outside, inClass string
+}
+
+var patternEscapes = map[rune]patternEscape{
+	'i': {`[\p{L}_:]`, `\p{L}_:`},
+	'I': {`[^\p{L}_:]`, ``},
+	'c': {`[\p{L}\p{M}\p{N}._:\-]`, `\p{L}\p{M}\p{N}._:\-`},
+	'C': {`[^\p{L}\p{M}\p{N}._:\-]`, ``},
+	'd': {`\p{Nd}`, `\p{Nd}`},
+	'D': {`\P{Nd}`, `\P{Nd}`},
+}
@@ -6,7 +6,11 @@ package gowsdl
 
 import "encoding/xml"
 
-const wsdlNamespace = "http://schemas.xmlsoap.org/wsdl/"
+const (
+	wsdlNamespace   = "http://schemas.xmlsoap.org/wsdl/"
+	soap11Namespace = "http://schemas.xmlsoap.org/wsdl/soap/"
+	soap12Namespace = "http://schemas.xmlsoap.org/wsdl/soap12/"
+)
 
 // WSDL represents the global structure of a WSDL file.
 type WSDL struct {
@@ -73,7 +77,7 @@ Loop:
 						}
 					}
 				case "message":
-					x := new(WSDLMessage)
+					x := &WSDLMessage{xmlns: w.Xmlns}
 					if err := d.DecodeElement(x, &t); err != nil {
 						return err
 					}
@@ -136,42 +140,52 @@ type WSDLMessage struct {
 	Name  string      `xml:"name,attr"`
 	Doc   string      `xml:"documentation"`
 	Parts []*WSDLPart `xml:"http://schemas.xmlsoap.org/wsdl/ part"`
+
+	// xmlns holds the namespace declarations in scope, used to resolve
+	// the parts elements and types.
+	xmlns map[string]string
 }
 
 // WSDLFault represents a WSDL fault message.
 type WSDLFault struct {
-	Name      string        `xml:"name,attr"`
-	Message   string        `xml:"message,attr"`
-	Doc       string        `xml:"documentation"`
-	SOAPFault WSDLSOAPFault `xml:"http://schemas.xmlsoap.org/wsdl/soap/ fault"`
+	Name        string        `xml:"name,attr"`
+	Message     string        `xml:"message,attr"`
+	Doc         string        `xml:"documentation"`
+	SOAPFault   WSDLSOAPFault `xml:"http://schemas.xmlsoap.org/wsdl/soap/ fault"`
+	SOAP12Fault WSDLSOAPFault `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ fault"`
 }
 
 // WSDLInput represents a WSDL input message.
 type WSDLInput struct {
-	Name       string            `xml:"name,attr"`
-	Message    string            `xml:"message,attr"`
-	Doc        string            `xml:"documentation"`
-	SOAPBody   WSDLSOAPBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
-	SOAPHeader []*WSDLSOAPHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap/ header"`
+	Name         string            `xml:"name,attr"`
+	Message      string            `xml:"message,attr"`
+	Doc          string            `xml:"documentation"`
+	SOAPBody     WSDLSOAPBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
+	SOAPHeader   []*WSDLSOAPHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap/ header"`
+	SOAP12Body   WSDLSOAPBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ body"`
+	SOAP12Header []*WSDLSOAPHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ header"`
 }
 
 // WSDLOutput represents a WSDL output message.
 type WSDLOutput struct {
-	Name       string            `xml:"name,attr"`
-	Message    string            `xml:"message,attr"`
-	Doc        string            `xml:"documentation"`
-	SOAPBody   WSDLSOAPBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
-	SOAPHeader []*WSDLSOAPHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap/ header"`
+	Name         string            `xml:"name,attr"`
+	Message      string            `xml:"message,attr"`
+	Doc          string            `xml:"documentation"`
+	SOAPBody     WSDLSOAPBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
+	SOAPHeader   []*WSDLSOAPHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap/ header"`
+	SOAP12Body   WSDLSOAPBody      `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ body"`
+	SOAP12Header []*WSDLSOAPHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ header"`
 }
 
 // WSDLOperation represents the contract of an entire operation or function.
 type WSDLOperation struct {
-	Name          string            `xml:"name,attr"`
-	Doc           string            `xml:"documentation"`
-	Input         WSDLInput         `xml:"input"`
-	Output        WSDLOutput        `xml:"output"`
-	Faults        []*WSDLFault      `xml:"fault"`
-	SOAPOperation WSDLSOAPOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	Name            string            `xml:"name,attr"`
+	Doc             string            `xml:"documentation"`
+	Input           WSDLInput         `xml:"input"`
+	Output          WSDLOutput        `xml:"output"`
+	Faults          []*WSDLFault      `xml:"fault"`
+	SOAPOperation   WSDLSOAPOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	SOAP12Operation WSDLSOAPOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ operation"`
 }
 
 // WSDLPortType defines the service, operations that can be performed and the messages involved.
@@ -184,6 +198,7 @@ type WSDLPortType struct {
 
 // WSDLSOAPBinding represents a SOAP binding to the web service.
 type WSDLSOAPBinding struct {
+	XMLName   xml.Name
 	Style     string `xml:"style,attr"`
 	Transport string `xml:"transport,attr"`
 }
@@ -236,19 +251,89 @@ type WSDLSOAPAddress struct {
 
 // WSDLBinding defines only a SOAP binding and its operations
 type WSDLBinding struct {
-	Name        string           `xml:"name,attr"`
-	Type        string           `xml:"type,attr"`
-	Doc         string           `xml:"documentation"`
-	SOAPBinding WSDLSOAPBinding  `xml:"http://schemas.xmlsoap.org/wsdl/soap/ binding"`
-	Operations  []*WSDLOperation `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
+	Name          string           `xml:"name,attr"`
+	Type          string           `xml:"type,attr"`
+	Doc           string           `xml:"documentation"`
+	SOAPBinding   WSDLSOAPBinding  `xml:"http://schemas.xmlsoap.org/wsdl/soap/ binding"`
+	SOAP12Binding WSDLSOAPBinding  `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ binding"`
+	Operations    []*WSDLOperation `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
+}
+
+// soapVersion returns the SOAP protocol version used by the binding, "1.1" or
+// "1.2", or an empty string if it is not a SOAP binding (e.g. HTTP GET/POST).
+func (b *WSDLBinding) soapVersion() string {
+	switch {
+	case b.SOAPBinding.XMLName.Space == soap11Namespace:
+		return "1.1"
+	case b.SOAP12Binding.XMLName.Space == soap12Namespace:
+		return "1.2"
+	}
+	return ""
+}
+
+// soapOperation returns the SOAP extension of the operation matching the
+// binding SOAP version.
+func (b *WSDLBinding) soapOperation(op *WSDLOperation) WSDLSOAPOperation {
+	if b.soapVersion() == "1.2" {
+		return op.SOAP12Operation
+	}
+	return op.SOAPOperation
+}
+
+// soapStyle returns the style ("rpc" or "document") of the given bound
+// operation. The operation style overrides the binding one and defaults to
+// "document".
+func (b *WSDLBinding) soapStyle(op *WSDLOperation) string {
+	style := b.soapOperation(op).Style
+	if style == "" {
+		if b.soapVersion() == "1.2" {
+			style = b.SOAP12Binding.Style
+		} else {
+			style = b.SOAPBinding.Style
+		}
+	}
+	if style == "" {
+		style = "document"
+	}
+	return style
+}
+
+// soapInputBody returns the SOAP body extension of the operation input
+// matching the binding SOAP version.
+func (b *WSDLBinding) soapInputBody(op *WSDLOperation) WSDLSOAPBody {
+	if b.soapVersion() == "1.2" {
+		return op.Input.SOAP12Body
+	}
+	return op.Input.SOAPBody
+}
+
+// soapOutputBody returns the SOAP body extension of the operation output
+// matching the binding SOAP version.
+func (b *WSDLBinding) soapOutputBody(op *WSDLOperation) WSDLSOAPBody {
+	if b.soapVersion() == "1.2" {
+		return op.Output.SOAP12Body
+	}
+	return op.Output.SOAPBody
+}
+
+// isEncoded reports whether any operation of the binding serializes its
+// messages with the SOAP encoding (use="encoded").
+func (b *WSDLBinding) isEncoded() bool {
+	for _, op := range b.Operations {
+		if b.soapInputBody(op).Use == "encoded" || b.soapOutputBody(op).Use == "encoded" {
+			return true
+		}
+	}
+	return false
 }
 
 // WSDLPort defines the properties for a SOAP port only.
 type WSDLPort struct {
-	Name        string          `xml:"name,attr"`
-	Binding     string          `xml:"binding,attr"`
-	Doc         string          `xml:"documentation"`
-	SOAPAddress WSDLSOAPAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap/ address"`
+	Name          string          `xml:"name,attr"`
+	Binding       string          `xml:"binding,attr"`
+	Doc           string          `xml:"documentation"`
+	SOAPAddress   WSDLSOAPAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap/ address"`
+	SOAP12Address WSDLSOAPAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ address"`
 }
 
 // WSDLService defines the list of SOAP services associated with the WSDL.
@@ -0,0 +1,375 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/xml"
+	"log"
+	"strings"
+)
+
+const (
+	wsdl20Namespace     = "http://www.w3.org/ns/wsdl"
+	wsdl20SOAPNamespace = "http://www.w3.org/ns/wsdl/soap"
+	wsdl20HTTPNamespace = "http://www.w3.org/ns/wsdl/http"
+)
+
+// WSDL2 represents the global structure of a WSDL 2.0 file. It is only used
+// while reading the document, which is then converted to the WSDL 1.1 model
+// consumed by the generator.
+type WSDL2 struct {
+	Xmlns           map[string]string `xml:"-"`
+	TargetNamespace string            `xml:"targetNamespace,attr"`
+	Doc             string            `xml:"http://www.w3.org/ns/wsdl documentation"`
+	Imports         []*WSDLImport     `xml:"http://www.w3.org/ns/wsdl import"`
+	Includes        []*WSDLImport     `xml:"http://www.w3.org/ns/wsdl include"`
+	Types           WSDLType          `xml:"http://www.w3.org/ns/wsdl types"`
+	Interfaces      []*WSDL2Interface `xml:"http://www.w3.org/ns/wsdl interface"`
+	Bindings        []*WSDL2Binding   `xml:"http://www.w3.org/ns/wsdl binding"`
+	Services        []*WSDL2Service   `xml:"http://www.w3.org/ns/wsdl service"`
+}
+
+// UnmarshalXML implements interface xml.Unmarshaler for WSDL2.
+func (w *WSDL2) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type wsdl2 WSDL2
+	if err := d.DecodeElement((*wsdl2)(w), &start); err != nil {
+		return err
+	}
+
+	w.Xmlns = make(map[string]string)
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" {
+			w.Xmlns[attr.Name.Local] = attr.Value
+		}
+	}
+	for prefix, namespace := range w.Xmlns {
+		for _, s := range w.Types.Schemas {
+			if _, ok := s.Xmlns[prefix]; !ok {
+				s.Xmlns[prefix] = namespace
+			}
+		}
+	}
+
+	return nil
+}
+
+// WSDL2Interface groups the abstract operations of a service, it is the
+// WSDL 2.0 counterpart of a port type.
+type WSDL2Interface struct {
+	Name       string                 `xml:"name,attr"`
+	Extends    string                 `xml:"extends,attr"`
+	Doc        string                 `xml:"http://www.w3.org/ns/wsdl documentation"`
+	Faults     []*WSDL2InterfaceFault `xml:"http://www.w3.org/ns/wsdl fault"`
+	Operations []*WSDL2Operation      `xml:"http://www.w3.org/ns/wsdl operation"`
+}
+
+// WSDL2InterfaceFault declares a fault that operations of an interface may
+// reference.
+type WSDL2InterfaceFault struct {
+	Name    string `xml:"name,attr"`
+	Element string `xml:"element,attr"`
+	Doc     string `xml:"http://www.w3.org/ns/wsdl documentation"`
+}
+
+// WSDL2Operation represents an abstract operation of an interface.
+type WSDL2Operation struct {
+	Name      string                   `xml:"name,attr"`
+	Pattern   string                   `xml:"pattern,attr"`
+	Style     string                   `xml:"style,attr"`
+	Doc       string                   `xml:"http://www.w3.org/ns/wsdl documentation"`
+	Input     []*WSDL2MessageReference `xml:"http://www.w3.org/ns/wsdl input"`
+	Output    []*WSDL2MessageReference `xml:"http://www.w3.org/ns/wsdl output"`
+	Infaults  []*WSDL2FaultReference   `xml:"http://www.w3.org/ns/wsdl infault"`
+	Outfaults []*WSDL2FaultReference   `xml:"http://www.w3.org/ns/wsdl outfault"`
+}
+
+// WSDL2MessageReference references the element carried by an operation
+// message. Element is either a QName or one of the "#any", "#none" and
+// "#other" tokens.
+type WSDL2MessageReference struct {
+	MessageLabel string `xml:"messageLabel,attr"`
+	Element      string `xml:"element,attr"`
+}
+
+// WSDL2FaultReference references an interface fault from an operation.
+type WSDL2FaultReference struct {
+	Ref          string `xml:"ref,attr"`
+	MessageLabel string `xml:"messageLabel,attr"`
+}
+
+// WSDL2Binding describes the concrete protocol of an interface.
+type WSDL2Binding struct {
+	Name         string                   `xml:"name,attr"`
+	Interface    string                   `xml:"interface,attr"`
+	Type         string                   `xml:"type,attr"`
+	SOAPVersion  string                   `xml:"http://www.w3.org/ns/wsdl/soap version,attr"`
+	SOAPProtocol string                   `xml:"http://www.w3.org/ns/wsdl/soap protocol,attr"`
+	Doc          string                   `xml:"http://www.w3.org/ns/wsdl documentation"`
+	Operations   []*WSDL2BindingOperation `xml:"http://www.w3.org/ns/wsdl operation"`
+}
+
+// WSDL2BindingOperation holds the binding details of an interface operation.
+type WSDL2BindingOperation struct {
+	Ref        string `xml:"ref,attr"`
+	SOAPAction string `xml:"http://www.w3.org/ns/wsdl/soap action,attr"`
+}
+
+// WSDL2Service groups the endpoints of an interface.
+type WSDL2Service struct {
+	Name      string           `xml:"name,attr"`
+	Interface string           `xml:"interface,attr"`
+	Doc       string           `xml:"http://www.w3.org/ns/wsdl documentation"`
+	Endpoints []*WSDL2Endpoint `xml:"http://www.w3.org/ns/wsdl endpoint"`
+}
+
+// WSDL2Endpoint defines the address of a service for a given binding.
+type WSDL2Endpoint struct {
+	Name    string `xml:"name,attr"`
+	Binding string `xml:"binding,attr"`
+	Address string `xml:"address,attr"`
+}
+
+// toWSDL converts the WSDL 2.0 description into the WSDL 1.1 model.
+// Interfaces bound by SOAP bindings become port types, with one synthesized
+// message per operation input, output and fault, SOAP bindings become SOAP 1.1
+// or SOAP 1.2 bindings and endpoints become ports. HTTP bindings are not
+// supported and are skipped.
+func (w *WSDL2) toWSDL() *WSDL {
+	wsdl := &WSDL{
+		Xmlns:           w.Xmlns,
+		TargetNamespace: w.TargetNamespace,
+		Doc:             w.Doc,
+		Types:           w.Types,
+	}
+	wsdl.Imports = append(wsdl.Imports, w.Imports...)
+	wsdl.Imports = append(wsdl.Imports, w.Includes...)
+
+	for _, iface := range w.Interfaces {
+		// the interfaces which are only extended or bound by unsupported
+		// bindings have no client
+		if !w.isBound(iface) {
+			continue
+		}
+
+		portType := &WSDLPortType{
+			Name: iface.Name,
+			Doc:  iface.Doc,
+		}
+
+		faults := make(map[string]string)
+		for _, fault := range w.interfaceFaults(iface, nil) {
+			message := iface.Name + "_" + fault.Name + "_Fault"
+			wsdl.Messages = append(wsdl.Messages, w.newMessage(message, fault.Element))
+			faults[fault.Name] = message
+		}
+
+		for _, op := range w.interfaceOperations(iface, nil) {
+			operation := &WSDLOperation{
+				Name: op.Name,
+				Doc:  op.Doc,
+			}
+			if len(op.Input) > 0 && op.Input[0].Element != "#none" {
+				operation.Input.Message = iface.Name + "_" + op.Name + "_Input"
+				wsdl.Messages = append(wsdl.Messages, w.newMessage(operation.Input.Message, op.Input[0].Element))
+			}
+			if len(op.Output) > 0 && op.Output[0].Element != "#none" {
+				operation.Output.Message = iface.Name + "_" + op.Name + "_Output"
+				wsdl.Messages = append(wsdl.Messages, w.newMessage(operation.Output.Message, op.Output[0].Element))
+			}
+			refs := append(append([]*WSDL2FaultReference(nil), op.Infaults...), op.Outfaults...)
+			for _, ref := range refs {
+				name := stripns(ref.Ref)
+				operation.Faults = append(operation.Faults, &WSDLFault{
+					Name:    name,
+					Message: faults[name],
+				})
+			}
+			portType.Operations = append(portType.Operations, operation)
+		}
+
+		wsdl.PortTypes = append(wsdl.PortTypes, portType)
+	}
+
+	bindings := make(map[string]*WSDLBinding)
+	for _, b := range w.Bindings {
+		switch b.Type {
+		case wsdl20SOAPNamespace:
+		case wsdl20HTTPNamespace:
+			log.Printf("[WARN] %s HTTP binding is not supported, ignoring binding...", b.Name)
+			continue
+		default:
+			log.Printf("[WARN] %s binding of type %s is not supported, ignoring binding...", b.Name, b.Type)
+			continue
+		}
+
+		iface := w.findInterface(b.Interface)
+		if iface == nil {
+			log.Printf("[WARN] %s binding references unknown interface %s, ignoring binding...", b.Name, b.Interface)
+			continue
+		}
+
+		soapBinding := WSDLSOAPBinding{
+			Style:     "document",
+			Transport: b.SOAPProtocol,
+		}
+		binding := &WSDLBinding{
+			Name: b.Name,
+			Type: b.Interface,
+			Doc:  b.Doc,
+		}
+		// SOAP 1.2 is the default version of WSDL 2.0 SOAP bindings
+		if b.SOAPVersion == "1.1" {
+			soapBinding.XMLName = xml.Name{Space: soap11Namespace, Local: "binding"}
+			binding.SOAPBinding = soapBinding
+		} else {
+			soapBinding.XMLName = xml.Name{Space: soap12Namespace, Local: "binding"}
+			binding.SOAP12Binding = soapBinding
+		}
+
+		for _, op := range w.interfaceOperations(iface, nil) {
+			operation := &WSDLOperation{Name: op.Name}
+			soapOperation := WSDLSOAPOperation{
+				SOAPAction: b.soapAction(op.Name),
+			}
+			if binding.soapVersion() == "1.1" {
+				operation.SOAPOperation = soapOperation
+				operation.Input.SOAPBody.Use = "literal"
+				operation.Output.SOAPBody.Use = "literal"
+			} else {
+				operation.SOAP12Operation = soapOperation
+				operation.Input.SOAP12Body.Use = "literal"
+				operation.Output.SOAP12Body.Use = "literal"
+			}
+			binding.Operations = append(binding.Operations, operation)
+		}
+
+		bindings[b.Name] = binding
+		wsdl.Binding = append(wsdl.Binding, binding)
+	}
+
+	for _, s := range w.Services {
+		service := &WSDLService{
+			Name: s.Name,
+			Doc:  s.Doc,
+		}
+		for _, e := range s.Endpoints {
+			binding, ok := bindings[stripns(e.Binding)]
+			if !ok {
+				continue
+			}
+			port := &WSDLPort{
+				Name:    e.Name,
+				Binding: e.Binding,
+			}
+			if binding.soapVersion() == "1.1" {
+				port.SOAPAddress.Location = e.Address
+			} else {
+				port.SOAP12Address.Location = e.Address
+			}
+			service.Ports = append(service.Ports, port)
+		}
+		wsdl.Service = append(wsdl.Service, service)
+	}
+
+	return wsdl
+}
+
+// newMessage returns a message carrying the given element, or an empty
+// message if the element is not a QName.
+func (w *WSDL2) newMessage(name, element string) *WSDLMessage {
+	message := &WSDLMessage{Name: name, xmlns: w.Xmlns}
+	if strings.HasPrefix(element, "#") || element == "" {
+		log.Printf("[WARN] %s message references %q instead of an element, ignoring part...", name, element)
+		return message
+	}
+	message.Parts = []*WSDLPart{{Name: "body", Element: element}}
+	return message
+}
+
+// isBound reports whether iface is bound by a SOAP binding.
+func (w *WSDL2) isBound(iface *WSDL2Interface) bool {
+	for _, b := range w.Bindings {
+		if b.Type == wsdl20SOAPNamespace && w.findInterface(b.Interface) == iface {
+			return true
+		}
+	}
+	return false
+}
+
+// findInterface returns the interface with the given qualified name.
+func (w *WSDL2) findInterface(name string) *WSDL2Interface {
+	name = stripns(name)
+	for _, iface := range w.Interfaces {
+		if iface.Name == name {
+			return iface
+		}
+	}
+	return nil
+}
+
+// interfaceOperations returns the operations of iface, including the ones
+// inherited from the interfaces it extends. An operation inherited several
+// times, or declared again with the same qualified name, is returned once.
+func (w *WSDL2) interfaceOperations(iface *WSDL2Interface, seen map[string]bool) []*WSDL2Operation {
+	if seen == nil {
+		seen = make(map[string]bool)
+	}
+	seen[iface.Name] = true
+
+	operations := append([]*WSDL2Operation(nil), iface.Operations...)
+	for _, name := range strings.Fields(iface.Extends) {
+		parent := w.findInterface(name)
+		if parent == nil {
+			log.Printf("[WARN] %s interface extends unknown interface %s", iface.Name, name)
+			continue
+		}
+		if seen[parent.Name] {
+			continue
+		}
+		operations = append(operations, w.interfaceOperations(parent, seen)...)
+	}
+
+	// the operations are qualified by the target namespace of the description
+	declared := make(map[xml.Name]bool)
+	unique := operations[:0]
+	for _, op := range operations {
+		name := xml.Name{Space: w.TargetNamespace, Local: op.Name}
+		if declared[name] {
+			continue
+		}
+		declared[name] = true
+		unique = append(unique, op)
+	}
+	return unique
+}
+
+// interfaceFaults returns the faults of iface, including the ones inherited
+// from the interfaces it extends.
+func (w *WSDL2) interfaceFaults(iface *WSDL2Interface, seen map[string]bool) []*WSDL2InterfaceFault {
+	if seen == nil {
+		seen = make(map[string]bool)
+	}
+	seen[iface.Name] = true
+
+	faults := append([]*WSDL2InterfaceFault(nil), iface.Faults...)
+	for _, name := range strings.Fields(iface.Extends) {
+		parent := w.findInterface(name)
+		if parent == nil || seen[parent.Name] {
+			continue
+		}
+		faults = append(faults, w.interfaceFaults(parent, seen)...)
+	}
+	return faults
+}
+
+// soapAction returns the SOAP action bound to the given operation.
+func (b *WSDL2Binding) soapAction(operation string) string {
+	for _, op := range b.Operations {
+		if stripns(op.Ref) == operation {
+			return op.SOAPAction
+		}
+	}
+	return ""
+}
@@ -6,25 +6,31 @@ package gowsdl
 
 import (
 	"encoding/xml"
+	"strconv"
 )
 
 const xmlschema11 = "http://www.w3.org/2001/XMLSchema"
 
 // XSDSchema represents an entire Schema structure.
 type XSDSchema struct {
-	XMLName            xml.Name          `xml:"schema"`
-	Xmlns              map[string]string `xml:"-"`
-	Tns                string            `xml:"xmlns tns,attr"`
-	Xs                 string            `xml:"xmlns xs,attr"`
-	Version            string            `xml:"version,attr"`
-	TargetNamespace    string            `xml:"targetNamespace,attr"`
-	ElementFormDefault string            `xml:"elementFormDefault,attr"`
-	Includes           []*XSDInclude     `xml:"include"`
-	Imports            []*XSDImport      `xml:"import"`
-	Elements           []*XSDElement     `xml:"element"`
-	Attributes         []*XSDAttribute   `xml:"attribute"`
-	ComplexTypes       []*XSDComplexType `xml:"complexType"` // global
-	SimpleType         []*XSDSimpleType  `xml:"simpleType"`
+	XMLName            xml.Name             `xml:"schema"`
+	Xmlns              map[string]string    `xml:"-"`
+	Tns                string               `xml:"xmlns tns,attr"`
+	Xs                 string               `xml:"xmlns xs,attr"`
+	Version            string               `xml:"version,attr"`
+	TargetNamespace    string               `xml:"targetNamespace,attr"`
+	ElementFormDefault string               `xml:"elementFormDefault,attr"`
+	Includes           []*XSDInclude        `xml:"include"`
+	Imports            []*XSDImport         `xml:"import"`
+	Elements           []*XSDElement        `xml:"element"`
+	Attributes         []*XSDAttribute      `xml:"attribute"`
+	ComplexTypes       []*XSDComplexType    `xml:"complexType"` // global
+	SimpleType         []*XSDSimpleType     `xml:"simpleType"`
+	Groups             []*XSDGroup          `xml:"group"`
+	AttributeGroups    []*XSDAttributeGroup `xml:"attributeGroup"`
+
+	// synthesized marks schemas made up by the generator, e.g. RPC wrappers.
+	synthesized bool
 }
 
 // UnmarshalXML implements interface xml.Unmarshaler for XSDSchema.
@@ -98,6 +104,18 @@ Loop:
 					return err
 				}
 				s.SimpleType = append(s.SimpleType, x)
+			case "group":
+				x := new(XSDGroup)
+				if err := d.DecodeElement(x, &t); err != nil {
+					return err
+				}
+				s.Groups = append(s.Groups, x)
+			case "attributeGroup":
+				x := new(XSDAttributeGroup)
+				if err := d.DecodeElement(x, &t); err != nil {
+					return err
+				}
+				s.AttributeGroups = append(s.AttributeGroups, x)
 			default:
 				d.Skip()
 				continue Loop
@@ -124,17 +142,21 @@ type XSDImport struct {
 
 // XSDElement represents a Schema element.
 type XSDElement struct {
-	XMLName     xml.Name        `xml:"element"`
-	Name        string          `xml:"name,attr"`
-	Doc         string          `xml:"annotation>documentation"`
-	Nillable    bool            `xml:"nillable,attr"`
-	Type        string          `xml:"type,attr"`
-	Ref         string          `xml:"ref,attr"`
-	MinOccurs   string          `xml:"minOccurs,attr"`
-	MaxOccurs   string          `xml:"maxOccurs,attr"`
-	ComplexType *XSDComplexType `xml:"complexType"` // local
-	SimpleType  *XSDSimpleType  `xml:"simpleType"`
-	Groups      []*XSDGroup     `xml:"group"`
+	XMLName           xml.Name        `xml:"element"`
+	Name              string          `xml:"name,attr"`
+	Doc               string          `xml:"annotation>documentation"`
+	Nillable          bool            `xml:"nillable,attr"`
+	Type              string          `xml:"type,attr"`
+	Ref               string          `xml:"ref,attr"`
+	MinOccurs         string          `xml:"minOccurs,attr"`
+	MaxOccurs         string          `xml:"maxOccurs,attr"`
+	Default           string          `xml:"default,attr"`
+	Fixed             string          `xml:"fixed,attr"`
+	ComplexType       *XSDComplexType `xml:"complexType"` // local
+	SimpleType        *XSDSimpleType  `xml:"simpleType"`
+	Groups            []*XSDGroup     `xml:"group"`
+	Abstract          bool            `xml:"abstract,attr"`
+	SubstitutionGroup string          `xml:"substitutionGroup,attr"` // head element
 }
 
 // XSDAny represents a Schema element.
@@ -149,51 +171,308 @@ type XSDAny struct {
 
 // XSDComplexType represents a Schema complex type.
 type XSDComplexType struct {
-	XMLName        xml.Name          `xml:"complexType"`
-	Abstract       bool              `xml:"abstract,attr"`
-	Name           string            `xml:"name,attr"`
-	Mixed          bool              `xml:"mixed,attr"`
-	Sequence       []*XSDElement     `xml:"sequence>element"`
-	Choice         []*XSDElement     `xml:"choice>element"`
-	SequenceChoice []*XSDElement     `xml:"sequence>choice>element"`
-	All            []*XSDElement     `xml:"all>element"`
-	ComplexContent XSDComplexContent `xml:"complexContent"`
-	SimpleContent  XSDSimpleContent  `xml:"simpleContent"`
-	Attributes     []*XSDAttribute   `xml:"attribute"`
-	Any            []*XSDAny         `xml:"sequence>any"`
-}
-
-// XSDGroup element is used to define a group of elements to be used in complex type definitions.
+	XMLName xml.Name `xml:"complexType"`
+	XSDContentModel
+	Abstract        bool                 `xml:"abstract,attr"`
+	Name            string               `xml:"name,attr"`
+	Mixed           bool                 `xml:"mixed,attr"`
+	ComplexContent  XSDComplexContent    `xml:"complexContent"`
+	SimpleContent   XSDSimpleContent     `xml:"simpleContent"`
+	Attributes      []*XSDAttribute      `xml:"attribute"`
+	AttributeGroups []*XSDAttributeGroup `xml:"attributeGroup"`
+}
+
+// XSDContentModel holds the model group of a complex type, an extension or a
+// restriction: a sequence, a choice, an all or a reference to a group.
+type XSDContentModel struct {
+	Sequence *XSDModelGroup `xml:"sequence"`
+	Choice   *XSDModelGroup `xml:"choice"`
+	All      *XSDModelGroup `xml:"all"`
+	Group    *XSDGroup      `xml:"group"`
+}
+
+// ModelGroup returns the model group of the content model, or nil if it has
+// no element content.
+func (c XSDContentModel) ModelGroup() *XSDModelGroup {
+	switch {
+	case c.Sequence != nil:
+		return c.Sequence
+	case c.Choice != nil:
+		return c.Choice
+	case c.All != nil:
+		return c.All
+	case c.Group != nil:
+		return &XSDModelGroup{
+			XMLName:   xml.Name{Space: xmlschema11, Local: "sequence"},
+			Particles: []*XSDParticle{{Group: c.Group}},
+		}
+	}
+	return nil
+}
+
+// Elements returns the elements of the content model in document order. See
+// XSDModelGroup.Elements.
+func (c XSDContentModel) Elements() []*XSDElement {
+	return c.ModelGroup().Elements()
+}
+
+// Wildcards returns the xs:any wildcards of the content model.
+func (c XSDContentModel) Wildcards() []*XSDAny {
+	return c.ModelGroup().Wildcards()
+}
+
+// XSDModelGroup represents a sequence, choice or all compositor.
+type XSDModelGroup struct {
+	XMLName   xml.Name
+	MinOccurs string
+	MaxOccurs string
+	// Particles are kept in document order.
+	Particles []*XSDParticle
+}
+
+// XSDParticle is a member of a model group: an element, a wildcard, a
+// reference to a group or a nested model group.
+type XSDParticle struct {
+	Element    *XSDElement
+	Any        *XSDAny
+	Group      *XSDGroup
+	ModelGroup *XSDModelGroup
+}
+
+// UnmarshalXML implements interface xml.Unmarshaler for XSDModelGroup.
+func (m *XSDModelGroup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	m.XMLName = start.Name
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "minOccurs":
+			m.MinOccurs = attr.Value
+		case "maxOccurs":
+			m.MaxOccurs = attr.Value
+		}
+	}
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space != xmlschema11 {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			p := new(XSDParticle)
+			switch t.Name.Local {
+			case "element":
+				p.Element = new(XSDElement)
+				err = d.DecodeElement(p.Element, &t)
+			case "any":
+				p.Any = new(XSDAny)
+				err = d.DecodeElement(p.Any, &t)
+			case "group":
+				p.Group = new(XSDGroup)
+				err = d.DecodeElement(p.Group, &t)
+			case "sequence", "choice", "all":
+				p.ModelGroup = new(XSDModelGroup)
+				err = d.DecodeElement(p.ModelGroup, &t)
+			default:
+				err = d.Skip()
+				p = nil
+			}
+			if err != nil {
+				return err
+			}
+			if p != nil {
+				m.Particles = append(m.Particles, p)
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// Elements returns the elements of the model group and of its nested model
+// groups and referenced groups, in document order. The occurrences of the
+// enclosing groups are applied to the elements: elements of a repeated group
+// repeat as many times, and the elements of optional groups or of choices
+// between several particles are optional.
+func (m *XSDModelGroup) Elements() []*XSDElement {
+	var elements []*XSDElement
+	m.walk(occurs{1, 1}, nil, func(p *XSDParticle, o occurs) {
+		if p.Element != nil {
+			elements = append(elements, p.Element.withOccurs(o))
+		}
+	})
+	return elements
+}
+
+// Wildcards returns the xs:any wildcards of the model group and of its nested
+// model groups and referenced groups.
+func (m *XSDModelGroup) Wildcards() []*XSDAny {
+	var wildcards []*XSDAny
+	m.walk(occurs{1, 1}, nil, func(p *XSDParticle, o occurs) {
+		if p.Any != nil {
+			wildcards = append(wildcards, p.Any)
+		}
+	})
+	return wildcards
+}
+
+// walk visits the elements and wildcards of the model group, with the
+// occurrences of the enclosing groups. If enter is not nil, it is called with
+// the model group and each nested model group along with their occurrences,
+// and the particles of a group are skipped when it returns false.
+func (m *XSDModelGroup) walk(outer occurs, enter func(*XSDModelGroup, occurs) bool, visit func(*XSDParticle, occurs)) {
+	if m == nil {
+		return
+	}
+
+	o := outer.times(parseOccurs(m.MinOccurs, m.MaxOccurs))
+	if enter != nil && !enter(m, o) {
+		return
+	}
+	if m.XMLName.Local == "choice" && len(m.Particles) > 1 {
+		o.min = 0
+	}
+
+	for _, p := range m.Particles {
+		switch {
+		case p.ModelGroup != nil:
+			p.ModelGroup.walk(o, enter, visit)
+		case p.Group != nil:
+			if model := p.Group.resolved; model != nil {
+				model.walk(o.times(parseOccurs(p.Group.MinOccurs, p.Group.MaxOccurs)), enter, visit)
+			}
+		default:
+			visit(p, o)
+		}
+	}
+}
+
+// withOccurs returns the element with the occurrences of its enclosing groups
+// applied. The element is copied when they are not the default ones, its
+// declaration is left untouched.
+func (el *XSDElement) withOccurs(o occurs) *XSDElement {
+	if o == (occurs{1, 1}) {
+		return el
+	}
+	c := *el
+	o = o.times(parseOccurs(el.MinOccurs, el.MaxOccurs))
+	c.MinOccurs, c.MaxOccurs = o.strings()
+	return &c
+}
+
+// occurs is the number of occurrences of a particle, max being -1 when
+// unbounded.
+type occurs struct {
+	min, max int
+}
+
+func parseOccurs(minOccurs, maxOccurs string) occurs {
+	o := occurs{1, 1}
+	if n, err := strconv.Atoi(minOccurs); err == nil {
+		o.min = n
+	}
+	if maxOccurs == "unbounded" {
+		o.max = -1
+	} else if n, err := strconv.Atoi(maxOccurs); err == nil {
+		o.max = n
+	}
+	return o
+}
+
+func (o occurs) times(p occurs) occurs {
+	r := occurs{min: o.min * p.min}
+	switch {
+	case o.max == 0 || p.max == 0:
+		r.max = 0
+	case o.max < 0 || p.max < 0:
+		r.max = -1
+	default:
+		r.max = o.max * p.max
+	}
+	return r
+}
+
+func (o occurs) strings() (minOccurs, maxOccurs string) {
+	maxOccurs = "unbounded"
+	if o.max >= 0 {
+		maxOccurs = strconv.Itoa(o.max)
+	}
+	return strconv.Itoa(o.min), maxOccurs
+}
+
+// XSDGroup element is used to define a group of elements to be used in
+// complex type definitions, or to reference such a group.
 type XSDGroup struct {
-	Name     string       `xml:"name,attr"`
-	Ref      string       `xml:"ref,attr"`
-	Sequence []XSDElement `xml:"sequence>element"`
-	Choice   []XSDElement `xml:"choice>element"`
-	All      []XSDElement `xml:"all>element"`
+	XSDContentModel
+	Name      string `xml:"name,attr"`
+	Ref       string `xml:"ref,attr"`
+	MinOccurs string `xml:"minOccurs,attr"`
+	MaxOccurs string `xml:"maxOccurs,attr"`
+
+	// resolved is the model group of the referenced group.
+	resolved *XSDModelGroup
+}
+
+// XSDAttributeGroup element is used to define a group of attributes to be
+// used in complex type definitions.
+type XSDAttributeGroup struct {
+	Name            string               `xml:"name,attr"`
+	Ref             string               `xml:"ref,attr"`
+	Attributes      []*XSDAttribute      `xml:"attribute"`
+	AttributeGroups []*XSDAttributeGroup `xml:"attributeGroup"`
 }
 
 // XSDComplexContent element defines extensions or restrictions on a complex
 // type that contains mixed content or elements only.
 type XSDComplexContent struct {
-	XMLName   xml.Name     `xml:"complexContent"`
-	Extension XSDExtension `xml:"extension"`
+	XMLName     xml.Name              `xml:"complexContent"`
+	Extension   XSDExtension          `xml:"extension"`
+	Restriction XSDComplexRestriction `xml:"restriction"`
+}
+
+// XSDComplexRestriction element restricts the content model of an existing
+// complexType, e.g. soapenc:Array.
+type XSDComplexRestriction struct {
+	XMLName xml.Name `xml:"restriction"`
+	XSDContentModel
+	Base            string               `xml:"base,attr"`
+	Attributes      []*XSDAttribute      `xml:"attribute"`
+	AttributeGroups []*XSDAttributeGroup `xml:"attributeGroup"`
 }
 
 // XSDSimpleContent element contains extensions or restrictions on a text-only
 // complex type or on a simple type as content and contains no elements.
 type XSDSimpleContent struct {
-	XMLName   xml.Name     `xml:"simpleContent"`
-	Extension XSDExtension `xml:"extension"`
+	XMLName     xml.Name                    `xml:"simpleContent"`
+	Extension   XSDExtension                `xml:"extension"`
+	Restriction XSDSimpleContentRestriction `xml:"restriction"`
+}
+
+// XSDSimpleContentRestriction element restricts the value and the attributes
+// of an existing complexType with simple content, with facets or an inline
+// simpleType.
+type XSDSimpleContentRestriction struct {
+	XMLName xml.Name `xml:"restriction"`
+	XSDRestriction
+	SimpleType      *XSDSimpleType       `xml:"simpleType"`
+	Attributes      []*XSDAttribute      `xml:"attribute"`
+	AttributeGroups []*XSDAttributeGroup `xml:"attributeGroup"`
 }
 
 // XSDExtension element extends an existing simpleType or complexType element.
 type XSDExtension struct {
-	XMLName        xml.Name        `xml:"extension"`
-	Base           string          `xml:"base,attr"`
-	Attributes     []*XSDAttribute `xml:"attribute"`
-	Sequence       []*XSDElement   `xml:"sequence>element"`
-	Choice         []*XSDElement   `xml:"choice>element"`
-	SequenceChoice []*XSDElement   `xml:"sequence>choice>element"`
+	XMLName xml.Name `xml:"extension"`
+	XSDContentModel
+	Base            string               `xml:"base,attr"`
+	Attributes      []*XSDAttribute      `xml:"attribute"`
+	AttributeGroups []*XSDAttributeGroup `xml:"attributeGroup"`
 }
 
 // XSDAttribute represent an element attribute. Simple elements cannot have
@@ -205,8 +484,13 @@ type XSDAttribute struct {
 	Ref        string         `xml:"ref,attr"`
 	Type       string         `xml:"type,attr"`
 	Use        string         `xml:"use,attr"`
+	Default    string         `xml:"default,attr"`
 	Fixed      string         `xml:"fixed,attr"`
+	ArrayType  string         `xml:"http://schemas.xmlsoap.org/wsdl/ arrayType,attr"`
 	SimpleType *XSDSimpleType `xml:"simpleType"`
+
+	// namespace qualifies references to global attributes.
+	namespace string
 }
 
 // XSDSimpleType element defines a simple type and specifies the constraints
@@ -237,7 +521,7 @@ type XSDUnion struct {
 type XSDRestriction struct {
 	Base         string                `xml:"base,attr"`
 	Enumeration  []XSDRestrictionValue `xml:"enumeration"`
-	Pattern      XSDRestrictionValue   `xml:"pattern"`
+	Pattern      []XSDRestrictionValue `xml:"pattern"`
 	MinInclusive XSDRestrictionValue   `xml:"minInclusive"`
 	MaxInclusive XSDRestrictionValue   `xml:"maxInclusive"`
 	WhiteSpace   XSDRestrictionValue   `xml:"whitespace"`